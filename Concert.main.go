@@ -2,249 +2,129 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"image"
-	"image/color"
 	"image/png"
 	"log"
-	"math"
-	"math/rand"
 	"os"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
-)
-
-const (
-	screenWidth  = 800
-	screenHeight = 600
-	maxParticles = 8000
-	defaultTexW  = 32
-	defaultTexH  = 32
-)
 
-var (
-	fireImage  *ebiten.Image
-	fireImageW float64
-	fireImageH float64
+	"github.com/arcesoftware/GO_Examples/concert"
+	"github.com/arcesoftware/GO_Examples/fireburst"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-
-	// Procedural circular alpha texture
-	img := image.NewRGBA(image.Rect(0, 0, defaultTexW, defaultTexH))
-	cx, cy := defaultTexW/2.0, defaultTexH/2.0
-	maxR := math.Hypot(cx, cy)
-	for y := 0; y < defaultTexH; y++ {
-		for x := 0; x < defaultTexW; x++ {
-			d := math.Hypot(float64(x)-cx, float64(y)-cy)
-			t := 1.0 - d/maxR
-			if t < 0 {
-				t = 0
-			}
-			a := uint8((t * t) * 255)
-			img.SetRGBA(x, y, color.RGBA{255, 255, 255, a})
-		}
-	}
-	fireImage = ebiten.NewImageFromImage(img)
-	var buf bytes.Buffer
-	_ = png.Encode(&buf, img)
-	_ = os.WriteFile("fallback_fire.png", buf.Bytes(), 0644)
-
-	fireImageW = float64(fireImage.Bounds().Dx())
-	fireImageH = float64(fireImage.Bounds().Dy())
-}
-
-type Particle struct {
-	x, y, z           float64
-	vx, vy, vz        float64
-	lifetime, maxLife int
-	baseScale         float64
-	angle             float64
-	angularVelocity   float64
-	active            bool
-}
-
-func (p *Particle) update() {
-	if !p.active {
-		return
-	}
-	p.lifetime++
-	if p.lifetime >= p.maxLife {
-		p.active = false
-		return
-	}
-
-	p.x += p.vx
-	p.y += p.vy
-	p.z += p.vz
-
-	p.angle += p.angularVelocity
-	p.vy += 0.02 // gentle upward drift
-	p.vz *= 0.98 // slow damping in depth
+// goldenRun renders concert.Snapshot, then writes it as a PNG and/or
+// compares it against a golden image, and exits.
+type goldenRun struct {
+	g         *concert.Game
+	frames    int
+	snapshot  string // write the rendered frame here
+	golden    string // compare the rendered frame against this PNG
+	tolerance int    // max per-channel difference before a pixel counts as changed
 }
 
-type Game struct {
-	particles []*Particle
-	vertices  []ebiten.Vertex
-	indices   []uint16
-}
+func (r *goldenRun) Update() error {
+	got := concert.Snapshot(r.g, r.frames)
 
-func NewGame() *Game {
-	g := &Game{
-		particles: make([]*Particle, 0, maxParticles),
-		vertices:  make([]ebiten.Vertex, 0, maxParticles*4),
-		indices:   make([]uint16, 0, maxParticles*6),
-	}
-	for i := 0; i < maxParticles; i++ {
-		g.particles = append(g.particles, &Particle{})
-	}
-	return g
-}
-
-func (g *Game) allocateParticle() *Particle {
-	for _, p := range g.particles {
-		if !p.active {
-			return p
+	if r.snapshot != "" {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, got); err != nil {
+			return err
+		}
+		if err := os.WriteFile(r.snapshot, buf.Bytes(), 0644); err != nil {
+			return err
 		}
 	}
-	return nil
-}
-
-func newFireParticle(x, y float64) *Particle {
-	p := &Particle{
-		active:          true,
-		x:               x + rand.Float64()*4 - 2,
-		y:               y + rand.Float64()*4 - 2,
-		z:               rand.Float64()*2 - 1, // depth
-		angle:           rand.Float64() * 2 * math.Pi,
-		angularVelocity: (rand.Float64()*2 - 1) * 0.1,
-		maxLife:         rand.Intn(40) + 40,
-		baseScale:       rand.Float64()*0.1 + 0.2,
-	}
-	ang := rand.Float64() * 2 * math.Pi
-	speed := rand.Float64()*4.0 + 2.0
-	p.vx = math.Cos(ang) * speed * 0.3
-	p.vy = math.Sin(ang) * speed * 0.7
-	p.vz = (rand.Float64()*2 - 1) * 0.5
-	return p
-}
-
-func (g *Game) spawnExplosion(x, y float64) {
-	for i := 0; i < 600; i++ {
-		if p := g.allocateParticle(); p != nil {
-			*p = *newFireParticle(x, y)
-		} else {
-			break
+	if r.golden != "" {
+		f, err := os.Open(r.golden)
+		if err != nil {
+			return err
+		}
+		want, _, err := image.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("decoding %s: %w", r.golden, err)
+		}
+		if n := concert.CountChangedPixels(got, want, r.tolerance); n > 0 {
+			return fmt.Errorf("%d pixels differ from %s by more than %d", n, r.golden, r.tolerance)
 		}
 	}
+	return ebiten.Termination
 }
 
-// Blue (far) → Red (near)
-func depthColor(z float64) (r, g, b float32) {
-	// Normalize z from -2 (far) to +2 (near)
-	t := float32((z + 2.0) / 4.0)
-	if t < 0 {
-		t = 0
-	}
-	if t > 1 {
-		t = 1
-	}
+func (r *goldenRun) Draw(screen *ebiten.Image) {}
 
-	// Interpolate blue → purple → red
-	r = t
-	g = 0.0
-	b = 1.0 - t
-	return
+func (r *goldenRun) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return concert.ScreenWidth, concert.ScreenHeight
 }
 
-func (g *Game) Update() error {
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		mx, my := ebiten.CursorPosition()
-		g.spawnExplosion(float64(mx), float64(my))
+func main() {
+	opts := concert.DefaultOptions()
+	bgTop := fireburst.HexColor{RGBA: opts.BgTop}
+	bgBottom := fireburst.HexColor{RGBA: opts.BgBottom}
+	ringColor := fireburst.HexColor{RGBA: opts.RingColor}
+
+	seed := flag.Int64("seed", 0, "seed the particle RNG (0 = time-based)")
+	frames := flag.Int("frames", 0, "render a fixed explosion after N frames, then exit (use with -snapshot/-golden)")
+	snapshot := flag.String("snapshot", "", "write the -frames render to this PNG")
+	golden := flag.String("golden", "", "compare the -frames render against this PNG (concert/testdata/golden.png is 30 frames) and fail on mismatch")
+	tolerance := flag.Int("tolerance", 2, "per-channel difference allowed by -golden")
+	flag.Float64Var(&opts.Falloff, "falloff", opts.Falloff, "particle texture falloff exponent (higher = harder edge)")
+	windowScale := flag.Int("scale", 1, "window size as a multiple of the logical resolution (the simulation is unaffected)")
+	fullscreen := flag.Bool("fullscreen", false, "start fullscreen")
+	flag.BoolVar(&opts.AlphaBlend, "alpha", false, "draw particles with alpha blending instead of additive (B toggles)")
+	sortSpec := flag.String("sort", "auto", "depth sort before drawing: auto (only with alpha blending), on or off (S cycles)")
+	flag.Var(&bgTop, "bgtop", "background color at the top of the screen, rrggbb")
+	flag.Var(&bgBottom, "bgbottom", "background color at the bottom of the screen, rrggbb")
+	flag.Var(&ringColor, "ringcolor", "explosion shockwave ring color, rrggbb")
+	flag.Float64Var(&opts.RingSpeed, "ringspeed", opts.RingSpeed, "shockwave ring growth in pixels per frame")
+	flag.Float64Var(&opts.RingFade, "ringfade", opts.RingFade, "shockwave ring alpha lost per frame (1 = one frame)")
+	flag.Parse()
+	if *windowScale < 1 {
+		log.Fatal("-scale must be at least 1")
 	}
-
-	for _, p := range g.particles {
-		if p.active {
-			p.update()
-		}
+	if !(opts.RingFade > 0) {
+		log.Fatal("-ringfade must be positive")
 	}
-	return nil
-}
-
-func (g *Game) Draw(screen *ebiten.Image) {
-	screen.Fill(color.RGBA{10, 10, 20, 255})
-
-	g.vertices = g.vertices[:0]
-	g.indices = g.indices[:0]
-	fireVertexCount := 0
-
-	sx0, sy0 := 0.0, 0.0
-	sx1, sy1 := fireImageW, fireImageH
-	halfW, halfH := fireImageW/2.0, fireImageH/2.0
-
-	for _, p := range g.particles {
-		if !p.active {
-			continue
-		}
-		rate := float64(p.lifetime) / float64(p.maxLife)
-		alpha := float32(1.0 - math.Pow(rate, 1.5))
-
-		// Perspective scaling based on depth
-		depthScale := float64(1.0 / (1.0 + p.z*0.5))
-		scale := p.baseScale * (1.0 + 0.5*rate) * depthScale
-
-		// Colorize based on depth
-		r, gcol, b := depthColor(p.z)
-
-		var geo ebiten.GeoM
-		geo.Translate(-halfW, -halfH)
-		geo.Rotate(p.angle)
-		geo.Scale(scale, scale)
-		geo.Translate(p.x, p.y)
-
-		vIndex := uint16(fireVertexCount)
-		fireVertexCount += 4
-		corners := []struct{ dx, dy, sx, sy float64 }{
-			{0, 0, sx0, sy0},
-			{0, fireImageH, sx0, sy1},
-			{fireImageW, 0, sx1, sy0},
-			{fireImageW, fireImageH, sx1, sy1},
-		}
-		for _, c := range corners {
-			vx, vy := geo.Apply(c.dx, c.dy)
-			g.vertices = append(g.vertices, ebiten.Vertex{
-				DstX: float32(vx), DstY: float32(vy),
-				SrcX: float32(c.sx), SrcY: float32(c.sy),
-				ColorR: r * alpha,
-				ColorG: gcol * alpha,
-				ColorB: b * alpha,
-				ColorA: alpha,
-			})
-		}
-		g.indices = append(g.indices, vIndex, vIndex+1, vIndex+2, vIndex+1, vIndex+3, vIndex+2)
+	if !(opts.RingSpeed >= 0) {
+		log.Fatal("-ringspeed must not be negative")
 	}
-
-	if len(g.vertices) > 0 && len(g.indices) > 0 {
-		op := &ebiten.DrawTrianglesOptions{CompositeMode: ebiten.CompositeModeLighter}
-		screen.DrawTriangles(g.vertices, g.indices, fireImage, op)
+	mode, err := concert.ParseSortMode(*sortSpec)
+	if err != nil {
+		log.Fatalf("-sort: %v", err)
 	}
+	opts.DepthSort = mode
+	opts.BgTop, opts.BgBottom, opts.RingColor = bgTop.RGBA, bgBottom.RGBA, ringColor.RGBA
 
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("Particles: %d/%d\n[LMB] Explosion (Depth Color: Blue→Red)", len(g.vertices)/4, maxParticles))
-}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, concert.FireTexture(opts.Falloff))
+	_ = os.WriteFile("fallback_fire.png", buf.Bytes(), 0644)
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenWidth, screenHeight
-}
+	switch {
+	case *seed != 0:
+		opts.Seed = *seed
+	case *frames > 0:
+		opts.Seed = 1
+	default:
+		opts.Seed = time.Now().UnixNano()
+	}
 
-func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
+	// Layout keeps returning the logical size; ebiten scales it to the window
+	ebiten.SetWindowSize(concert.ScreenWidth*(*windowScale), concert.ScreenHeight*(*windowScale))
+	ebiten.SetFullscreen(*fullscreen)
 	ebiten.SetWindowTitle("🔥 3D Depth Fire Particles (Blue→Red)")
 	ebiten.SetTPS(60)
-	g := NewGame()
+	g := concert.NewGame(opts)
+	if *frames > 0 {
+		run := &goldenRun{g: g, frames: *frames, snapshot: *snapshot, golden: *golden, tolerance: *tolerance}
+		if err := ebiten.RunGame(run); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -14,109 +15,239 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/examples/resources/images"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/arcesoftware/GO_Examples/smokepaint"
 )
 
 const (
 	screenWidth  = 800
 	screenHeight = 600
-	maxParticles = 800
+	maxParticles = 8000 // batched rendering keeps this cheap; 4*maxParticles must fit in uint16
+
+	// Painting spawns paintMinSpawn particles per tick with the cursor still,
+	// plus paintSpawnPerPixel for every pixel it moved since the last tick,
+	// up to paintMaxSpawn (half a percent of the pool) per tick. Each spawn
+	// inherits paintInherit of the cursor velocity.
+	paintMinSpawn      = 1.0
+	paintSpawnPerPixel = 0.5
+	paintMaxSpawn      = maxParticles / 200
+	paintInherit       = 0.3
+
+	// cullMargin is how far past the screen edge a particle may drift before it is
+	// dropped. Raise it if trails that briefly leave the screen are cut short.
+	cullMargin = 150.0
+
+	// defaultGravity matches the original light downward drift per tick.
+	defaultGravity = 0.01
+
+	// groundY is the floor particles bounce off; groundFriction scales vx on
+	// each bounce.
+	groundY        = screenHeight - 20
+	groundFriction = 0.8
 )
 
+// restitution is the fraction of vertical speed kept when a particle bounces
+// off the ground (0 = stick, 1 = perfectly elastic).
+var restitution = 0.6
+
 var smokeImage *ebiten.Image
+var smokeImageW, smokeImageH float64
 
 func init() {
-	rand.Seed(time.Now().UnixNano())
-
 	img, _, err := image.Decode(bytes.NewReader(images.Smoke_png))
 	if err != nil {
 		log.Fatal(err)
 	}
 	smokeImage = ebiten.NewImageFromImage(img)
+	smokeImageW = float64(smokeImage.Bounds().Dx())
+	smokeImageH = float64(smokeImage.Bounds().Dy())
 }
 
-type Particle struct {
-	x, y     float64
-	vx, vy   float64
-	angle    float64
-	scale    float64
-	alpha    float32
-	life     int
-	maxLife  int
-	img      *ebiten.Image
-	colorMix color.RGBA
+// Emitter spawns `count` particles every `rate` ticks inside a cone of
+// directions centered on dir.
+type Emitter struct {
+	x, y               float64
+	rate               int     // spawn every `rate` ticks (1 = every tick)
+	count              int     // particles per spawn
+	dir                float64 // cone center in radians
+	spread             float64 // full cone width in radians (2π = all directions)
+	minSpeed, maxSpeed float64
+	col                color.RGBA // zero value keeps smokepaint.New's random tint
+	counter            int
 }
 
-func NewParticle(img *ebiten.Image) *Particle {
-	dir := rand.Float64() * 2 * math.Pi
-	speed := rand.Float64()*1.5 + 0.5
+// emitterColors is cycled through for emitters added at the cursor.
+var emitterColors = []color.RGBA{
+	{255, 170, 80, 255},
+	{120, 255, 140, 255},
+	{255, 110, 220, 255},
+	{255, 240, 120, 255},
+}
 
-	return &Particle{
-		x:        screenWidth / 2,
-		y:        screenHeight / 2,
-		vx:       math.Cos(dir) * speed,
-		vy:       math.Sin(dir) * speed,
-		angle:    rand.Float64() * 2 * math.Pi,
-		scale:    rand.Float64()*0.2 + 0.3,
-		alpha:    0.6,
-		life:     60 + rand.Intn(120),
-		maxLife:  60 + rand.Intn(120),
-		img:      img,
-		colorMix: color.RGBA{uint8(200 + rand.Intn(55)), uint8(200 + rand.Intn(55)), 255, 255},
+func (e *Emitter) spawn(g *Game) {
+	e.counter++
+	if e.rate <= 0 {
+		e.rate = 1
+	}
+	if e.counter%e.rate != 0 {
+		return
+	}
+	for i := 0; i < e.count && len(g.particles) < maxParticles; i++ {
+		p := smokepaint.New(g.rng, smokeImage, e.x, e.y)
+		dir := e.dir + (g.rng.Float64()-0.5)*e.spread
+		speed := e.minSpeed + g.rng.Float64()*(e.maxSpeed-e.minSpeed)
+		p.VX = math.Cos(dir) * speed
+		p.VY = math.Sin(dir) * speed
+		if e.col.A != 0 {
+			p.ColorMix = e.col
+		}
+		g.particles = append(g.particles, p)
 	}
 }
 
-func (p *Particle) Update() bool {
-	p.x += p.vx
-	p.y += p.vy
-	p.vy += 0.01 // light upward drift or gravity effect tweak
+type Game struct {
+	particles []*smokepaint.Particle
+	emitters  []*Emitter
+	tick      int
 
-	p.angle += 0.01
-	p.life--
+	// placed counts emitters added with E; it picks the next emitterColors
+	// entry, so the cycle does not depend on how many emitters exist.
+	placed int
 
-	return p.life > 0
-}
+	// rng drives every spawn; seeded from the clock in NewGame
+	rng *rand.Rand
 
-func (p *Particle) Draw(screen *ebiten.Image) {
-	if p.life <= 0 {
-		return
-	}
+	// gravity is applied to every particle while gravityOn is set.
+	gravityX, gravityY float64
+	gravityOn          bool
 
-	ratio := float32(p.life) / float32(p.maxLife)
-	alpha := p.alpha * ratio
-	if alpha < 0 {
-		alpha = 0
-	}
+	// compositeMode selects soft smoke (SourceOver) or glowing sparks (Lighter)
+	compositeMode ebiten.CompositeMode
 
-	op := &ebiten.DrawImageOptions{}
-	w, h := p.img.Bounds().Dx(), p.img.Bounds().Dy()
+	// Cursor position on the previous painting tick, valid while painting;
+	// paintCarry keeps the fractional spawn count between ticks.
+	paintX, paintY float64
+	painting       bool
+	paintCarry     float64
+	paintRate      int // particles spawned on the last tick, for the HUD
 
-	op.GeoM.Translate(-float64(w)/2, -float64(h)/2)
-	op.GeoM.Rotate(p.angle)
-	op.GeoM.Scale(p.scale, p.scale)
-	op.GeoM.Translate(p.x, p.y)
-	op.ColorScale.Scale(float32(p.colorMix.R)/255, float32(p.colorMix.G)/255, float32(p.colorMix.B)/255, alpha)
+	// Reused DrawTriangles buffers (4 vertices and 6 indices per particle)
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+func NewGame() *Game {
+	g := &Game{
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		gravityY:  defaultGravity,
+		gravityOn: true,
 
-	screen.DrawImage(p.img, op)
+		compositeMode: ebiten.CompositeModeSourceOver,
+		vertices:      make([]ebiten.Vertex, 0, maxParticles*4),
+		indices:       make([]uint16, 0, maxParticles*6),
+	}
+	// the original central fountain: 5 particles every other tick in all directions
+	g.emitters = append(g.emitters, &Emitter{
+		x:        screenWidth / 2,
+		y:        screenHeight / 2,
+		rate:     2,
+		count:    5,
+		spread:   2 * math.Pi,
+		minSpeed: 0.5,
+		maxSpeed: 2.0,
+	})
+	return g
 }
 
-type Game struct {
-	particles []*Particle
-	tick      int
+// paint spawns particles along the cursor's path since the last tick, more
+// the faster it moved, each carrying part of the cursor velocity.
+func (g *Game) paint(mx, my float64) {
+	if !g.painting {
+		g.paintX, g.paintY, g.painting = mx, my, true
+	}
+	dx, dy := mx-g.paintX, my-g.paintY
+	g.paintX, g.paintY = mx, my
+
+	want := paintMinSpawn + math.Hypot(dx, dy)*paintSpawnPerPixel + g.paintCarry
+	n := min(int(want), paintMaxSpawn, maxParticles-len(g.particles))
+	g.paintCarry = want - math.Floor(want)
+	g.paintRate = n
+	for i := 0; i < n; i++ {
+		// spread along the segment so fast strokes leave a line, not dots
+		t := (float64(i) + g.rng.Float64()) / float64(n)
+		p := smokepaint.New(g.rng, smokeImage, mx-dx*(1-t), my-dy*(1-t))
+		p.VX += dx * paintInherit
+		p.VY += dy * paintInherit
+		g.particles = append(g.particles, p)
+	}
 }
 
 func (g *Game) Update() error {
-	// Spawn new particles periodically
-	if len(g.particles) < maxParticles && g.tick%2 == 0 {
-		for i := 0; i < 5; i++ {
-			g.particles = append(g.particles, NewParticle(smokeImage))
+	// G toggles gravity, F flips it between falling sparks and rising smoke
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		g.gravityOn = !g.gravityOn
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		g.gravityX, g.gravityY = -g.gravityX, -g.gravityY
+	}
+	// B switches between alpha and additive blending
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		if g.compositeMode == ebiten.CompositeModeLighter {
+			g.compositeMode = ebiten.CompositeModeSourceOver
+		} else {
+			g.compositeMode = ebiten.CompositeModeLighter
 		}
 	}
+	gx, gy := 0.0, 0.0
+	if g.gravityOn {
+		gx, gy = g.gravityX, g.gravityY
+	}
+
+	// E adds a narrow upward emitter at the cursor
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		mx, my := ebiten.CursorPosition()
+		g.emitters = append(g.emitters, &Emitter{
+			x:        float64(mx),
+			y:        float64(my),
+			rate:     1,
+			count:    2,
+			dir:      -math.Pi / 2,
+			spread:   math.Pi / 4,
+			minSpeed: 1.0,
+			maxSpeed: 2.5,
+			col:      emitterColors[g.placed%len(emitterColors)],
+		})
+		g.placed++
+	}
+
+	// [ and ] tune how bouncy the ground is
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+		restitution = math.Max(restitution-0.1, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+		restitution = math.Min(restitution+0.1, 1)
+	}
+
+	// Paint at the cursor while the left button is held
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		mx, my := ebiten.CursorPosition()
+		g.paint(float64(mx), float64(my))
+	} else {
+		g.painting, g.paintCarry, g.paintRate = false, 0, 0
+	}
+
+	for _, e := range g.emitters {
+		e.spawn(g)
+	}
 	g.tick++
 
-	// Update particles and compact slice
+	// Update particles and compact slice, dropping expired and far off-screen ones
 	n := 0
 	for _, p := range g.particles {
-		if p.Update() {
+		alive := p.Update(gx, gy)
+		p.Bounce(groundY, restitution, groundFriction)
+		if alive && !p.Offscreen(screenWidth, screenHeight, cullMargin) {
 			g.particles[n] = p
 			n++
 		}
@@ -127,11 +258,80 @@ func (g *Game) Update() error {
 
 func (g *Game) Draw(screen *ebiten.Image) {
 	screen.Fill(color.RGBA{0x10, 0x18, 0x30, 0xff})
+
+	g.vertices = g.vertices[:0]
+	g.indices = g.indices[:0]
+
+	sx0, sy0 := 0.0, 0.0
+	sx1, sy1 := smokeImageW, smokeImageH
+	halfW, halfH := smokeImageW/2.0, smokeImageH/2.0
+
 	for _, p := range g.particles {
-		p.Draw(screen)
+		alpha := p.Alpha * p.Fade()
+		if alpha <= 0 {
+			continue
+		}
+
+		// Premultiplied vertex color carries the per-particle tint and fade
+		cr := float32(p.ColorMix.R) / 0xff * alpha
+		cg := float32(p.ColorMix.G) / 0xff * alpha
+		cb := float32(p.ColorMix.B) / 0xff * alpha
+
+		var geo ebiten.GeoM
+		geo.Translate(-halfW, -halfH)
+		geo.Rotate(p.Angle)
+		geo.Scale(p.Scale, p.Scale)
+		geo.Translate(p.X, p.Y)
+
+		vIndex := uint16(len(g.vertices))
+		corners := []struct{ dx, dy, sx, sy float64 }{
+			{0, 0, sx0, sy0},
+			{0, smokeImageH, sx0, sy1},
+			{smokeImageW, 0, sx1, sy0},
+			{smokeImageW, smokeImageH, sx1, sy1},
+		}
+		for _, c := range corners {
+			vx, vy := geo.Apply(c.dx, c.dy)
+			g.vertices = append(g.vertices, ebiten.Vertex{
+				DstX: float32(vx), DstY: float32(vy),
+				SrcX: float32(c.sx), SrcY: float32(c.sy),
+				ColorR: cr, ColorG: cg, ColorB: cb, ColorA: alpha,
+			})
+		}
+		g.indices = append(g.indices, vIndex, vIndex+1, vIndex+2, vIndex+1, vIndex+3, vIndex+2)
 	}
 
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %.2f\nParticles: %d", ebiten.ActualTPS(), len(g.particles)))
+	// Single draw call for all particles
+	if len(g.indices) > 0 {
+		op := &ebiten.DrawTrianglesOptions{CompositeMode: g.compositeMode}
+		screen.DrawTriangles(g.vertices, g.indices, smokeImage, op)
+	}
+
+	gravity := "off"
+	if g.gravityOn {
+		gravity = "down"
+		if g.gravityY < 0 {
+			gravity = "up"
+		}
+	}
+	// ground
+	ebitenutil.DrawRect(screen, 0, groundY, screenWidth, 1, color.RGBA{90, 110, 150, 255})
+
+	// emitter markers
+	for _, e := range g.emitters {
+		mc := e.col
+		if mc.A == 0 {
+			mc = color.RGBA{200, 220, 255, 255}
+		}
+		ebitenutil.DrawRect(screen, e.x-3, e.y-3, 6, 6, mc)
+	}
+
+	blend := "SourceOver"
+	if g.compositeMode == ebiten.CompositeModeLighter {
+		blend = "Lighter"
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %.2f\nParticles: %d\nGravity: %s (%.3f)\nBlend: %s\nEmitters: %d\nRestitution: %.1f\nPaint rate: %d/tick\n[LMB] Paint  [E] Add emitter  [G] Toggle gravity  [F] Flip direction  [B] Blend mode  [ [ ] ] Bounce",
+		ebiten.ActualTPS(), len(g.particles), gravity, math.Abs(g.gravityY), blend, len(g.emitters), restitution, g.paintRate))
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -139,9 +339,15 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func main() {
+	flag.Float64Var(&restitution, "restitution", restitution, "fraction of vertical speed kept when particles bounce off the ground (0..1)")
+	flag.Parse()
+	if restitution < 0 || restitution > 1 {
+		log.Fatal("-restitution must be between 0 and 1")
+	}
+
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Modern Particle System (Ebiten)")
-	if err := ebiten.RunGame(&Game{}); err != nil {
+	if err := ebiten.RunGame(NewGame()); err != nil {
 		log.Fatal(err)
 	}
 }
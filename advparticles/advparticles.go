@@ -0,0 +1,535 @@
+// Package advparticles is the advanced.particles demo's 3D smoke cloud:
+// particles spawned in a sphere drift outward under damping, optionally
+// pulled back together by a cohesion spring, and are projected through an
+// orbiting camera and drawn depth-sorted with atmospheric fog. The demo
+// only reads flags, applies them to the package settings and a Game, and
+// runs it.
+package advparticles
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/examples/resources/images"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/arcesoftware/GO_Examples/orbitcam"
+)
+
+const (
+	ScreenWidth  = 1024
+	ScreenHeight = 768
+	maxParticles = 1200
+	spawnPerTick = 8
+	focalLength  = 450.0 // controls perspective strength
+	worldRadius  = 220.0 // size of the particle cloud
+
+	// Wall-clock rates, matching the original per-tick values at 60 TPS
+	spawnInterval = 2.0 / 60.0 // seconds between spawns of spawnPerTick
+	maxFrameDelta = 0.1        // clamp for long stalls (seconds)
+)
+
+var smokeImage *ebiten.Image
+
+// Atmospheric fog: particles fade from full brightness at FogNear toward
+// FogFloor by FogFar. The defaults reproduce the original linear fade.
+var (
+	FogNear    = 200.0
+	FogFar     = 1400.0
+	FogFloor   = 0.25
+	FogExp     bool // exponential curve instead of linear
+	FogDensity = 2.0
+)
+
+// depthFade returns the brightness multiplier for a particle at depth. The
+// linear curve reaches 0 at FogFar; the exponential one decays as
+// exp(-FogDensity*t) with t the fraction of the near..far range. Both are
+// floored at FogFloor.
+func depthFade(depth float64) float64 {
+	t := (depth - FogNear) / (FogFar - FogNear)
+	var f float64
+	if FogExp {
+		f = math.Exp(-FogDensity * math.Max(t, 0))
+	} else {
+		f = 1.0 - t
+	}
+	return math.Max(f, FogFloor)
+}
+
+// adjustFog applies the runtime fog keys: Left/Right move the near plane,
+// Down/Up the far plane, [ and ] the floor, E switches the curve.
+func adjustFog() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		FogExp = !FogExp
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
+		FogNear = math.Max(FogNear-5, 0)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyRight) {
+		FogNear = math.Min(FogNear+5, FogFar-50)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyDown) {
+		FogFar = math.Max(FogFar-10, FogNear+50)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyUp) {
+		FogFar += 10
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+		FogFloor = math.Max(FogFloor-0.05, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+		FogFloor = math.Min(FogFloor+0.05, 1)
+	}
+}
+
+// FadeInTicks is how many ticks a new particle takes to ramp up to full
+// opacity (-fadein), so regenerating the cloud doesn't pop; 0 = off.
+var FadeInTicks = 15
+
+func init() {
+	img, _, err := image.Decode(bytes.NewReader(images.Smoke_png))
+	if err != nil {
+		log.Fatal(err)
+	}
+	smokeImage = ebiten.NewImageFromImage(img)
+}
+
+// Particle holds a simple 3D particle
+type Particle struct {
+	// 3D position
+	x, y, z float64
+	// 3D velocity
+	vx, vy, vz float64
+
+	angle float64
+	spin  float64
+
+	baseScale float64
+
+	life    int
+	maxLife int
+
+	colorMix color.RGBA
+	img      *ebiten.Image
+}
+
+// NewParticle creates a particle inside a spherical cloud around origin,
+// drawing every random value from rng.
+func NewParticle(rng *rand.Rand, img *ebiten.Image) *Particle {
+	// random point in sphere
+	phi := rng.Float64() * 2 * math.Pi
+	costheta := rng.Float64()*2 - 1
+	u := rng.Float64()
+	r := worldRadius * math.Cbrt(u) // uniform in sphere by cube root
+
+	x := r * math.Cos(phi) * math.Sqrt(1-costheta*costheta)
+	y := r * math.Sin(phi) * math.Sqrt(1-costheta*costheta)
+	z := r * costheta
+
+	// small random outward velocity
+	speed := rng.Float64()*0.6 + 0.1
+	vx := x / (worldRadius + 1) * speed * 0.8
+	vy := y / (worldRadius + 1) * speed * 0.8
+	vz := z / (worldRadius + 1) * speed * 0.8
+
+	maxLife := 80 + rng.Intn(160)
+
+	return &Particle{
+		x:         x,
+		y:         y,
+		z:         z,
+		vx:        vx,
+		vy:        vy,
+		vz:        vz,
+		angle:     rng.Float64() * 2 * math.Pi,
+		spin:      (rng.Float64()*2 - 1) * 0.05,
+		baseScale: rng.Float64()*0.18 + 0.12,
+		life:      maxLife,
+		maxLife:   maxLife,
+		colorMix:  color.RGBA{uint8(180 + rng.Intn(60)), uint8(180 + rng.Intn(60)), 255, 255},
+		img:       img,
+	}
+}
+
+// update advances the particle one tick, pulling it toward target with an
+// acceleration of cohesion times its distance (0 = no pull).
+func (p *Particle) update(target [3]float64, cohesion float64) bool {
+	// simple motion; slight drift and damping
+	p.x += p.vx
+	p.y += p.vy
+	p.z += p.vz
+
+	// inward pull to keep cloud cohesive
+	p.vx += (target[0] - p.x) * cohesion
+	p.vy += (target[1] - p.y) * cohesion
+	p.vz += (target[2] - p.z) * cohesion
+
+	p.vx *= 0.995
+	p.vy *= 0.995
+	p.vz *= 0.995
+
+	// life
+	p.life--
+	p.angle += p.spin
+
+	return p.life > 0
+}
+
+// lifeAlpha is the particle's opacity from its age alone: ramping in over
+// its first FadeInTicks ticks and fading out over its whole life.
+func (p *Particle) lifeAlpha() float64 {
+	a := float64(p.life) / float64(p.maxLife)
+	if age := p.maxLife - p.life; age < FadeInTicks {
+		a *= float64(age) / float64(FadeInTicks)
+	}
+	return a
+}
+
+// projected returns screen x,y, scale, and depth (used for sorting).
+// cameraYaw and cameraPitch rotate the world before projection.
+func (p *Particle) projected(cameraYaw, cameraPitch float64) (sx, sy, scale, depth float64, visible bool) {
+	x1, y1, z2 := viewSpace(p.x, p.y, p.z, cameraYaw, cameraPitch)
+
+	// translate camera a bit back so particles are in front
+	z2 += 600 // move camera behind origin (increase for more depth)
+
+	// if behind camera or too close, not visible
+	if z2 <= 10 {
+		return 0, 0, 0, z2, false
+	}
+
+	// perspective projection
+	f := focalLength / z2
+	screenX := x1*f + ScreenWidth/2.0
+	screenY := y1*f + ScreenHeight/2.0
+
+	// scale by perspective and baseScale
+	scale = p.baseScale * f * 2.0 // multiplier to get pleasant sizes
+
+	// optionally clamp values for safety
+	if scale <= 0 || scale > 10 {
+		// still visible (but maybe very small/large); we can allow small values
+	}
+
+	// depth used for sorting: larger depth => farther from camera
+	depth = z2
+
+	return screenX, screenY, scale, depth, true
+}
+
+// viewSpace rotates a world point by the camera yaw and pitch, returning
+// its coordinates before the camera offset is applied.
+func viewSpace(x, y, z, cameraYaw, cameraPitch float64) (x1, y1, z2 float64) {
+	// rotate around Y (yaw) then X (pitch)
+	// rotation around Y:
+	siny := math.Sin(cameraYaw)
+	cosy := math.Cos(cameraYaw)
+	x1 = x*cosy + z*siny
+	z1 := -x*siny + z*cosy
+
+	// rotation around X (pitch)
+	sinp := math.Sin(cameraPitch)
+	cosp := math.Cos(cameraPitch)
+	y1 = y*cosp - z1*sinp
+	z2 = y*sinp + z1*cosp
+	return x1, y1, z2
+}
+
+// cloudStats is the world-space extent of the cloud: per-axis minimum,
+// maximum and mean (the centroid) over x, y, z, and the bounding radius
+// (farthest particle from the centroid).
+type cloudStats struct {
+	min, max, mean [3]float64
+	radius         float64
+}
+
+// measureCloud computes cloudStats over ps; all zero when ps is empty.
+func measureCloud(ps []*Particle) cloudStats {
+	var s cloudStats
+	if len(ps) == 0 {
+		return s
+	}
+	for k := 0; k < 3; k++ {
+		s.min[k], s.max[k] = math.Inf(1), math.Inf(-1)
+	}
+	for _, p := range ps {
+		for k, v := range [3]float64{p.x, p.y, p.z} {
+			s.min[k] = math.Min(s.min[k], v)
+			s.max[k] = math.Max(s.max[k], v)
+			s.mean[k] += v
+		}
+	}
+	for k := 0; k < 3; k++ {
+		s.mean[k] /= float64(len(ps))
+	}
+	for _, p := range ps {
+		s.radius = math.Max(s.radius, math.Sqrt(sq(p.x-s.mean[0])+sq(p.y-s.mean[1])+sq(p.z-s.mean[2])))
+	}
+	return s
+}
+
+func sq(v float64) float64 { return v * v }
+
+// boxColor outlines the cloud's bounding box (B).
+var boxColor = color.RGBA{120, 200, 120, 200}
+
+// drawBounds draws the twelve edges of the bounding box, skipping any with
+// an end behind the camera.
+func (g *Game) drawBounds(screen *ebiten.Image) {
+	b := g.cloud
+	var corners [8][2]float64
+	var ok [8]bool
+	for i := range corners {
+		x, y, z := b.min[0], b.min[1], b.min[2]
+		if i&1 != 0 {
+			x = b.max[0]
+		}
+		if i&2 != 0 {
+			y = b.max[1]
+		}
+		if i&4 != 0 {
+			z = b.max[2]
+		}
+		x1, y1, z2 := viewSpace(x, y, z, g.cam.Yaw, g.cam.Pitch)
+		z2 += 600 // same camera offset as projected
+		if ok[i] = z2 > 10; ok[i] {
+			f := focalLength / z2
+			corners[i] = [2]float64{x1*f + ScreenWidth/2.0, y1*f + ScreenHeight/2.0}
+		}
+	}
+	// corners differing in exactly one bit share an edge
+	for i := range corners {
+		for _, bit := range []int{1, 2, 4} {
+			j := i | bit
+			if j == i || !ok[i] || !ok[j] {
+				continue
+			}
+			ebitenutil.DrawLine(screen, corners[i][0], corners[i][1], corners[j][0], corners[j][1], boxColor)
+		}
+	}
+}
+
+type Game struct {
+	particles []*Particle
+	cam       *orbitcam.Camera // -orbit-speed, -pitch-amp
+	rng       *rand.Rand       // drives every spawn
+
+	// Camera and spawn cadence follow wall-clock time, not ticks
+	lastUpdate time.Time
+	spawnAcc   float64 // seconds accumulated toward the next spawn
+
+	cloud      cloudStats // measured each Update
+	showBounds bool       // outline the bounding box (B)
+
+	// cohesion pulls particles toward the centroid, or the origin with
+	// cohesionOrigin (O); -/= adjust it, 0 = off
+	cohesion       float64
+	cohesionOrigin bool
+}
+
+// Options configures a game built by NewGame.
+type Options struct {
+	Seed     int64            // seeds every spawn
+	Cohesion float64          // starting cohesion strength, 0 to MaxCohesion
+	Camera   *orbitcam.Camera // nil = orbitcam.New()
+}
+
+// DefaultOptions returns the demo's starting settings.
+func DefaultOptions() Options {
+	return Options{Seed: 1}
+}
+
+// NewGame returns an empty cloud configured by opts; it fills in as the
+// game steps.
+func NewGame(opts Options) *Game {
+	cam := opts.Camera
+	if cam == nil {
+		cam = orbitcam.New()
+	}
+	return &Game{cohesion: opts.Cohesion, cam: cam, rng: rand.New(rand.NewSource(opts.Seed))}
+}
+
+// Count is the number of live particles.
+func (g *Game) Count() int {
+	return len(g.particles)
+}
+
+// cohesionStep is how much -/= change the cohesion strength. The pull is a
+// spring, so particles swing through the target every 2π/√k ticks;
+// MaxCohesion keeps that swing slower than about 90 ticks.
+const (
+	cohesionStep = 0.0001
+	MaxCohesion  = 0.005
+)
+
+// cohesionTarget is the point particles are pulled toward this tick.
+func (g *Game) cohesionTarget() [3]float64 {
+	if g.cohesionOrigin {
+		return [3]float64{}
+	}
+	return g.cloud.mean
+}
+
+func (g *Game) spawn(n int) {
+	for i := 0; i < n && len(g.particles) < maxParticles; i++ {
+		g.particles = append(g.particles, NewParticle(g.rng, smokeImage))
+	}
+}
+
+func (g *Game) Update() error {
+	now := time.Now()
+	dt := 0.0
+	if !g.lastUpdate.IsZero() {
+		dt = math.Min(now.Sub(g.lastUpdate).Seconds(), maxFrameDelta)
+	}
+	g.lastUpdate = now
+
+	adjustFog()
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		g.showBounds = !g.showBounds
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) {
+		g.cohesion = math.Min(g.cohesion+cohesionStep, MaxCohesion)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyMinus) {
+		g.cohesion = math.Max(g.cohesion-cohesionStep, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		g.cohesionOrigin = !g.cohesionOrigin
+	}
+
+	g.step(dt)
+	return nil
+}
+
+// step advances the simulation by dt seconds without reading input.
+func (g *Game) step(dt float64) {
+	// spawn
+	g.spawnAcc += dt
+	for g.spawnAcc >= spawnInterval {
+		g.spawn(spawnPerTick)
+		g.spawnAcc -= spawnInterval
+	}
+
+	// animate camera slowly
+	g.cam.Update(dt)
+
+	// update particles and compact slice in place
+	target := g.cohesionTarget()
+	write := 0
+	for _, p := range g.particles {
+		if p.update(target, g.cohesion) {
+			g.particles[write] = p
+			write++
+		}
+	}
+	g.particles = g.particles[:write]
+
+	// occasionally inject new ones from center so cloud regenerates
+	if len(g.particles) < maxParticles/3 {
+		g.spawn(40)
+	}
+	g.cloud = measureCloud(g.particles)
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	// background gradient-ish fill (single color for simplicity)
+	screen.Fill(color.RGBA{10, 14, 28, 255})
+
+	type drawItem struct {
+		p         *Particle
+		sx, sy    float64
+		scale     float64
+		depth     float64
+		alphaMult float64
+	}
+
+	items := make([]drawItem, 0, len(g.particles))
+
+	// Project particles and collect draw items
+	for _, p := range g.particles {
+		sx, sy, scale, depth, ok := p.projected(g.cam.Yaw, g.cam.Pitch)
+		if !ok {
+			continue
+		}
+		// life-based fade in and out (0..1)
+		// depth-based fade to simulate atmospheric depth (farther => dimmer)
+		alpha := p.lifeAlpha() * depthFade(depth)
+
+		items = append(items, drawItem{
+			p:         p,
+			sx:        sx,
+			sy:        sy,
+			scale:     scale,
+			depth:     depth,
+			alphaMult: alpha,
+		})
+	}
+
+	// depth sort: far -> near (draw far first)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].depth > items[j].depth // larger depth = farther
+	})
+
+	// draw items: farther first so nearer draw last (occlude)
+	for _, it := range items {
+		op := &ebiten.DrawImageOptions{}
+		w, h := it.p.img.Bounds().Dx(), it.p.img.Bounds().Dy()
+		op.GeoM.Translate(-float64(w)/2, -float64(h)/2)
+		// rotate with particle angle for visual variety
+		op.GeoM.Rotate(it.p.angle)
+		op.GeoM.Scale(it.scale, it.scale)
+		op.GeoM.Translate(it.sx, it.sy)
+
+		// color scale + alpha; base color + life/depth alpha
+		// Compute normalized components as float64 and clamp alpha into [0,1].
+		rf := float64(it.p.colorMix.R) / 255.0
+		gf := float64(it.p.colorMix.G) / 255.0
+		bf := float64(it.p.colorMix.B) / 255.0
+		a := it.alphaMult
+		if a < 0 {
+			a = 0
+		} else if a > 1 {
+			a = 1
+		}
+		// Use a temporary ColorM to set the color (ColorM.Scale expects float64 in this ebiten version)
+		cs := &ebiten.ColorM{}
+		cs.Scale(rf, gf, bf, a)
+		op.ColorM = *cs
+
+		screen.DrawImage(it.p.img, op)
+	}
+
+	if g.showBounds && len(g.particles) > 0 {
+		g.drawBounds(screen)
+	}
+
+	// HUD
+	curve := "linear"
+	if FogExp {
+		curve = fmt.Sprintf("exp (density %.1f)", FogDensity)
+	}
+	c := g.cloud
+	target := "centroid"
+	if g.cohesionOrigin {
+		target = "origin"
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("Particles: %d\nTPS: %.2f\nFog [E] %s  near %.0f [Left/Right]  far %.0f [Down/Up]  floor %.2f [ [ ] ]\nCentroid: (%.1f, %.1f, %.1f)  radius %.0f\nBounds [B]: %.0f x %.0f x %.0f\nCohesion [-/=]: %.4f toward %s [O]",
+		len(g.particles), ebiten.ActualTPS(), curve, FogNear, FogFar, FogFloor,
+		c.mean[0], c.mean[1], c.mean[2], c.radius, c.max[0]-c.min[0], c.max[1]-c.min[1], c.max[2]-c.min[2],
+		g.cohesion, target))
+}
+
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return ScreenWidth, ScreenHeight
+}
@@ -0,0 +1,87 @@
+package advparticles
+
+import (
+	"math"
+	"testing"
+)
+
+// stepDelta is the seconds per step the tests advance the game by.
+const stepDelta = 1.0 / 60
+
+// peakRadius steps a cloud from seed 1 with the given cohesion for ticks
+// ticks and returns the largest bounding radius seen after the first
+// second, and the tick it was seen on.
+func peakRadius(ticks int, cohesion float64) (peak float64, at int) {
+	g := NewGame(Options{Seed: 1, Cohesion: cohesion})
+	for t := 0; t < ticks; t++ {
+		g.step(stepDelta)
+		if t >= 60 && g.cloud.radius > peak {
+			peak, at = g.cloud.radius, t
+		}
+	}
+	return peak, at
+}
+
+// TestCohesion checks that a cohesion pull keeps the cloud's bounding
+// radius bounded. Particles spawn up to worldRadius out and a spring can't
+// pull them inside that, only stop the outward drift past it.
+func TestCohesion(t *testing.T) {
+	const ticks, k = 1800, 0.001
+	free, _ := peakRadius(ticks, 0)
+	peak, at := peakRadius(ticks, k)
+	t.Logf("bounding radius peaked at %.1f with cohesion %g, %.1f without", peak, k, free)
+	if limit := 1.15 * worldRadius; peak > limit {
+		t.Errorf("tick %d: bounding radius %.1f exceeds %.1f", at, peak, limit)
+	}
+}
+
+// TestFadeIn steps the same seeded cloud with and without the fade-in and
+// tracks the summed life opacity of all particles. With it no particle may
+// gain more than 1/FadeInTicks of opacity in a tick, and the cloud's
+// largest one-tick brightening, where a refill lands, must come out lower
+// than without it.
+func TestFadeIn(t *testing.T) {
+	const ticks = 1800
+	run := func(fade int) (jump float64, refills int) {
+		saved := FadeInTicks
+		FadeInTicks = fade
+		defer func() { FadeInTicks = saved }()
+		g := NewGame(DefaultOptions())
+		prev := 0.0
+		seen := make(map[*Particle]float64)
+		for tick := 0; tick < ticks; tick++ {
+			g.step(stepDelta)
+			sum, fresh := 0.0, 0
+			next := make(map[*Particle]float64, len(g.particles))
+			for _, p := range g.particles {
+				a := p.lifeAlpha()
+				sum += a
+				next[p] = a
+				if p.maxLife-p.life == 1 {
+					fresh++
+				}
+				if fade > 0 && a-seen[p] > 1/float64(fade)+1e-12 {
+					t.Fatalf("fade-in %d, tick %d: particle aged %d jumped from opacity %.3f to %.3f", fade, tick, p.maxLife-p.life, seen[p], a)
+				}
+			}
+			seen = next
+			if fresh >= 40 { // the refill, not the steady spawnPerTick
+				refills++
+			}
+			if tick > 0 {
+				jump = math.Max(jump, sum-prev)
+			}
+			prev = sum
+		}
+		return jump, refills
+	}
+	faded, refills := run(FadeInTicks)
+	popped, _ := run(0)
+	t.Logf("%d refills: largest one-tick brightening %.1f with a %d-tick fade-in, %.1f without", refills, faded, FadeInTicks, popped)
+	if refills == 0 {
+		t.Fatalf("the cloud never refilled in %d ticks", ticks)
+	}
+	if faded >= popped {
+		t.Errorf("fade-in didn't soften the refill: %.1f vs %.1f", faded, popped)
+	}
+}
@@ -0,0 +1,2563 @@
+// Package amazing is the amazing demo's concert particle show: orbiting
+// fire and ember emitters feeding a pooled particle system drawn in depth
+// layers, with an attractor chain, kill zones, quality presets, gamepad
+// control, show scripts, shape emitters, state dumps and spawn recordings
+// that replay exactly. The demo only reads flags, applies them to the
+// package settings and a Game, and runs it.
+package amazing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/arcesoftware/GO_Examples/outline"
+	"github.com/arcesoftware/GO_Examples/palette"
+	"github.com/arcesoftware/GO_Examples/spawnlog"
+	"github.com/arcesoftware/GO_Examples/splitmix"
+	"github.com/arcesoftware/GO_Examples/spritebatch"
+)
+
+const (
+	ScreenWidth  = 1280
+	ScreenHeight = 720
+	defaultTexW  = 36
+	defaultTexH  = 36
+	MaxEmitters  = 10
+
+	// DefaultMaxParticles is the pool size unless -max overrides it, and
+	// MaxPoolSize bounds -max. Pools past spritebatch.MaxQuads draw in several
+	// calls per blend mode.
+	DefaultMaxParticles = 14000
+	MaxPoolSize         = 4 * spritebatch.MaxQuads
+
+	// Default spawn caps (-spawncap, -emittercap); 0 disables a cap.
+	DefaultSpawnPerFrame = 200 // soft cap per frame (emitters modulate actual spawns)
+	DefaultEmitterCap    = 250 // per emitter per frame, to avoid pool exhaustion
+
+	// Emitter spawns are queued and drained at most DefaultSpawnDrain per
+	// tick (-spawndrain), so pulse peaks and surprise bursts spread over
+	// several frames; past maxPendingSpawns queued requests are dropped.
+	DefaultSpawnDrain = 150
+	maxPendingSpawns  = 4000
+)
+
+var (
+	fireImage  *ebiten.Image
+	fireImageW float64
+	fireImageH float64
+
+	// RunInBackground keeps simulating while the window is unfocused
+	// (-background).
+	RunInBackground bool
+
+	// ParallaxStrength is the horizontal shift in pixels of the nearest
+	// layer per unit of camera wobble (-parallax).
+	ParallaxStrength = 120.0
+
+	// MaxParticles is the pooled particle capacity (-max); set before
+	// NewGame.
+	MaxParticles = DefaultMaxParticles
+
+	// FireEmitters and EmberEmitters are how many emitters of each kind
+	// NewGame builds (-fire-emitters, -ember-emitters); together at most
+	// MaxEmitters.
+	FireEmitters  = 6
+	EmberEmitters = 3
+
+	// StateFile is where the S key dumps the simulation state (-statefile).
+	StateFile = "amazing.state.json"
+)
+
+// parallaxLayers is how many discrete depth planes the parallax uses.
+const parallaxLayers = 4
+
+// parallaxShift is the horizontal screen offset for a particle at depth z
+// (-2 far .. +2 near). Depths snap to parallaxLayers planes; the farthest
+// plane stays put and nearer planes move proportionally more with the wobble.
+func parallaxShift(z, depthOffset float64) float64 {
+	near := math.Max(0, math.Min(1, (z+2)/4))
+	layer := math.Round(near*(parallaxLayers-1)) / (parallaxLayers - 1)
+	return layer * depthOffset * ParallaxStrength
+}
+
+func init() {
+	blueNoise = bestCandidate(rand.New(rand.NewSource(1)), blueNoiseSize, 12)
+}
+
+// blueNoiseSize is the length of the precomputed spawn-offset sequence.
+const blueNoiseSize = 256
+
+// blueNoise holds well-spaced offsets in [-1,1]^2, cycled per spawn when
+// blue-noise jitter is on.
+var blueNoise [][2]float64
+
+// bestCandidate generates n points in [-1,1]^2 with Mitchell's best-candidate
+// algorithm: each new point is the one of k random candidates farthest from
+// all previous points (with wraparound), so every prefix of the sequence is
+// evenly spread. Candidates are drawn from rng.
+func bestCandidate(rng *rand.Rand, n, k int) [][2]float64 {
+	pts := make([][2]float64, 0, n)
+	wrapDist := func(a, b [2]float64) float64 {
+		dx := math.Abs(a[0] - b[0])
+		dy := math.Abs(a[1] - b[1])
+		dx = math.Min(dx, 2-dx)
+		dy = math.Min(dy, 2-dy)
+		return dx*dx + dy*dy
+	}
+	for len(pts) < n {
+		var best [2]float64
+		bestD := -1.0
+		for c := 0; c < k; c++ {
+			cand := [2]float64{rng.Float64()*2 - 1, rng.Float64()*2 - 1}
+			d := math.Inf(1)
+			for _, p := range pts {
+				d = math.Min(d, wrapDist(cand, p))
+			}
+			if d > bestD {
+				best, bestD = cand, d
+			}
+		}
+		pts = append(pts, best)
+	}
+	return pts
+}
+
+// FireTexture is the radial particle texture with the given falloff
+// exponent.
+func FireTexture(falloff float64) *image.RGBA {
+	return spritebatch.RadialAlpha(defaultTexW, defaultTexH, falloff)
+}
+
+// LoadTextures builds the particle texture with the given falloff exponent.
+func LoadTextures(falloff float64) {
+	fireImage = ebiten.NewImageFromImage(FireTexture(falloff))
+	fireImageW = float64(fireImage.Bounds().Dx())
+	fireImageH = float64(fireImage.Bounds().Dy())
+}
+
+// Particle types: two flavors for variety
+type PKind int
+
+const (
+	KindFire PKind = iota
+	KindEmber
+)
+
+// valid reports whether k is one of the kinds above, for input read from
+// files.
+func (k PKind) valid() bool { return k == KindFire || k == KindEmber }
+
+func (k PKind) String() string {
+	if k == KindEmber {
+		return "ember"
+	}
+	return "fire"
+}
+
+// Layer is a depth group. Emitters belong to one and their particles are
+// drawn in its pass, back to front, each layer with its own dimming and
+// blur (layerStyles). Mid is the zero value, so state dumps from before
+// layers load there.
+type Layer int
+
+const (
+	LayerMid Layer = iota
+	LayerBack
+	LayerFront
+	numLayers
+)
+
+// layerOrder is the draw order, back to front.
+var layerOrder = [numLayers]Layer{LayerBack, LayerMid, LayerFront}
+
+func (l Layer) String() string {
+	switch l {
+	case LayerBack:
+		return "back"
+	case LayerFront:
+		return "front"
+	}
+	return "mid"
+}
+
+// valid reports whether l is one of the layers above, for input read from
+// files.
+func (l Layer) valid() bool { return l >= 0 && l < numLayers }
+
+// layerByName returns the layer whose String is name.
+func layerByName(name string) (Layer, bool) {
+	for _, l := range layerOrder {
+		if l.String() == name {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// layerForDepth puts an emitter orbiting at depth cz (positive toward the
+// camera) in the back, mid or front layer.
+func layerForDepth(cz float64) Layer {
+	switch {
+	case cz < -0.2:
+		return LayerBack
+	case cz > 0.2:
+		return LayerFront
+	}
+	return LayerMid
+}
+
+type Particle struct {
+	x, y, z           float64
+	vx, vy, vz        float64
+	lifetime, maxLife int
+	baseScale         float64
+	angle             float64
+	angularVelocity   float64
+	kind              PKind
+	layer             Layer // inherited from the emitter
+	active            bool
+
+	// brightness flicker: phase offset and angular frequency (rad/s)
+	flickerPhase, flickerFreq float64
+
+	// attractor chain: 1 + index of the chain point being steered toward;
+	// 0 until the particle first joins the chain
+	chainNext int
+
+	// kill-zone fade: 0 until the particle enters a kill zone, then rising
+	// to 1, where it is deactivated
+	killed float64
+
+	// noise seed set at spawn: the ember wobble is splitmix.Noise(seed,
+	// lifetime), so it needs no RNG state and doesn't touch the shared
+	// generator
+	seed uint64
+}
+
+// Flicker depth per kind: alpha is scaled by 1-amp+amp*sin(phase+t*freq),
+// i.e. 0.8+0.2*sin(...) for fire. Embers glow more steadily.
+const (
+	fireFlickerAmp  = 0.2
+	emberFlickerAmp = 0.05
+)
+
+// flickerFactor returns the brightness multiplier for p at time t (seconds).
+func (p *Particle) flickerFactor(t float64) float32 {
+	amp := fireFlickerAmp
+	if p.kind == KindEmber {
+		amp = emberFlickerAmp
+	}
+	return float32(1 - amp + amp*math.Sin(p.flickerPhase+t*p.flickerFreq))
+}
+
+// KindMaxSpeed caps each kind's on-screen speed in pixels per tick
+// (-maxspeed-fire, -maxspeed-ember; 0 = no cap), so chain pulls and bursts
+// can't push a particle across the screen in one frame. The caps sit just
+// above what spawn speed plus buoyancy reach on their own.
+var KindMaxSpeed = [...]float64{
+	KindFire:  12,
+	KindEmber: 5,
+}
+
+func (p *Particle) update() {
+	if !p.active {
+		return
+	}
+	p.lifetime++
+	if p.lifetime >= p.maxLife {
+		p.active = false
+		return
+	}
+	// natural forces vary by kind; applied before moving (semi-implicit
+	// Euler) so the position step uses the updated velocity
+	if p.kind == KindFire {
+		// slight upward acceleration and drag
+		p.vy -= 0.015
+		p.vx *= 0.998
+		p.vy *= 0.999
+		p.vz *= 0.994
+	} else {
+		// embers: float upwards slowly, fade with wobble
+		p.vy -= 0.01
+		p.vx += splitmix.Noise(p.seed, uint64(p.lifetime)) * 0.02
+		p.vz *= 0.995
+	}
+	// clamp |(vx, vy)| keeping the direction; vz is in depth units and
+	// left alone
+	if limit := KindMaxSpeed[p.kind]; limit > 0 {
+		if sp := math.Hypot(p.vx, p.vy); sp > limit {
+			p.vx, p.vy = p.vx*limit/sp, p.vy*limit/sp
+		}
+	}
+
+	p.x += p.vx
+	p.y += p.vy
+	p.z += p.vz
+	p.angle += p.angularVelocity
+}
+
+// Emitter: autonomous, moves along a path and pulses
+type Emitter struct {
+	cx, cy     float64 // center of orbit
+	radius     float64
+	phase      float64
+	speed      float64
+	baseSpawn  int     // base spawn per pulse
+	pulseWidth float64 // pulse frequency component
+	kind       PKind
+	offsetY    float64 // vertical offset for layout
+	cz         float64 // depth of the orbit center (3D mode)
+	layer      Layer   // draw pass its particles go to
+}
+
+// position returns where the emitter sits at the given orbit phase: a
+// Lissajous-style loop around its center, squashed vertically.
+func (e *Emitter) position(phase float64) (x, y float64) {
+	angle := phase*2*math.Pi + phase*1.1
+	x = e.cx + math.Cos(angle)*e.radius
+	y = e.cy + math.Sin(angle*0.9)*e.radius*0.55 + e.offsetY
+	return x, y
+}
+
+// emitterDepthSwing is how far (depth units) an emitter's orbit swings
+// toward and away from the camera in 3D mode.
+const emitterDepthSwing = 0.6
+
+// depth returns the emitter's depth at the given orbit phase. It closes
+// every 4π of angle, so the orbit still repeats after pathPhaseCycle.
+func (e *Emitter) depth(phase float64) float64 {
+	angle := phase*2*math.Pi + phase*1.1
+	return e.cz + math.Sin(angle*0.5)*emitterDepthSwing
+}
+
+// 3D mode camera: x/y are pixels on the focal plane (z = 0), which sits
+// camDist in front of the camera and projects 1:1; z is depth in units of
+// worldDepth pixels, positive toward the camera.
+const (
+	camDist    = 600.0
+	camNear    = 40.0 // closer than this to the camera is not drawn
+	worldDepth = 250.0
+)
+
+// project maps a world position to the screen for the 3D mode and returns
+// the perspective scale. The camera yaws by yaw around the screen center,
+// so near and far particles slide in opposite directions.
+func project(x, y, z, yaw float64) (sx, sy, f float64, ok bool) {
+	wx := x - ScreenWidth/2
+	wy := y - ScreenHeight/2
+	wz := z * worldDepth
+	sin, cos := math.Sincos(yaw)
+	x1 := wx*cos + wz*sin
+	z1 := -wx*sin + wz*cos
+	d := camDist - z1
+	if d < camNear {
+		return 0, 0, 0, false
+	}
+	f = camDist / d
+	return x1*f + ScreenWidth/2, wy*f + ScreenHeight/2, f, true
+}
+
+// cameraYaw turns the parallax wobble into a camera yaw such that the
+// nearest plane the 2D mode uses (z = 2) moves ParallaxStrength pixels per
+// unit of wobble, as it does in 2D.
+func (g *Game) cameraYaw() float64 {
+	return g.depthOffset * ParallaxStrength / (2 * worldDepth)
+}
+
+// emitterScreenPos is where the emitter appears at the given phase: its
+// orbit position, projected in 3D mode.
+func (g *Game) emitterScreenPos(e *Emitter, phase float64) (x, y float64) {
+	x, y = e.position(phase)
+	if g.World3D {
+		if sx, sy, _, ok := project(x, y, e.depth(phase), g.cameraYaw()); ok {
+			return sx, sy
+		}
+	}
+	return x, y
+}
+
+// pathPhaseCycle is the phase span after which every orbit repeats: x closes
+// every 2π of angle and y every 2π/0.9, so both close after 20π.
+const pathPhaseCycle = 20 * math.Pi / (2*math.Pi + 1.1)
+
+// pathSamples is how many segments the debug path preview draws per emitter.
+const pathSamples = 720
+
+// drawEmitterPaths overlays each emitter's full orbit as a faint line and
+// marks its current position with its index.
+func (g *Game) drawEmitterPaths(screen *ebiten.Image) {
+	pathColor := color.RGBA{60, 60, 90, 90}
+	markColor := color.RGBA{255, 255, 255, 255}
+	for i, e := range g.emitters {
+		px, py := g.emitterScreenPos(e, 0)
+		for s := 1; s <= pathSamples; s++ {
+			x, y := g.emitterScreenPos(e, pathPhaseCycle*float64(s)/pathSamples)
+			ebitenutil.DrawLine(screen, px, py, x, y, pathColor)
+			px, py = x, y
+		}
+		ex, ey := g.emitterScreenPos(e, e.phase)
+		ebitenutil.DrawRect(screen, ex-3, ey-3, 6, 6, markColor)
+		ebitenutil.DebugPrintAt(screen, strconv.Itoa(i), int(ex)+6, int(ey)-8)
+	}
+}
+
+// Emitter nudging: center and radius move this many pixels per frame while
+// their key is held; each speed key press scales the orbit speed.
+const (
+	nudgeStep      = 2.0
+	nudgeSpeedStep = 1.1
+)
+
+// nudgeEmitter applies the tuning keys to the selected emitter: arrows move
+// its orbit center, , and . shrink and grow the orbit, ; and ' slow and
+// speed it up, Z moves it to the next layer, and Enter logs its settings in
+// the state-dump format. The same changes go to its initial configuration
+// so R keeps them.
+func (g *Game) nudgeEmitter() {
+	if g.selected < 0 || g.selected >= len(g.emitters) {
+		return
+	}
+	e := g.emitters[g.selected]
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		e.cx -= nudgeStep
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		e.cx += nudgeStep
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		e.cy -= nudgeStep
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		e.cy += nudgeStep
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyComma) {
+		e.radius = math.Max(e.radius-nudgeStep, 0)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyPeriod) {
+		e.radius += nudgeStep
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeySemicolon) {
+		e.speed /= nudgeSpeedStep
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyQuote) {
+		e.speed *= nudgeSpeedStep
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
+		e.layer = (e.layer + 1) % numLayers
+	}
+	if g.selected < len(g.initialEmitters) {
+		start := &g.initialEmitters[g.selected]
+		start.cx, start.cy, start.radius, start.speed, start.layer = e.cx, e.cy, e.radius, e.speed, e.layer
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		b, err := json.Marshal(e.state())
+		if err != nil {
+			log.Printf("emitter %d: %v", g.selected, err)
+			return
+		}
+		log.Printf("emitter %d: %s", g.selected, b)
+	}
+}
+
+// drawSelectedEmitter rings the selected emitter, marks its orbit center
+// and lists the tunable settings beside it.
+func (g *Game) drawSelectedEmitter(screen *ebiten.Image) {
+	if g.selected < 0 || g.selected >= len(g.emitters) {
+		return
+	}
+	e := g.emitters[g.selected]
+	selColor := color.RGBA{255, 220, 60, 255}
+	ex, ey := g.emitterScreenPos(e, e.phase)
+	for s := 0; s < 24; s++ {
+		a0, a1 := float64(s)*math.Pi/12, float64(s+1)*math.Pi/12
+		ebitenutil.DrawLine(screen, ex+10*math.Cos(a0), ey+10*math.Sin(a0), ex+10*math.Cos(a1), ey+10*math.Sin(a1), selColor)
+	}
+	// the orbit center sits at the emitter's base depth; project it like
+	// the emitter itself so the cross stays inside the ring's orbit in 3D
+	cx, cy := e.cx, e.cy+e.offsetY
+	if g.World3D {
+		if sx, sy, _, ok := project(cx, cy, e.cz, g.cameraYaw()); ok {
+			cx, cy = sx, sy
+		}
+	}
+	ebitenutil.DrawLine(screen, cx-6, cy, cx+6, cy, selColor)
+	ebitenutil.DrawLine(screen, cx, cy-6, cx, cy+6, selColor)
+	info := fmt.Sprintf("emitter %d/%d\ncenter [arrows]: %.0f, %.0f\nradius [,/.]: %.0f\nspeed [;/']: %.5f\nlayer [Z]: %v\n[Enter] print  [Tab] next",
+		g.selected, len(g.emitters), e.cx, e.cy, e.radius, e.speed, e.layer)
+	ebitenutil.DebugPrintAt(screen, info, int(ex)+14, int(ey)-8)
+}
+
+// Attractor chain: particles are pulled toward the next point in an ordered,
+// user-placed list, advancing along it like a ribbon.
+const (
+	chainPull  = 0.35 // acceleration toward the current target, px/tick^2
+	chainReach = 30.0 // distance at which a particle moves on to the next point
+)
+
+type point struct{ x, y float64 }
+
+// pendingSpawn is a queued spawnAt request.
+type pendingSpawn struct {
+	x, y, z float64
+	kind    PKind
+	layer   Layer
+}
+
+// steerAlongChain accelerates p toward its next chain point. A particle joins
+// at the nearest point, moves on when within chainReach, and flies free
+// after the last one.
+func (g *Game) steerAlongChain(p *Particle) {
+	if len(g.chain) == 0 {
+		return
+	}
+	if p.chainNext == 0 {
+		best := math.Inf(1)
+		for i, c := range g.chain {
+			if d := math.Hypot(c.x-p.x, c.y-p.y); d < best {
+				best, p.chainNext = d, i+1
+			}
+		}
+	}
+	for p.chainNext <= len(g.chain) {
+		c := g.chain[p.chainNext-1]
+		dx, dy := c.x-p.x, c.y-p.y
+		d := math.Hypot(dx, dy)
+		if d < chainReach {
+			p.chainNext++
+			continue
+		}
+		p.vx += dx / d * chainPull
+		p.vy += dy / d * chainPull
+		return
+	}
+}
+
+// addChainPoint appends pt to the attractor chain.
+func (g *Game) addChainPoint(pt point) {
+	g.record(spawnlog.Event{Op: "chain", X: pt.x, Y: pt.y})
+	g.chain = append(g.chain, pt)
+}
+
+// clearChain removes every chain point and lets particles rejoin from
+// scratch when a new chain is placed.
+func (g *Game) clearChain() {
+	g.record(spawnlog.Event{Op: "clearchain"})
+	g.chain = g.chain[:0]
+	for _, p := range g.particles {
+		p.chainNext = 0
+	}
+}
+
+// Kill zones: screen rectangles that fade out and remove any particle that
+// enters them, for masking particles behind scenery or dissolve edges.
+type killZone struct {
+	x0, y0, x1, y1 float64
+}
+
+func (z killZone) contains(x, y float64) bool {
+	return x >= z.x0 && x < z.x1 && y >= z.y0 && y < z.y1
+}
+
+// addKillZone adds z to the kill zones.
+func (g *Game) addKillZone(z killZone) {
+	g.record(spawnlog.Event{Op: "zone", Zone: [4]float64{z.x0, z.y0, z.x1, z.y1}})
+	g.killZones = append(g.killZones, z)
+}
+
+// removeKillZone removes the most recently added kill zone, if any.
+func (g *Game) removeKillZone() {
+	if len(g.killZones) == 0 {
+		return
+	}
+	g.record(spawnlog.Event{Op: "unzone"})
+	g.killZones = g.killZones[:len(g.killZones)-1]
+}
+
+// killFadeTicks is how long a particle takes to fade out once it enters a
+// kill zone.
+const killFadeTicks = 12
+
+// applyKillZones starts the fade of every particle inside a zone and
+// advances the fades already running. Zones are tested against where the
+// particle is drawn, so in 3D mode against its projected position.
+func (g *Game) applyKillZones(p *Particle) {
+	if p.killed == 0 {
+		x, y := p.x, p.y
+		if g.World3D {
+			var ok bool
+			if x, y, _, ok = project(p.x, p.y, p.z, g.cameraYaw()); !ok {
+				return
+			}
+		}
+		for _, z := range g.killZones {
+			if z.contains(x, y) {
+				p.killed = 1.0 / killFadeTicks
+				return
+			}
+		}
+		return
+	}
+	p.killed += 1.0 / killFadeTicks
+	if p.killed >= 1 {
+		p.active = false
+	}
+}
+
+// drawKillZones outlines the kill zones, and the one being dragged out,
+// while they are being edited.
+func (g *Game) drawKillZones(screen *ebiten.Image) {
+	fill := color.RGBA{60, 0, 0, 60}
+	for _, z := range g.killZones {
+		ebitenutil.DrawRect(screen, z.x0, z.y0, z.x1-z.x0, z.y1-z.y0, fill)
+	}
+	if g.zoneDragging {
+		z := g.draggedZone()
+		ebitenutil.DrawRect(screen, z.x0, z.y0, z.x1-z.x0, z.y1-z.y0, color.RGBA{120, 20, 20, 90})
+	}
+}
+
+// draggedZone is the zone spanned from where the drag started to the cursor.
+func (g *Game) draggedZone() killZone {
+	mx, my := ebiten.CursorPosition()
+	x, y := float64(mx), float64(my)
+	return killZone{
+		math.Min(g.zoneStart.x, x), math.Min(g.zoneStart.y, y),
+		math.Max(g.zoneStart.x, x), math.Max(g.zoneStart.y, y),
+	}
+}
+
+// drawChain shows the attractor chain faintly while it is being edited.
+func (g *Game) drawChain(screen *ebiten.Image) {
+	lineColor := color.RGBA{90, 140, 200, 110}
+	for i, c := range g.chain {
+		if i > 0 {
+			prev := g.chain[i-1]
+			ebitenutil.DrawLine(screen, prev.x, prev.y, c.x, c.y, lineColor)
+		}
+		ebitenutil.DrawRect(screen, c.x-2, c.y-2, 4, 4, lineColor)
+	}
+}
+
+// Follow emitter: a fire emitter that tracks the mouse cursor, or the
+// gamepad's left stick while it is pushed, for playing the show by hand.
+const (
+	followSpawn      = 8    // fire particles per tick at intensity 1
+	followStickSpeed = 12.0 // px per frame at full stick deflection
+	stickDeadzone    = 0.15 // stick deflection below this reads as centered
+)
+
+// Gamepad intensity steps: D-pad up and down scale the spawn rate by
+// intensityStep within [minIntensity, maxIntensity].
+const (
+	intensityStep = 1.25
+	minIntensity  = 0.25
+	maxIntensity  = 4.0
+)
+
+// depthPalettes are the ramps particles are colored with by depth (V, D-pad
+// left and right): the show's own, then the palette presets.
+var depthPalettes = append([]string{"depth"}, palette.Names()...)
+
+// cyclePalette moves step entries through depthPalettes, wrapping around.
+func (g *Game) cyclePalette(step int) {
+	n := len(depthPalettes)
+	g.paletteIdx = ((g.paletteIdx+step)%n + n) % n
+	if g.paletteIdx == 0 {
+		g.ramp = depthRamp
+	} else {
+		g.ramp = palette.Presets[depthPalettes[g.paletteIdx]]
+	}
+}
+
+// randomSuperBurst fires a 1200-particle burst somewhere in the lower
+// two thirds of the screen.
+func (g *Game) randomSuperBurst() {
+	px := float64(g.rng.Intn(ScreenWidth))
+	py := float64(g.rng.Intn(ScreenHeight/2) + ScreenHeight/3)
+	g.spawnBurst(px, py, 1200)
+}
+
+// padInput is one frame of gamepad input, merged over every connected pad
+// with the standard layout. Step fields are -1, 0 or +1.
+type padInput struct {
+	stickX, stickY float64 // left stick, zero inside the deadzone
+	toggleFollow   bool    // left stick press
+	burstFollow    bool    // right trigger: super-burst at the follow emitter
+	burstRandom    bool    // left trigger: super-burst at random, as Space
+	paletteStep    int     // D-pad left and right
+	intensityStep  int     // D-pad down and up
+	reset          bool    // start
+}
+
+// readPads polls the connected gamepads. Pads without the standard layout
+// are listed (for the HUD) but not read; with no pads the input is empty and
+// the keyboard and mouse work alone.
+func (g *Game) readPads() padInput {
+	var in padInput
+	g.pads = ebiten.AppendGamepadIDs(g.pads[:0])
+	pressed := func(id ebiten.GamepadID, b ebiten.StandardGamepadButton) bool {
+		return inpututil.IsStandardGamepadButtonJustPressed(id, b)
+	}
+	step := func(down, up bool) int {
+		switch {
+		case up && !down:
+			return 1
+		case down && !up:
+			return -1
+		}
+		return 0
+	}
+	for _, id := range g.pads {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+		y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+		if math.Hypot(x, y) > stickDeadzone {
+			in.stickX += x
+			in.stickY += y
+		}
+		in.toggleFollow = in.toggleFollow || pressed(id, ebiten.StandardGamepadButtonLeftStick)
+		in.burstFollow = in.burstFollow || pressed(id, ebiten.StandardGamepadButtonFrontBottomRight)
+		in.burstRandom = in.burstRandom || pressed(id, ebiten.StandardGamepadButtonFrontBottomLeft)
+		in.paletteStep += step(pressed(id, ebiten.StandardGamepadButtonLeftLeft), pressed(id, ebiten.StandardGamepadButtonLeftRight))
+		in.intensityStep += step(pressed(id, ebiten.StandardGamepadButtonLeftBottom), pressed(id, ebiten.StandardGamepadButtonLeftTop))
+		in.reset = in.reset || pressed(id, ebiten.StandardGamepadButtonCenterRight)
+	}
+	return in
+}
+
+// applyPad acts on one frame of gamepad input. Pushing the stick turns the
+// follow emitter on and steers it, clamped to the screen; pressing the
+// stick turns it off again.
+func (g *Game) applyPad(in padInput) {
+	if in.reset {
+		g.reset()
+	}
+	if in.toggleFollow {
+		g.followOn = !g.followOn
+	} else if in.stickX != 0 || in.stickY != 0 {
+		g.followOn = true
+		g.follow.x = math.Min(math.Max(g.follow.x+in.stickX*followStickSpeed, 0), ScreenWidth)
+		g.follow.y = math.Min(math.Max(g.follow.y+in.stickY*followStickSpeed, 0), ScreenHeight)
+	}
+	if in.burstFollow {
+		g.spawnBurst(g.follow.x, g.follow.y, 1200)
+	}
+	if in.burstRandom {
+		g.randomSuperBurst()
+	}
+	if in.paletteStep != 0 {
+		g.cyclePalette(in.paletteStep)
+	}
+	switch {
+	case in.intensityStep > 0:
+		g.intensity = math.Min(g.intensity*intensityStep, maxIntensity)
+	case in.intensityStep < 0:
+		g.intensity = math.Max(g.intensity/intensityStep, minIntensity)
+	}
+}
+
+// followMouse moves the follow emitter to the cursor whenever the mouse
+// moves, so the mouse and the stick can take turns steering it.
+func (g *Game) followMouse() {
+	mx, my := ebiten.CursorPosition()
+	if c := (point{float64(mx), float64(my)}); c != g.lastCursor {
+		g.lastCursor = c
+		g.follow = c
+	}
+}
+
+// padStatus describes the connected gamepads for the HUD.
+func (g *Game) padStatus() string {
+	if len(g.pads) == 0 {
+		return "none (keyboard/mouse)"
+	}
+	names := make([]string, len(g.pads))
+	for i, id := range g.pads {
+		names[i] = ebiten.GamepadName(id)
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			names[i] += " (unsupported layout)"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Path emitter defaults (-path-samples, -path-speed, -path-drift) and the
+// share of the screen a loaded shape is scaled to fill.
+const (
+	DefaultPathSamples = 24
+	DefaultPathSpeed   = 4.0
+	DefaultPathDrift   = 0.15
+	pathFit            = 0.7
+)
+
+// pathEmitter spawns fire along a polyline (-path) so the particle cloud
+// traces its shape. Each tick it drops samples points evenly spaced around
+// the whole path, starting from head, which walks speed px along it per
+// tick; the particles' velocities are scaled by drift so the shape holds
+// for their lifetime instead of bursting apart.
+type pathEmitter struct {
+	*outline.Path
+	head    float64 // arc length the walk has reached
+	samples int
+	speed   float64
+	drift   float64
+}
+
+// newPathEmitter scales pts, keeping their aspect, to fill pathFit of the
+// screen around its center, so a shape can be drawn in any units.
+func newPathEmitter(pts []outline.Point, samples int, speed, drift float64) (*pathEmitter, error) {
+	path, err := outline.Fit(pts, ScreenWidth, ScreenHeight, pathFit)
+	if err != nil {
+		return nil, err
+	}
+	return &pathEmitter{Path: path, samples: samples, speed: speed, drift: drift}, nil
+}
+
+// emit spawns n particles evenly spaced around the path from the head,
+// then walks the head on.
+func (pe *pathEmitter) emit(g *Game, n int) {
+	for i := 0; i < n; i++ {
+		c := pe.At(pe.head + pe.Length()*float64(i)/float64(n))
+		g.spawnScaled(c.X, c.Y, 0, KindFire, LayerFront, pe.drift)
+	}
+	pe.head = math.Mod(pe.head+pe.speed, pe.Length())
+}
+
+// event is one timed step of a show script: action runs once the show clock
+// reaches at seconds.
+type event struct {
+	at     float64
+	action func(*Game)
+}
+
+// parseScript reads a show script (-script). Each non-blank line is
+//
+//	<seconds> <command> [args...]
+//
+// with # starting a comment. Commands:
+//
+//	burst <x> <y> <count>    fire burst of count particles at (x, y)
+//	superburst [<x> <y>]     1200-particle burst, at the center by default
+//	intensity <factor>       scale every emitter's spawn rate
+//	speed <factor>           simulation speed (> 0), as on keys 1-5
+//	chain <x> <y>            append an attractor chain point
+//	clearchain               remove the attractor chain
+//
+// The events come back sorted by time; ties keep their file order.
+func parseScript(r io.Reader) ([]event, error) {
+	var events []event
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text, _, _ := strings.Cut(sc.Text(), "#")
+		f := strings.Fields(text)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) < 2 {
+			return nil, fmt.Errorf("line %d: want \"<seconds> <command> [args]\"", line)
+		}
+		at, err := strconv.ParseFloat(f[0], 64)
+		if err != nil || !(at >= 0) || math.IsInf(at, 1) {
+			return nil, fmt.Errorf("line %d: bad time %q", line, f[0])
+		}
+		cmd, args := f[1], f[2:]
+		nums := make([]float64, len(args))
+		for i, a := range args {
+			if nums[i], err = strconv.ParseFloat(a, 64); err != nil || math.IsNaN(nums[i]) || math.IsInf(nums[i], 0) {
+				return nil, fmt.Errorf("line %d: %s: bad number %q", line, cmd, a)
+			}
+		}
+		argErr := func(want string) error {
+			return fmt.Errorf("line %d: %s takes %s arguments, got %d", line, cmd, want, len(nums))
+		}
+
+		var action func(*Game)
+		switch cmd {
+		case "burst":
+			if len(nums) != 3 {
+				return nil, argErr("3")
+			}
+			x, y, n := nums[0], nums[1], int(nums[2])
+			action = func(g *Game) { g.spawnBurst(x, y, n) }
+		case "superburst":
+			x, y := ScreenWidth/2.0, ScreenHeight/2.0
+			switch len(nums) {
+			case 0:
+			case 2:
+				x, y = nums[0], nums[1]
+			default:
+				return nil, argErr("0 or 2")
+			}
+			action = func(g *Game) { g.spawnBurst(x, y, 1200) }
+		case "intensity":
+			if len(nums) != 1 || nums[0] < 0 {
+				return nil, argErr("1 non-negative")
+			}
+			v := nums[0]
+			action = func(g *Game) { g.intensity = v }
+		case "speed":
+			// speed 0 would stop the script clock and freeze the show for good
+			if len(nums) != 1 {
+				return nil, argErr("1")
+			}
+			if nums[0] <= 0 {
+				return nil, fmt.Errorf("line %d: speed must be positive, got %g", line, nums[0])
+			}
+			v := nums[0]
+			action = func(g *Game) { g.timeScale = v }
+		case "chain":
+			if len(nums) != 2 {
+				return nil, argErr("2")
+			}
+			pt := point{nums[0], nums[1]}
+			action = func(g *Game) { g.addChainPoint(pt) }
+		case "clearchain":
+			if len(nums) != 0 {
+				return nil, argErr("no")
+			}
+			action = (*Game).clearChain
+		default:
+			return nil, fmt.Errorf("line %d: unknown command %q", line, cmd)
+		}
+		events = append(events, event{at: at, action: action})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at < events[j].at })
+	return events, nil
+}
+
+// runScript fires every script event whose time has come.
+func (g *Game) runScript(now float64) {
+	for g.scriptNext < len(g.script) && g.script[g.scriptNext].at <= now {
+		g.script[g.scriptNext].action(g)
+		g.scriptNext++
+	}
+}
+
+// Spawn recording (-recordspawns) and replay (-replayspawns). A recording is
+// what the particles saw of a live session rather than the input that drove
+// it: every spawnAt and spawnBurst call, plus the chain, kill zone and
+// particle limit changes that affect particles, each stamped with the tick
+// whose update it precedes. Spawns draw their randomness from spawnRand,
+// whose state the take header carries, so replaying the events in order
+// reproduces the show exactly however the emitters that made them change.
+// The file format is package spawnlog's.
+
+// record adds ev to the spawn recording, if one is running. Events from
+// input between frames precede the next tick's update.
+func (g *Game) record(ev spawnlog.Event) {
+	if g.take == nil {
+		return
+	}
+	ev.Tick = g.tick
+	if !g.inStep {
+		ev.Tick++
+	}
+	g.take.add(ev)
+}
+
+// kindByName returns the kind whose String is name.
+func kindByName(name string) (PKind, bool) {
+	for _, k := range []PKind{KindFire, KindEmber} {
+		if k.String() == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// applySpawnEvent re-issues a recorded event.
+func (g *Game) applySpawnEvent(ev spawnlog.Event) {
+	switch ev.Op {
+	case "spawn":
+		// spawnlog.Parse only accepts the names of kinds and layers
+		kind, _ := kindByName(ev.Kind)
+		layer, _ := layerByName(ev.Layer)
+		g.spawnScaled(ev.X, ev.Y, ev.Z, kind, layer, ev.Scale)
+	case "burst":
+		g.spawnBurst(ev.X, ev.Y, ev.N)
+	case "chain":
+		g.addChainPoint(point{ev.X, ev.Y})
+	case "clearchain":
+		g.clearChain()
+	case "zone":
+		z := ev.Zone
+		g.addKillZone(killZone{z[0], z[1], z[2], z[3]})
+	case "unzone":
+		g.removeKillZone()
+	case "limit":
+		g.particleLimit = min(ev.N, len(g.particles))
+	}
+}
+
+// spawnTake writes a spawn recording as the show runs. Restarting the show
+// truncates the file and starts the take over.
+type spawnTake struct {
+	f      *os.File
+	w      *bufio.Writer
+	events int
+}
+
+// start (re)writes the take from the top: the header for the show as it
+// stands, then the chain and kill zones already placed, as events of the
+// first tick.
+func (t *spawnTake) start(g *Game) error {
+	if err := t.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	t.w.Reset(t.f)
+	t.events = 0
+	h := spawnlog.Header{
+		Seed: g.spawnSrc.State, Pool: len(g.particles), Limit: g.particleLimit, View: "2d",
+		MaxFire: KindMaxSpeed[KindFire], MaxEmber: KindMaxSpeed[KindEmber], Parallax: ParallaxStrength,
+	}
+	if g.World3D {
+		h.View = "3d"
+	}
+	t.w.WriteString("# amazing spawn recording; play it back with -replayspawns\n")
+	h.Write(t.w)
+	for _, c := range g.chain {
+		g.record(spawnlog.Event{Op: "chain", X: c.x, Y: c.y})
+	}
+	for _, z := range g.killZones {
+		g.record(spawnlog.Event{Op: "zone", Zone: [4]float64{z.x0, z.y0, z.x1, z.y1}})
+	}
+	return nil
+}
+
+func (t *spawnTake) add(ev spawnlog.Event) {
+	ev.Write(t.w) // bufio keeps the first error for close
+	t.events++
+}
+
+// close flushes the take and closes the file.
+func (t *spawnTake) close() error {
+	err := t.w.Flush()
+	if cerr := t.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// RecordSpawns starts recording the show's spawns to path. The show resets,
+// so the recording starts from an empty pool.
+func (g *Game) RecordSpawns(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	g.take = &spawnTake{f: f, w: bufio.NewWriter(f)}
+	g.reset()
+	return nil
+}
+
+// spawnReplay feeds a recording back one event at a time. next is the
+// event to apply once its tick comes; after the last one or an error, done
+// is set.
+type spawnReplay struct {
+	rd      *spawnlog.Reader
+	next    spawnlog.Event
+	done    bool
+	applied int
+	err     error
+}
+
+// advance reads the next event, or stops the replay at the end or on a bad
+// line.
+func (r *spawnReplay) advance() {
+	ev, err := r.rd.Next()
+	if err != nil {
+		if err != io.EOF {
+			r.err = err
+		}
+		r.done = true
+		return
+	}
+	r.next = ev
+}
+
+// run applies every event due by the current tick, in recorded order.
+func (r *spawnReplay) run(g *Game) {
+	for !r.done && r.next.Tick <= g.tick {
+		ev := r.next
+		r.advance()
+		g.applySpawnEvent(ev)
+		r.applied++
+	}
+}
+
+// ReplaySpawns switches the show to replaying the recording read from rd:
+// the show resets to the recorded start, and from then on only the
+// recording spawns, with the emitters, script and live input out of play.
+// The pool must be the size it was recorded with (-max).
+func (g *Game) ReplaySpawns(rd io.Reader) error {
+	r := &spawnReplay{rd: spawnlog.NewReader(rd)}
+	h, err := r.rd.Header()
+	if err != nil {
+		return err
+	}
+	if h.Pool != len(g.particles) {
+		return fmt.Errorf("recorded with a pool of %d particles, this show has %d (set -max %d)", h.Pool, len(g.particles), h.Pool)
+	}
+	g.take = nil
+	g.reset()
+	g.chain, g.killZones = g.chain[:0], g.killZones[:0]
+	g.spawnSrc.State = h.Seed
+	g.particleLimit = h.Limit
+	g.World3D = h.View == "3d"
+	KindMaxSpeed[KindFire], KindMaxSpeed[KindEmber] = h.MaxFire, h.MaxEmber
+	ParallaxStrength = h.Parallax
+	r.advance()
+	if r.err != nil {
+		return r.err
+	}
+	g.replay = r
+	return nil
+}
+
+// recorder pipes raw RGBA frames to an ffmpeg subprocess over stdin.
+type recorder struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	pix   []byte // reused ReadPixels buffer
+}
+
+func newRecorder(path string, w, h, fps int) (*recorder, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("-record %s needs ffmpeg on PATH: %w", path, err)
+	}
+	cmd := exec.Command("ffmpeg", "-y", "-loglevel", "error",
+		"-f", "rawvideo", "-pix_fmt", "rgba",
+		"-s", fmt.Sprintf("%dx%d", w, h), "-r", strconv.Itoa(fps),
+		"-i", "-",
+		"-c:v", "libx264", "-pix_fmt", "yuv420p", path)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting ffmpeg: %w", err)
+	}
+	return &recorder{cmd: cmd, stdin: stdin, pix: make([]byte, 4*w*h)}, nil
+}
+
+func (r *recorder) capture(screen *ebiten.Image) error {
+	screen.ReadPixels(r.pix)
+	_, err := r.stdin.Write(r.pix)
+	return err
+}
+
+// close ends the stream and waits for ffmpeg to finish writing the file.
+func (r *recorder) close() error {
+	if err := r.stdin.Close(); err != nil {
+		return err
+	}
+	return r.cmd.Wait()
+}
+
+// timeScales are the selectable simulation speeds, on keys 1 through 5.
+var (
+	timeScales    = []float64{0.25, 0.5, 1, 2, 4}
+	timeScaleKeys = []ebiten.Key{ebiten.KeyDigit1, ebiten.KeyDigit2, ebiten.KeyDigit3, ebiten.KeyDigit4, ebiten.KeyDigit5}
+)
+
+// kindComposite is the blend mode each particle kind is drawn with: additive
+// fire, alpha-blended embers by default (-emberblend, B).
+var kindComposite = [...]ebiten.CompositeMode{
+	KindFire:  ebiten.CompositeModeLighter,
+	KindEmber: ebiten.CompositeModeSourceOver,
+}
+
+// SetEmberBlend draws embers with alpha blending ("alpha", the default) or
+// additively like fire ("additive", one batch for everything).
+func SetEmberBlend(mode string) error {
+	switch mode {
+	case "alpha":
+		kindComposite[KindEmber] = ebiten.CompositeModeSourceOver
+	case "additive":
+		kindComposite[KindEmber] = ebiten.CompositeModeLighter
+	default:
+		return fmt.Errorf("ember blend must be alpha or additive, not %q", mode)
+	}
+	return nil
+}
+
+// drawBatch collects the quads of every kind that shares one composite mode.
+type drawBatch struct {
+	mode  ebiten.CompositeMode
+	quads spritebatch.SpriteBatch
+}
+
+// layerPass is one layer's draw batches: one per distinct composite mode, in
+// kind order, with kindBatch mapping each kind to its batch. small is the
+// downsampled copy the layer is blurred through; nil draws it crisp.
+type layerPass struct {
+	batches   []*drawBatch
+	kindBatch [len(kindComposite)]*drawBatch
+	small     *ebiten.Image
+}
+
+// layerStyle is how a layer is drawn: alpha scaled by dim, and blurred by
+// drawing it downsampled blur times and stretching it back (1 = crisp).
+type layerStyle struct {
+	dim  float64
+	blur int
+}
+
+// layerStyles default to a dim, soft back layer, a slightly dimmed mid and
+// a crisp front (-layerstyle).
+var layerStyles = [numLayers]layerStyle{
+	LayerBack:  {dim: 0.5, blur: 4},
+	LayerMid:   {dim: 0.85, blur: 1},
+	LayerFront: {dim: 1, blur: 1},
+}
+
+// ParseLayerStyles sets layerStyles from "layer=dim:blur" entries separated
+// by commas, e.g. "back=0.4:6,mid=0.8:2"; layers not named keep their style.
+func ParseLayerStyles(spec string) error {
+	for _, entry := range strings.Split(spec, ",") {
+		name, style, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		dimText, blurText, ok2 := strings.Cut(style, ":")
+		if !ok || !ok2 {
+			return fmt.Errorf("layer style %q: want layer=dim:blur", entry)
+		}
+		l, ok := layerByName(name)
+		if !ok {
+			return fmt.Errorf("layer style %q: unknown layer %q (want back, mid or front)", entry, name)
+		}
+		dim, err := strconv.ParseFloat(dimText, 64)
+		if err != nil || dim < 0 || dim > 1 {
+			return fmt.Errorf("layer style %q: dim must be in [0, 1]", entry)
+		}
+		blur, err := strconv.Atoi(blurText)
+		if err != nil || blur < 1 || blur > 16 {
+			return fmt.Errorf("layer style %q: blur must be 1 to 16", entry)
+		}
+		layerStyles[l] = layerStyle{dim, blur}
+	}
+	return nil
+}
+
+type Game struct {
+	particles []*Particle
+
+	// draw batches per layer (setupBatches), and the full-screen scratch
+	// image blurred layers are drawn into first
+	passes       [numLayers]layerPass
+	layerScratch *ebiten.Image
+
+	// blur the layers styled with it (G); off draws every layer crisp
+	layerBlur bool
+
+	emitters []*Emitter
+	tick     int64
+
+	// emitter configuration as built by NewGame, restored by reset
+	initialEmitters []Emitter
+
+	// spawn jitter: blue-noise sequence (J) instead of uniform random
+	blueJitter bool
+	jitterIdx  int
+
+	// per-particle brightness flicker (F toggles, for A/B comparison)
+	flicker bool
+
+	// debug overlay of emitter orbits (P)
+	showPaths bool
+
+	// spawn caps per frame: all emitters together, and each emitter; 0 = none
+	SpawnPerFrame int
+	EmitterCap    int
+
+	// spawn smoothing (L, -smoothspawns): emitter spawns wait in pending and
+	// at most SpawnDrain leave it per tick; spawned counts the last tick's
+	// spawns
+	SmoothSpawns bool
+	SpawnDrain   int
+	pending      []pendingSpawn
+	spawned      int
+
+	// quality preset (Q cycles, -preset) and the settings only it changes:
+	// how many pool slots spawns may use, the vignette and the starfield
+	preset        string
+	particleLimit int
+	showVignette  bool
+	stars         bool
+
+	// attractor chain (C edits: clicks add points; X clears)
+	chain        []point
+	editingChain bool
+
+	// kill zones (K edits: drag to add; Backspace removes the last one)
+	killZones    []killZone
+	editingZones bool
+	zoneDragging bool
+	zoneStart    point
+
+	// emitter being tuned from the keyboard (Tab cycles; -1 = none)
+	selected int
+
+	// emitter spawn-rate multiplier (script "intensity", gamepad D-pad)
+	intensity float64
+
+	// particle color ramp: depthPalettes[paletteIdx] (V, gamepad D-pad)
+	ramp       palette.Gradient
+	paletteIdx int
+
+	// follow emitter (M, gamepad left stick) at follow; lastCursor is the
+	// cursor position followMouse last saw
+	followOn   bool
+	follow     point
+	lastCursor point
+
+	// connected gamepads, refreshed every frame
+	pads []ebiten.GamepadID
+
+	// shape emitter (-path); nil = none
+	path *pathEmitter
+
+	// show script (-script): events sorted by time, and the next one to run
+	script     []event
+	scriptNext int
+
+	// simulation speed: ticks advanced per frame (1-5 keys); stepAcc carries
+	// the fractional part between frames
+	timeScale float64
+	stepAcc   float64
+
+	// camera parallax wobble
+	depthOffset float64
+
+	// emit in world-space 3D and perspective-project every particle; off
+	// draws screen-space particles with layered parallax (-2d)
+	World3D bool
+
+	// translucent black full-screen layer for the vignette, built once
+	vignette *ebiten.Image
+
+	// optional video capture (-record); one frame per tick
+	rec          *recorder
+	recordedTick int64
+
+	// rng drives the show's own randomness: the emitter layout, spawn counts
+	// and jitter, surprise bursts and random super-bursts. NewGame seeds it
+	// and state dumps carry it, so a seed or a dump reproduces the show.
+	rngSrc splitmix.Source
+	rng    *rand.Rand
+
+	// spawns draw their random variation from their own stream, seeded from
+	// rng, so it depends only on the sequence of spawns
+	spawnSrc  splitmix.Source
+	spawnRand *rand.Rand
+
+	// spawn recording (-recordspawns) and replay (-replayspawns); nil = off.
+	// inStep is set while step runs, to stamp recorded events with their tick.
+	take   *spawnTake
+	replay *spawnReplay
+	inStep bool
+}
+
+// NewGame builds a show whose randomness all comes from seed.
+func NewGame(seed uint64) *Game {
+	g := &Game{
+		particles: make([]*Particle, 0, MaxParticles),
+		emitters:  make([]*Emitter, 0, MaxEmitters),
+		flicker:   true,
+
+		SpawnPerFrame: DefaultSpawnPerFrame,
+		EmitterCap:    DefaultEmitterCap,
+		SmoothSpawns:  true,
+		SpawnDrain:    DefaultSpawnDrain,
+		timeScale:     1,
+		intensity:     1,
+		ramp:          depthRamp,
+		follow:        point{ScreenWidth / 2, ScreenHeight / 2},
+		World3D:       true,
+		selected:      -1,
+		layerBlur:     true,
+	}
+	g.rng = rand.New(&g.rngSrc)
+	g.rngSrc.State = seed
+	g.spawnRand = rand.New(&g.spawnSrc)
+	g.setupBatches()
+	g.setupLayers()
+
+	g.vignette = ebiten.NewImage(ScreenWidth, ScreenHeight)
+	g.vignette.Fill(color.RGBA{0, 0, 0, 40})
+
+	// the cursor where it starts isn't a mouse move, so it mustn't pull
+	// the follow emitter on the first frame
+	mx, my := ebiten.CursorPosition()
+	g.lastCursor = point{float64(mx), float64(my)}
+
+	// prefill pool
+	for i := 0; i < MaxParticles; i++ {
+		g.particles = append(g.particles, &Particle{})
+	}
+	// after the prefill: the preset limits spawns to a share of the pool
+	if err := g.ApplyPreset(DefaultPreset); err != nil {
+		panic(err)
+	}
+
+	// configure a few moving emitters across the screen
+	for i := 0; i < FireEmitters; i++ {
+		a := g.rng.Float64() * 2 * math.Pi
+		r := 120.0 + g.rng.Float64()*420.0
+		cx := ScreenWidth/2.0 + g.rng.Float64()*200.0 - 100.0
+		cy := ScreenHeight/2.0 + g.rng.Float64()*120.0 - 60.0
+		e := &Emitter{
+			cx:         cx,
+			cy:         cy,
+			radius:     r,
+			phase:      a,
+			speed:      0.002 + g.rng.Float64()*0.006,
+			baseSpawn:  6 + g.rng.Intn(12),
+			pulseWidth: 0.8 + g.rng.Float64()*1.8,
+			kind:       KindFire,
+			offsetY:    g.rng.Float64()*40 - 20,
+			cz:         g.rng.Float64()*1.2 - 0.6,
+		}
+		e.layer = layerForDepth(e.cz)
+		g.emitters = append(g.emitters, e)
+	}
+
+	// a couple of ember-focused emitters for long tails
+	for i := 0; i < EmberEmitters; i++ {
+		e := &Emitter{
+			cx:         float64(ScreenWidth) * (0.2 + g.rng.Float64()*0.6),
+			cy:         float64(ScreenHeight) * (0.6 + g.rng.Float64()*0.2),
+			radius:     10 + g.rng.Float64()*60,
+			phase:      g.rng.Float64() * 2 * math.Pi,
+			speed:      0.001 + g.rng.Float64()*0.004,
+			baseSpawn:  2 + g.rng.Intn(3),
+			pulseWidth: 3.0 + g.rng.Float64()*6.0,
+			kind:       KindEmber,
+			offsetY:    0,
+			cz:         g.rng.Float64()*0.8 - 0.4,
+		}
+		e.layer = layerForDepth(e.cz)
+		g.emitters = append(g.emitters, e)
+	}
+
+	for _, e := range g.emitters {
+		g.initialEmitters = append(g.initialEmitters, *e)
+	}
+	g.spawnSrc.State = g.rng.Uint64()
+	return g
+}
+
+// reset clears the show for a fresh start: every particle is deactivated,
+// emitters return to their starting orbit and the clock restarts. A spawn
+// recording starts over with it.
+func (g *Game) reset() {
+	for _, p := range g.particles {
+		*p = Particle{}
+	}
+	for i := range g.passes {
+		for _, b := range g.passes[i].batches {
+			b.quads.Reset()
+		}
+	}
+	for i, e := range g.emitters {
+		*e = g.initialEmitters[i]
+	}
+	g.tick = 0
+	g.recordedTick = -1
+	g.depthOffset = 0
+	g.scriptNext = 0
+	g.pending = g.pending[:0]
+	if g.path != nil {
+		g.path.head = 0
+	}
+	if g.take != nil {
+		if err := g.take.start(g); err != nil {
+			log.Printf("restarting the spawn recording: %v", err)
+		}
+	}
+}
+
+// qualityPreset is a bundle of settings trading spectacle for speed.
+type qualityPreset struct {
+	name          string
+	poolFraction  float64 // share of the particle pool spawns may use
+	spawnPerFrame int     // 0 = no cap
+	emitterCap    int     // 0 = no cap
+	flicker       bool
+	vignette      bool
+	stars         bool
+}
+
+// presets are in increasing cost; Q cycles through them in this order.
+var presets = []qualityPreset{
+	{"low", 0.3, 60, 40, false, false, false},
+	{"medium", 0.6, 120, 150, true, true, false},
+	{"high", 1, DefaultSpawnPerFrame, DefaultEmitterCap, true, true, true},
+	{"ultra", 1, 400, 0, true, true, true},
+}
+
+const DefaultPreset = "medium"
+
+// ApplyPreset configures the particle limit, spawn caps, flicker, vignette
+// and starfield from the named preset.
+func (g *Game) ApplyPreset(name string) error {
+	p, err := findPreset(name)
+	if err != nil {
+		return err
+	}
+	g.preset = p.name
+	g.particleLimit = max(1, int(p.poolFraction*float64(len(g.particles))))
+	g.record(spawnlog.Event{Op: "limit", N: g.particleLimit})
+	g.SpawnPerFrame, g.EmitterCap = p.spawnPerFrame, p.emitterCap
+	g.flicker, g.showVignette, g.stars = p.flicker, p.vignette, p.stars
+	return nil
+}
+
+// findPreset looks up a preset by name.
+func findPreset(name string) (qualityPreset, error) {
+	for _, p := range presets {
+		if p.name == name {
+			return p, nil
+		}
+	}
+	names := make([]string, len(presets))
+	for i, p := range presets {
+		names[i] = p.name
+	}
+	return qualityPreset{}, fmt.Errorf("unknown preset %q (want %s)", name, strings.Join(names, ", "))
+}
+
+// nextPreset returns the name of the preset after the current one.
+func (g *Game) nextPreset() string {
+	for i, p := range presets {
+		if p.name == g.preset {
+			return presets[(i+1)%len(presets)].name
+		}
+	}
+	return presets[0].name
+}
+
+// setupBatches groups each layer's kinds by composite mode, reusing
+// existing buffers. When every kind shares a mode a layer has a single batch
+// and one draw call.
+func (g *Game) setupBatches() {
+	for i := range g.passes {
+		pass := &g.passes[i]
+		old := append([]*drawBatch(nil), pass.batches...)
+		pass.batches = pass.batches[:0]
+		for kind, mode := range kindComposite {
+			var b *drawBatch
+			for _, existing := range pass.batches {
+				if existing.mode == mode {
+					b = existing
+				}
+			}
+			if b == nil {
+				if len(pass.batches) < len(old) {
+					b = old[len(pass.batches)]
+				} else {
+					b = &drawBatch{}
+					b.quads.Reserve(MaxParticles)
+				}
+				b.mode = mode
+				pass.batches = append(pass.batches, b)
+			}
+			pass.kindBatch[kind] = b
+		}
+	}
+}
+
+// batchCount is the number of draw batches over all layers.
+func (g *Game) batchCount() int {
+	n := 0
+	for i := range g.passes {
+		n += len(g.passes[i].batches)
+	}
+	return n
+}
+
+// setupLayers allocates the images for the layers styled with a blur: the
+// shared full-screen scratch and each layer's downsampled copy.
+func (g *Game) setupLayers() {
+	for i, style := range layerStyles {
+		if style.blur > 1 {
+			g.passes[i].small = ebiten.NewImage((ScreenWidth+style.blur-1)/style.blur, (ScreenHeight+style.blur-1)/style.blur)
+			if g.layerScratch == nil {
+				g.layerScratch = ebiten.NewImage(ScreenWidth, ScreenHeight)
+			}
+		}
+	}
+}
+
+// drawLayer flushes one layer's batches: straight to the screen when it is
+// crisp, or into the scratch image, down to the layer's small copy and
+// stretched back over the screen, which blurs it. The blurred layer is
+// added like the fire.
+func (g *Game) drawLayer(screen *ebiten.Image, l Layer) {
+	pass := &g.passes[l]
+	if !g.layerBlur || pass.small == nil {
+		for _, b := range pass.batches {
+			b.quads.Flush(screen, fireImage, b.mode)
+		}
+		return
+	}
+	empty := true
+	for _, b := range pass.batches {
+		empty = empty && b.quads.Len() == 0
+	}
+	if empty {
+		return
+	}
+	g.layerScratch.Clear()
+	for _, b := range pass.batches {
+		b.quads.Flush(g.layerScratch, fireImage, b.mode)
+	}
+	f := float64(layerStyles[l].blur)
+	var down, up ebiten.DrawImageOptions
+	down.GeoM.Scale(1/f, 1/f)
+	down.Filter = ebiten.FilterLinear
+	pass.small.Clear()
+	pass.small.DrawImage(g.layerScratch, &down)
+	up.GeoM.Scale(f, f)
+	up.Filter = ebiten.FilterLinear
+	up.CompositeMode = ebiten.CompositeModeLighter
+	screen.DrawImage(pass.small, &up)
+}
+
+// allocateParticle returns an inactive particle from the first
+// particleLimit pool slots, or nil if they are all in use.
+func (g *Game) allocateParticle() *Particle {
+	for _, p := range g.particles[:g.particleLimit] {
+		if !p.active {
+			return p
+		}
+	}
+	return nil
+}
+
+// spawnAt spawns a single particle of the given kind and layer around
+// (x, y) and returns it, or nil if the pool is full. In 3D mode it starts
+// near depth z; in 2D the depth is random spread only.
+func (g *Game) spawnAt(x, y, z float64, kind PKind, layer Layer) *Particle {
+	return g.spawnScaled(x, y, z, kind, layer, 1)
+}
+
+// spawnScaled spawns like spawnAt and scales the new particle's velocity by
+// scale. Every spawn outside bursts goes through here to be recorded.
+func (g *Game) spawnScaled(x, y, z float64, kind PKind, layer Layer, scale float64) *Particle {
+	g.record(spawnlog.Event{Op: "spawn", Kind: kind.String(), Layer: layer.String(), X: x, Y: y, Z: z, Scale: scale})
+	p := g.spawnParticle(x, y, z, kind, layer)
+	if p != nil {
+		p.vx *= scale
+		p.vy *= scale
+		p.vz *= scale
+	}
+	return p
+}
+
+// spawnParticle does the work of spawnAt without recording it. Its random
+// variation comes from spawnRand alone.
+func (g *Game) spawnParticle(x, y, z float64, kind PKind, layer Layer) *Particle {
+	// spawn a single particle of given kind with random variation
+	if p := g.allocateParticle(); p != nil {
+		r := g.spawnRand
+		g.spawned++
+		*p = Particle{}
+		p.active = true
+		p.kind = kind
+		p.layer = layer
+		p.x = x + (r.Float64()*2-1)*6
+		p.y = y + (r.Float64()*2-1)*6
+		if g.World3D {
+			p.z = z + (r.Float64()*2-1)*0.15
+		} else {
+			// depth placed slightly in front/behind for spread
+			p.z = r.Float64()*2.2 - 1.0
+		}
+		p.angle = r.Float64() * 2 * math.Pi
+		p.angularVelocity = (r.Float64()*2 - 1) * 0.12
+		p.flickerPhase = r.Float64() * 2 * math.Pi
+
+		if kind == KindFire {
+			p.flickerFreq = 10 + r.Float64()*14
+			p.maxLife = 30 + r.Intn(50)
+			p.baseScale = 0.14 + r.Float64()*0.22
+			ang := r.Float64() * 2 * math.Pi
+			speed := 1.2 + r.Float64()*5.8
+			p.vx = math.Cos(ang) * speed * (0.2 + r.Float64()*0.6)
+			p.vy = math.Sin(ang) * speed * (0.3 + r.Float64()*0.9)
+			p.vz = r.Float64()*1.2 - 0.6
+		} else {
+			// ember: smaller, longer lived, slower
+			p.maxLife = 120 + r.Intn(200)
+			p.baseScale = 0.05 + r.Float64()*0.08
+			p.vx = (r.Float64()*2 - 1) * 0.6
+			p.vy = -0.2 - r.Float64()*0.6
+			p.vz = (r.Float64()*2 - 1) * 0.15
+			p.angularVelocity = (r.Float64()*2 - 1) * 0.03
+			p.flickerFreq = 2 + r.Float64()*3
+		}
+		p.seed = r.Uint64()
+		return p
+	}
+	return nil
+}
+
+// spawnBurst spawns count fire particles at (x, y) in the front layer, as
+// bursts are the show's punctuation. A burst is recorded as one event.
+func (g *Game) spawnBurst(x, y float64, count int) {
+	g.record(spawnlog.Event{Op: "burst", X: x, Y: y, N: count})
+	for i := 0; i < count; i++ {
+		g.spawnParticle(x, y, 0, KindFire, LayerFront)
+	}
+}
+
+// requestSpawn spawns like spawnAt, or with smoothing queues the spawn for
+// drainSpawns, dropping it if the queue is full.
+func (g *Game) requestSpawn(x, y, z float64, kind PKind, layer Layer) {
+	if !g.SmoothSpawns {
+		g.spawnAt(x, y, z, kind, layer)
+		return
+	}
+	if len(g.pending) < maxPendingSpawns {
+		g.pending = append(g.pending, pendingSpawn{x, y, z, kind, layer})
+	}
+}
+
+// drainSpawns spawns up to n queued requests, oldest first.
+func (g *Game) drainSpawns(n int) {
+	n = min(n, len(g.pending))
+	for _, r := range g.pending[:n] {
+		g.spawnAt(r.x, r.y, r.z, r.kind, r.layer)
+	}
+	g.pending = g.pending[:copy(g.pending, g.pending[n:])]
+}
+
+// spawnJitter returns an offset in [-1,1]^2: uniform random, or the next
+// entry of the blue-noise sequence for more even coverage.
+func (g *Game) spawnJitter() (float64, float64) {
+	if !g.blueJitter {
+		return g.rng.Float64()*2 - 1, g.rng.Float64()*2 - 1
+	}
+	o := blueNoise[g.jitterIdx]
+	g.jitterIdx = (g.jitterIdx + 1) % len(blueNoise)
+	return o[0], o[1]
+}
+
+// depthRamp runs blue (far) -> purple -> red (near), following the
+// sinusoidal ease of the original formula with a slight green tint in the
+// middle and the red boosted up close.
+var depthRamp = palette.Even(palette.RGB,
+	color.RGBA{0, 64, 255, 255},
+	color.RGBA{98, 48, 157, 255},
+	color.RGBA{180, 32, 75, 255},
+	color.RGBA{255, 16, 19, 255},
+	color.RGBA{255, 0, 0, 255},
+)
+
+// depthColor samples ramp by depth, far to near, with a small time shift;
+// with depthRamp that is blue (far) -> purple -> red (near)
+func depthColor(ramp palette.Gradient, z float64, t float64) (r, g, b float32) {
+	// Normalize z from -2 (far) to +2 (near)
+	nt := math.Min(math.Max((z+2.0)/4.0, 0), 1)
+	// add slow hue shift for spectacle; the ramp clamps the result
+	c := ramp.Sample(nt + 0.15*math.Sin(t*0.8))
+	return float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255
+}
+
+func (g *Game) Update() error {
+	// pause the simulation (but keep drawing) while the window is unfocused
+	if !RunInBackground && !ebiten.IsFocused() {
+		return nil
+	}
+	// a replay plays on its own: live input would change the show
+	if g.replay != nil {
+		g.advance()
+		return nil
+	}
+
+	// input: left click still does a big burst, places a chain point while
+	// editing the chain, or starts dragging out a kill zone
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		mx, my := ebiten.CursorPosition()
+		switch {
+		case g.editingZones:
+			g.zoneDragging = true
+			g.zoneStart = point{float64(mx), float64(my)}
+		case g.editingChain:
+			g.addChainPoint(point{float64(mx), float64(my)})
+		default:
+			// big synchronized burst
+			g.spawnBurst(float64(mx), float64(my), 900)
+		}
+	}
+	if g.zoneDragging && inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+		g.zoneDragging = false
+		if z := g.draggedZone(); z.x1-z.x0 >= 4 && z.y1-z.y0 >= 4 {
+			g.addKillZone(z)
+		}
+	}
+
+	// C toggles chain editing, X clears the chain; K toggles kill-zone
+	// editing, Backspace removes the last zone. One editor is active at a time.
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		g.editingChain = !g.editingChain
+		g.editingZones, g.zoneDragging = false, false
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) {
+		g.editingZones = !g.editingZones
+		g.zoneDragging = false
+		g.editingChain = false
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
+		g.removeKillZone()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		g.clearChain()
+	}
+
+	// J switches spawn jitter between uniform random and blue noise
+	if inpututil.IsKeyJustPressed(ebiten.KeyJ) {
+		g.blueJitter = !g.blueJitter
+	}
+
+	// L toggles spawn smoothing; turning it off spawns whatever is queued
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		g.SmoothSpawns = !g.SmoothSpawns
+		if !g.SmoothSpawns {
+			g.drainSpawns(len(g.pending))
+		}
+	}
+
+	// F toggles flame flicker
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		g.flicker = !g.flicker
+	}
+
+	// B switches embers between alpha and additive blending
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		if kindComposite[KindEmber] == ebiten.CompositeModeLighter {
+			kindComposite[KindEmber] = ebiten.CompositeModeSourceOver
+		} else {
+			kindComposite[KindEmber] = ebiten.CompositeModeLighter
+		}
+		g.setupBatches()
+	}
+
+	// - and = adjust the per-frame spawn cap, [ and ] the per-emitter cap
+	if inpututil.IsKeyJustPressed(ebiten.KeyMinus) {
+		g.SpawnPerFrame = max(g.SpawnPerFrame-50, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) {
+		g.SpawnPerFrame += 50
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+		g.EmitterCap = max(g.EmitterCap-25, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+		g.EmitterCap += 25
+	}
+
+	// Tab selects the next emitter for tuning (after the last: none)
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.selected++
+		if g.selected >= len(g.emitters) {
+			g.selected = -1
+		}
+	}
+	g.nudgeEmitter()
+
+	// Q cycles the quality presets
+	if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
+		_ = g.ApplyPreset(g.nextPreset())
+	}
+
+	// G toggles blurring the soft layers
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		g.layerBlur = !g.layerBlur
+	}
+
+	// P shows the emitter path preview
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.showPaths = !g.showPaths
+	}
+
+	// R clears the scene
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		g.reset()
+	}
+
+	// S dumps the simulation for reproducing it later with -load
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		if err := g.dumpState(StateFile); err != nil {
+			log.Printf("dumping state: %v", err)
+		} else {
+			log.Printf("state at tick %d written to %s", g.tick, StateFile)
+		}
+	}
+
+	// press space for random super-burst
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		g.randomSuperBurst()
+	}
+
+	// M toggles the follow emitter, V cycles the color palette
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		g.followOn = !g.followOn
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		g.cyclePalette(1)
+	}
+
+	// gamepads play alongside the keyboard and mouse
+	g.followMouse()
+	g.applyPad(g.readPads())
+
+	// 1-5 pick the simulation speed
+	for i, key := range timeScaleKeys {
+		if inpututil.IsKeyJustPressed(key) {
+			g.timeScale = timeScales[i]
+		}
+	}
+
+	g.advance()
+	return nil
+}
+
+// advance runs the simulation timeScale ticks per frame; fractional scales
+// accumulate so 0.25x steps every fourth frame.
+func (g *Game) advance() {
+	g.stepAcc += g.timeScale
+	for g.stepAcc >= 1 {
+		g.step()
+		g.stepAcc--
+	}
+}
+
+// step advances the show by one simulation tick: emitters move and spawn
+// (or a replay re-issues the recorded spawns), the camera wobbles and every
+// particle updates.
+func (g *Game) step() {
+	g.tick++
+	g.inStep = true
+	now := float64(g.tick) / 60.0 // seconds elapsed
+
+	g.spawned = 0
+	if g.replay != nil {
+		g.replay.run(g)
+	} else {
+		g.emitSpawns(now)
+	}
+
+	// small global camera depth offset wobble for parallax
+	g.depthOffset = 0.18 * math.Sin(now*0.25)
+
+	// update particles
+	for _, p := range g.particles {
+		if p.active {
+			g.steerAlongChain(p)
+			if len(g.killZones) > 0 || p.killed > 0 {
+				g.applyKillZones(p)
+			}
+			p.update()
+			// recycle if off screen far away
+			if p.x < -200 || p.x > ScreenWidth+200 || p.y < -300 || p.y > ScreenHeight+400 {
+				p.active = false
+			}
+		}
+	}
+	g.inStep = false
+}
+
+// emitSpawns runs the live show's part of a tick: script events, then the
+// emitters, the follow emitter and the path emitter spawn.
+func (g *Game) emitSpawns(now float64) {
+	g.runScript(now)
+
+	// autonomous emitters: move them and spawn based on sine pulses
+	totalSpawns := 0
+	for _, e := range g.emitters {
+		e.phase += e.speed
+		ex, ey := e.position(e.phase)
+		ez := e.depth(e.phase)
+
+		// pulse factor (0..1)
+		pulse := (math.Sin(now*e.pulseWidth+e.phase*4.0) + 1.0) * 0.5
+		// jittered spawn count
+		target := int(float64(e.baseSpawn) * (0.5 + pulse) * (0.8 + g.rng.Float64()*0.8))
+		if e.kind == KindEmber {
+			// embers spawn slowly
+			target = int(float64(e.baseSpawn) * (0.2 + pulse*0.5))
+		}
+		target = int(float64(target) * g.intensity)
+		// cap per-emitter to avoid pool exhaustion
+		if g.EmitterCap > 0 && target > g.EmitterCap {
+			target = g.EmitterCap
+		}
+		for i := 0; i < target && (g.SpawnPerFrame == 0 || totalSpawns < g.SpawnPerFrame); i++ {
+			// small jitter around emitter
+			ox, oy := g.spawnJitter()
+			jx := ex + ox*20
+			jy := ey + oy*20
+			g.requestSpawn(jx, jy, ez, e.kind, e.layer)
+			totalSpawns++
+		}
+
+		// occasional surprise burst
+		if g.rng.Float64() < 0.003 {
+			for i, n := 0, 220+g.rng.Intn(480); i < n; i++ {
+				g.requestSpawn(ex, ey, 0, KindFire, e.layer)
+			}
+		}
+	}
+	if g.followOn {
+		n := int(followSpawn * g.intensity)
+		for i := 0; i < n && (g.SpawnPerFrame == 0 || totalSpawns < g.SpawnPerFrame); i++ {
+			ox, oy := g.spawnJitter()
+			g.requestSpawn(g.follow.x+ox*8, g.follow.y+oy*8, 0, KindFire, LayerFront)
+			totalSpawns++
+		}
+	}
+	if g.path != nil {
+		n := int(float64(g.path.samples) * g.intensity)
+		if g.SpawnPerFrame > 0 {
+			n = min(n, max(g.SpawnPerFrame-totalSpawns, 0))
+		}
+		g.path.emit(g, n)
+	}
+	g.drainSpawns(g.SpawnDrain)
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	// nice dark radial background gradient
+	bg := color.RGBA{10, 6, 26, 255}
+	screen.Fill(bg)
+
+	// subtle vignette: draw a semi-transparent rectangle overlay for concert look
+	if g.showVignette {
+		screen.DrawImage(g.vignette, nil)
+	}
+
+	// prepare buffers (reuse slices)
+	for i := range g.passes {
+		for _, b := range g.passes[i].batches {
+			b.quads.Reset()
+		}
+	}
+
+	now := float64(g.tick) / 60.0
+
+	src := fireImage.Bounds()
+	halfW, halfH := fireImageW/2.0, fireImageH/2.0
+	yaw := g.cameraYaw()
+
+	// draw a faint starfield (cheap)
+	if g.stars && (g.tick%30) == 0 {
+		// occasionally add a twinkling star (just draw small points); its
+		// place comes from the tick, so drawing leaves the show's RNG alone
+		x := (splitmix.Noise(uint64(g.tick), 0) + 1) / 2 * ScreenWidth
+		y := (splitmix.Noise(uint64(g.tick), 1) + 1) / 2 * ScreenHeight * 0.6
+		ebitenutil.DrawRect(screen, x, y, 2, 2, color.RGBA{200, 200, 255, 60})
+	}
+
+	for _, p := range g.particles {
+		if !p.active {
+			continue
+		}
+		rate := float64(p.lifetime) / float64(p.maxLife)
+		// depth adjusted by camera offset
+		z := p.z + g.depthOffset
+		// perspective scaling: near particles bigger
+		depthScale := 1.0 / (1.0 + z*0.6)
+		if depthScale < 0.3 {
+			depthScale = 0.3
+		}
+		px, py := p.x+parallaxShift(z, g.depthOffset), p.y
+		if g.World3D {
+			// the camera yaw carries the wobble, so depth is the particle's own
+			z = p.z
+			var ok bool
+			px, py, depthScale, ok = project(p.x, p.y, p.z, yaw)
+			if !ok {
+				continue
+			}
+		}
+		alpha := float32((1.0 - math.Pow(rate, 1.4)) * (0.20 + (1.0-math.Abs(z))*0.85))
+		if alpha < 0 {
+			alpha = 0
+		}
+		scale := p.baseScale * (1.0 + 0.8*rate) * depthScale
+
+		// color by depth + time
+		rcol, gcol, bcol := depthColor(g.ramp, z, now)
+
+		// brighter for fire, dim for embers
+		if p.kind == KindEmber {
+			alpha *= 0.7
+			scale *= 0.6
+		} else {
+			alpha = float32(math.Min(1.0, float64(alpha)*1.15))
+		}
+		if g.flicker {
+			alpha *= p.flickerFactor(now)
+		}
+		alpha *= float32(1 - p.killed)
+		alpha *= float32(layerStyles[p.layer].dim)
+
+		var geo ebiten.GeoM
+		geo.Translate(-halfW, -halfH)
+		geo.Rotate(p.angle)
+		geo.Scale(scale, scale)
+		geo.Translate(px, py)
+
+		g.passes[p.layer].kindBatch[p.kind].quads.Add(geo, src, rcol*alpha, gcol*alpha, bcol*alpha, alpha)
+	}
+
+	// Layers back to front, one draw call per composite mode in each (per
+	// spritebatch.MaxQuads particles): additive fire glows, embers blend
+	for _, l := range layerOrder {
+		g.drawLayer(screen, l)
+	}
+
+	// capture before the HUD so recordings stay clean
+	if g.rec != nil && g.recordedTick != g.tick {
+		g.recordedTick = g.tick
+		if err := g.rec.capture(screen); err != nil {
+			log.Printf("recording stopped: %v", err)
+			_ = g.rec.close()
+			g.rec = nil
+		}
+	}
+
+	if g.showPaths {
+		g.drawEmitterPaths(screen)
+	}
+	if g.editingChain {
+		g.drawChain(screen)
+	}
+	if g.editingZones {
+		g.drawKillZones(screen)
+	}
+	g.drawSelectedEmitter(screen)
+
+	// HUD: simple status for live shows
+	activeCount := 0
+	for _, p := range g.particles {
+		if p.active {
+			activeCount++
+		}
+	}
+	jitter := "uniform"
+	if g.blueJitter {
+		jitter = "blue noise"
+	}
+	flicker := "off"
+	if g.flicker {
+		flicker = "on"
+	}
+	emberBlend := "alpha"
+	if kindComposite[KindEmber] == ebiten.CompositeModeLighter {
+		emberBlend = "additive"
+	}
+	status := fmt.Sprintf("Quality [Q]: %s  |  ", g.preset)
+	status += fmt.Sprintf("Particles: %d/%d  |  Emitters: %d  |  [LMB]=burst  [SPACE]=superburst  [R]=reset  [J]=jitter: %s  [F]=flicker: %s  [B]=embers: %s (%d batches)  [P]=paths  [Tab]=tune emitter  [S]=dump state",
+		activeCount, g.particleLimit, len(g.emitters), jitter, flicker, emberBlend, g.batchCount())
+	capLabel := func(n int) string {
+		if n == 0 {
+			return "none"
+		}
+		return strconv.Itoa(n)
+	}
+	status += fmt.Sprintf("\nSpawn cap/frame [-/=]: %s  |  Per-emitter cap [ [ ] ]: %s  |  Speed [1-5]: %gx  |  Chain [C]=edit [X]=clear: %d points", capLabel(g.SpawnPerFrame), capLabel(g.EmitterCap), g.timeScale, len(g.chain))
+	if g.SmoothSpawns {
+		status += fmt.Sprintf("\nSmooth spawns [L]: on, %d/tick, %d queued", g.SpawnDrain, len(g.pending))
+	} else {
+		status += "\nSmooth spawns [L]: off"
+	}
+	if g.editingChain {
+		status += " (editing: click to add)"
+	}
+	status += fmt.Sprintf("  |  Kill zones [K]=edit [Backspace]=remove: %d", len(g.killZones))
+	if g.editingZones {
+		status += " (editing: drag to add)"
+	}
+	if g.World3D {
+		status += "  |  View: 3D"
+	} else {
+		status += "  |  View: 2D"
+	}
+	if len(g.script) > 0 {
+		status += fmt.Sprintf("  |  Script: %d/%d events", g.scriptNext, len(g.script))
+	}
+	if g.path != nil {
+		status += fmt.Sprintf("  |  Path: %d points, %d samples/tick", len(g.path.Points), g.path.samples)
+	}
+	if g.take != nil {
+		status += fmt.Sprintf("  |  Recording spawns: %d events", g.take.events)
+	}
+	switch r := g.replay; {
+	case r == nil:
+	case r.done:
+		status += fmt.Sprintf("  |  Replay finished: %d events (input off)", r.applied)
+	default:
+		status += fmt.Sprintf("  |  Replay: %d events, next at tick %d (input off)", r.applied, r.next.Tick)
+	}
+	follow := "off"
+	if g.followOn {
+		follow = fmt.Sprintf("%.0f, %.0f", g.follow.x, g.follow.y)
+	}
+	status += fmt.Sprintf("\nFollow emitter [M]: %s  |  Palette [V]: %s  |  Intensity: %.2fx  |  Gamepad: %s",
+		follow, depthPalettes[g.paletteIdx], g.intensity, g.padStatus())
+	var perLayer [numLayers]int
+	for _, e := range g.emitters {
+		perLayer[e.layer]++
+	}
+	blur := "off"
+	if g.layerBlur {
+		blur = "on"
+	}
+	status += fmt.Sprintf("\nLayers back/mid/front: %d/%d/%d emitters  |  Layer blur [G]: %s", perLayer[LayerBack], perLayer[LayerMid], perLayer[LayerFront], blur)
+	if len(g.pads) > 0 {
+		status += "\n  [L stick]=steer/[L3]=off  [RT]=burst at emitter  [LT]=superburst  [D-pad L/R]=palette  [D-pad U/D]=intensity  [Start]=reset"
+	}
+	if !RunInBackground && !ebiten.IsFocused() {
+		status += "  |  PAUSED (unfocused)"
+	}
+	ebitenutil.DebugPrint(screen, status)
+}
+
+// ---------- state dumps ----------
+
+// particleState and emitterState mirror Particle and Emitter with exported
+// fields for encoding/json.
+type particleState struct {
+	Slot                      int // index in the pool
+	X, Y, Z                   float64
+	VX, VY, VZ                float64
+	Lifetime, MaxLife         int
+	BaseScale                 float64
+	Angle, AngularVelocity    float64
+	Kind                      PKind
+	Layer                     Layer
+	FlickerPhase, FlickerFreq float64
+	ChainNext                 int
+	Killed                    float64
+	Seed                      uint64
+}
+
+type emitterState struct {
+	CX, CY, CZ float64
+	Radius     float64
+	Phase      float64
+	Speed      float64
+	BaseSpawn  int
+	PulseWidth float64
+	Kind       PKind
+	OffsetY    float64
+	Layer      Layer
+}
+
+func (e *Emitter) state() emitterState {
+	return emitterState{e.cx, e.cy, e.cz, e.radius, e.phase, e.speed, e.baseSpawn, e.pulseWidth, e.kind, e.offsetY, e.layer}
+}
+
+func (s emitterState) emitter() Emitter {
+	return Emitter{cx: s.CX, cy: s.CY, cz: s.CZ, radius: s.Radius, phase: s.Phase, speed: s.Speed,
+		baseSpawn: s.BaseSpawn, pulseWidth: s.PulseWidth, kind: s.Kind, offsetY: s.OffsetY, layer: s.Layer}
+}
+
+// gameState is what dumpState writes: the simulation and the quality
+// preset, not the view or the editors. Only active particles are stored,
+// with their pool slots. ParticleLimit, SpawnPerFrame and EmitterCap are
+// kept apart from Preset because -spawncap and -emittercap override it.
+type gameState struct {
+	Preset          string
+	ParticleLimit   int
+	SpawnPerFrame   int
+	EmitterCap      int
+	Tick            int64
+	StepAcc         float64
+	JitterIdx       int
+	Intensity       float64
+	ScriptNext      int
+	DepthOffset     float64
+	World3D         bool
+	Chain           [][2]float64
+	KillZones       [][4]float64
+	Emitters        []emitterState
+	InitialEmitters []emitterState
+	Particles       []particleState
+	Pending         []spawnState
+	RNG             uint64
+	SpawnRNG        uint64
+}
+
+// spawnState mirrors pendingSpawn.
+type spawnState struct {
+	X, Y, Z float64
+	Kind    PKind
+	Layer   Layer
+}
+
+func (g *Game) state() gameState {
+	s := gameState{
+		Preset:        g.preset,
+		ParticleLimit: g.particleLimit,
+		SpawnPerFrame: g.SpawnPerFrame,
+		EmitterCap:    g.EmitterCap,
+		Tick:          g.tick,
+		StepAcc:       g.stepAcc,
+		JitterIdx:     g.jitterIdx,
+		Intensity:     g.intensity,
+		ScriptNext:    g.scriptNext,
+		DepthOffset:   g.depthOffset,
+		World3D:       g.World3D,
+		RNG:           g.rngSrc.State,
+		SpawnRNG:      g.spawnSrc.State,
+	}
+	for _, c := range g.chain {
+		s.Chain = append(s.Chain, [2]float64{c.x, c.y})
+	}
+	for _, z := range g.killZones {
+		s.KillZones = append(s.KillZones, [4]float64{z.x0, z.y0, z.x1, z.y1})
+	}
+	for _, e := range g.emitters {
+		s.Emitters = append(s.Emitters, e.state())
+	}
+	for i := range g.initialEmitters {
+		s.InitialEmitters = append(s.InitialEmitters, g.initialEmitters[i].state())
+	}
+	for _, r := range g.pending {
+		s.Pending = append(s.Pending, spawnState{r.x, r.y, r.z, r.kind, r.layer})
+	}
+	for i, p := range g.particles {
+		if !p.active {
+			continue
+		}
+		s.Particles = append(s.Particles, particleState{
+			Slot: i,
+			X:    p.x, Y: p.y, Z: p.z,
+			VX: p.vx, VY: p.vy, VZ: p.vz,
+			Lifetime: p.lifetime, MaxLife: p.maxLife,
+			BaseScale: p.baseScale,
+			Angle:     p.angle, AngularVelocity: p.angularVelocity,
+			Kind:         p.kind,
+			Layer:        p.layer,
+			FlickerPhase: p.flickerPhase, FlickerFreq: p.flickerFreq,
+			ChainNext: p.chainNext,
+			Killed:    p.killed,
+			Seed:      p.seed,
+		})
+	}
+	return s
+}
+
+// restore replaces the simulation with s. The script itself is not part of
+// the dump; load the same -script to continue it.
+func (g *Game) restore(s gameState) error {
+	p, err := findPreset(s.Preset)
+	if err != nil {
+		return err
+	}
+	if s.ParticleLimit < 1 || s.ParticleLimit > len(g.particles) {
+		return fmt.Errorf("particle limit %d outside the pool of %d", s.ParticleLimit, len(g.particles))
+	}
+	if s.SpawnPerFrame < 0 || s.EmitterCap < 0 {
+		return fmt.Errorf("negative spawn cap %d or emitter cap %d", s.SpawnPerFrame, s.EmitterCap)
+	}
+	if len(s.Emitters) != len(s.InitialEmitters) {
+		return fmt.Errorf("%d emitters but %d initial emitters", len(s.Emitters), len(s.InitialEmitters))
+	}
+	for _, ps := range s.Particles {
+		if ps.Slot < 0 || ps.Slot >= len(g.particles) {
+			return fmt.Errorf("particle slot %d outside the pool of %d", ps.Slot, len(g.particles))
+		}
+		if !ps.Kind.valid() || !ps.Layer.valid() {
+			return fmt.Errorf("particle in slot %d has kind %d, layer %d", ps.Slot, ps.Kind, ps.Layer)
+		}
+	}
+	for _, r := range s.Pending {
+		if !r.Kind.valid() || !r.Layer.valid() {
+			return fmt.Errorf("pending spawn has kind %d, layer %d", r.Kind, r.Layer)
+		}
+	}
+	for i := range s.Emitters {
+		for _, e := range []emitterState{s.Emitters[i], s.InitialEmitters[i]} {
+			if !e.Kind.valid() || !e.Layer.valid() {
+				return fmt.Errorf("emitter %d has kind %d, layer %d", i, e.Kind, e.Layer)
+			}
+		}
+	}
+	g.reset()
+	g.preset = p.name
+	g.particleLimit = s.ParticleLimit
+	g.record(spawnlog.Event{Op: "limit", N: g.particleLimit})
+	g.SpawnPerFrame, g.EmitterCap = s.SpawnPerFrame, s.EmitterCap
+	g.flicker, g.showVignette, g.stars = p.flicker, p.vignette, p.stars
+	g.tick, g.stepAcc, g.jitterIdx = s.Tick, s.StepAcc, s.JitterIdx
+	g.intensity, g.scriptNext = s.Intensity, s.ScriptNext
+	g.depthOffset, g.World3D = s.DepthOffset, s.World3D
+	g.rngSrc.State, g.spawnSrc.State = s.RNG, s.SpawnRNG
+	g.chain = g.chain[:0]
+	for _, c := range s.Chain {
+		g.chain = append(g.chain, point{c[0], c[1]})
+	}
+	g.killZones = g.killZones[:0]
+	for _, z := range s.KillZones {
+		g.killZones = append(g.killZones, killZone{z[0], z[1], z[2], z[3]})
+	}
+	g.emitters = g.emitters[:0]
+	g.initialEmitters = g.initialEmitters[:0]
+	for i := range s.Emitters {
+		e := s.Emitters[i].emitter()
+		g.emitters = append(g.emitters, &e)
+		g.initialEmitters = append(g.initialEmitters, s.InitialEmitters[i].emitter())
+	}
+	for _, r := range s.Pending {
+		g.pending = append(g.pending, pendingSpawn{r.X, r.Y, r.Z, r.Kind, r.Layer})
+	}
+	for _, ps := range s.Particles {
+		*g.particles[ps.Slot] = Particle{
+			x: ps.X, y: ps.Y, z: ps.Z,
+			vx: ps.VX, vy: ps.VY, vz: ps.VZ,
+			lifetime: ps.Lifetime, maxLife: ps.MaxLife,
+			baseScale:       ps.BaseScale,
+			angle:           ps.Angle,
+			angularVelocity: ps.AngularVelocity,
+			kind:            ps.Kind,
+			layer:           ps.Layer,
+			active:          true,
+			flickerPhase:    ps.FlickerPhase, flickerFreq: ps.FlickerFreq,
+			chainNext: ps.ChainNext,
+			killed:    ps.Killed,
+			seed:      ps.Seed,
+		}
+	}
+	return nil
+}
+
+// dumpState writes the simulation state to path as JSON (S key).
+func (g *Game) dumpState(path string) error {
+	data, err := json.MarshalIndent(g.state(), "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadState restores a state written by dumpState (-load). Runs that load
+// the same dump with the same -seed continue identically.
+func (g *Game) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var s gameState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if err := g.restore(s); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return ScreenWidth, ScreenHeight
+}
+
+// LoadScript reads a show script (-script) for the game to run.
+func (g *Game) LoadScript(r io.Reader) error {
+	script, err := parseScript(r)
+	if err != nil {
+		return err
+	}
+	g.script, g.scriptNext = script, 0
+	return nil
+}
+
+// SetPath spawns fire along the outline pts (-path): samples particles per
+// tick, the sampling walking speed px per tick along it, with drift scaling
+// the particles' velocity.
+func (g *Game) SetPath(pts []outline.Point, samples int, speed, drift float64) error {
+	pe, err := newPathEmitter(pts, samples, speed, drift)
+	if err != nil {
+		return err
+	}
+	g.path = pe
+	return nil
+}
+
+// RecordVideo starts piping one frame per tick to ffmpeg, which writes
+// them to path (-record).
+func (g *Game) RecordVideo(path string) error {
+	rec, err := newRecorder(path, ScreenWidth, ScreenHeight, 60)
+	if err != nil {
+		return err
+	}
+	g.rec = rec
+	return nil
+}
+
+// StopVideo finishes the video started by RecordVideo, if it is still
+// recording.
+func (g *Game) StopVideo() error {
+	if g.rec == nil {
+		return nil
+	}
+	err := g.rec.close()
+	g.rec = nil
+	return err
+}
+
+// StopSpawnRecording finishes the recording started by RecordSpawns and
+// returns how many events it holds.
+func (g *Game) StopSpawnRecording() (events int, err error) {
+	if g.take == nil {
+		return 0, nil
+	}
+	events, err = g.take.events, g.take.close()
+	g.take = nil
+	return events, err
+}
+
+// ReplayErr is the error that stopped a replay started by ReplaySpawns,
+// if any.
+func (g *Game) ReplayErr() error {
+	if g.replay == nil {
+		return nil
+	}
+	return g.replay.err
+}
+
+// LogEmitters logs the emitter configuration, one line per emitter.
+func (g *Game) LogEmitters() {
+	log.Printf("%d emitters", len(g.emitters))
+	for i, e := range g.emitters {
+		log.Printf("  %d: %-5v %-5v center (%.0f, %.0f) z %+.2f radius %.0f speed %.4f spawn %d pulse %.2f",
+			i, e.kind, e.layer, e.cx, e.cy, e.cz, e.radius, e.speed, e.baseSpawn, e.pulseWidth)
+	}
+}
@@ -0,0 +1,569 @@
+package amazing
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/arcesoftware/GO_Examples/gametest"
+	"github.com/arcesoftware/GO_Examples/outline"
+	"github.com/arcesoftware/GO_Examples/palette"
+	"github.com/arcesoftware/GO_Examples/spawnlog"
+)
+
+func TestMain(m *testing.M) {
+	gametest.Main(m)
+}
+
+// TestParallax checks that nearer depth planes shift further than far ones
+// for the same camera wobble.
+func TestParallax(t *testing.T) {
+	const wobble = 0.18
+	prev := math.Inf(-1)
+	for z := -2.0; z <= 2.0; z += 0.5 {
+		shift := math.Abs(parallaxShift(z, wobble))
+		if shift < prev {
+			t.Fatalf("z=%.1f shifts %.2fpx, less than a farther particle (%.2fpx)", z, shift, prev)
+		}
+		prev = shift
+	}
+	if far, near := parallaxShift(-2, wobble), parallaxShift(2, wobble); near <= far {
+		t.Errorf("near shift %.2fpx is not larger than far shift %.2fpx", near, far)
+	}
+}
+
+// TestFalloff checks that a higher exponent gives a sharper particle: the
+// center stays fully opaque while alpha away from the center drops faster.
+func TestFalloff(t *testing.T) {
+	const w, h = defaultTexW, defaultTexH
+	soft, hard := FireTexture(1.0), FireTexture(3.0)
+	if a, b := hard.RGBAAt(w/2, h/2).A, soft.RGBAAt(w/2, h/2).A; a < b {
+		t.Errorf("center alpha: exponent 3 gives %d, exponent 1 gives %d", a, b)
+	}
+	for x := w/2 + 2; x < w; x += 2 {
+		if a, b := hard.RGBAAt(x, h/2).A, soft.RGBAAt(x, h/2).A; a > b || b > 0 && a == b {
+			t.Errorf("x=%d: exponent 3 alpha %d not below exponent 1 alpha %d", x, a, b)
+		}
+	}
+}
+
+// TestStateRoundTrip runs a show on a non-default preset for a while,
+// dumps it, loads the dump into a differently seeded game, and checks that
+// the state matches and that both games then evolve identically, since the
+// dump carries the RNG state. Dumps with out-of-range kinds or layers must
+// be refused.
+func TestStateRoundTrip(t *testing.T) {
+	a := NewGame(1)
+	if err := a.ApplyPreset("low"); err != nil {
+		t.Fatal(err)
+	}
+	a.SpawnPerFrame = 75
+	a.chain = []point{{300, 200}, {900, 400}}
+	a.killZones = []killZone{{0, 0, 200, 150}}
+	for i := 0; i < 240; i++ {
+		a.step()
+	}
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := a.dumpState(path); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewGame(2)
+	if err := b.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.state(), b.state()) {
+		t.Fatal("loaded state differs from the dumped one")
+	}
+	if b.flicker != a.flicker || b.showVignette != a.showVignette || b.stars != a.stars {
+		t.Errorf("loaded preset %q did not restore flicker, vignette and stars", b.preset)
+	}
+	// inactive slots keep stale fields, but spawning clears them
+	for i, p := range a.particles {
+		if q := b.particles[i]; p.active != q.active || p.active && *p != *q {
+			t.Fatalf("pool slot %d: loaded %+v, dumped %+v", i, *q, *p)
+		}
+	}
+
+	for i := 0; i < 60; i++ {
+		a.step()
+		b.step()
+	}
+	if !reflect.DeepEqual(a.state(), b.state()) {
+		t.Fatal("restored game diverged within 60 ticks")
+	}
+	if len(a.state().Particles) == 0 {
+		t.Fatal("no active particles to compare")
+	}
+
+	// out-of-range kinds and layers index per-kind and per-layer tables, so
+	// restore must refuse them
+	for i, corrupt := range []func(s *gameState){
+		func(s *gameState) { s.Particles[0].Layer = numLayers },
+		func(s *gameState) { s.Particles[0].Kind = -1 },
+		func(s *gameState) { s.Emitters[0].Layer = -1 },
+		func(s *gameState) { s.InitialEmitters[0].Kind = 2 },
+		func(s *gameState) { s.Pending = append(s.Pending, spawnState{Layer: 9}) },
+	} {
+		s := a.state()
+		corrupt(&s)
+		if err := b.restore(s); err == nil {
+			t.Errorf("corruption %d: restored a dump with an out-of-range kind or layer", i)
+		}
+	}
+}
+
+// TestSpeedCap runs a seeded show with an attractor chain and a super-burst
+// every second, and checks that no active particle ever ends a tick faster
+// than its kind's cap. It reruns the show uncapped to log how fast
+// particles would have gone.
+func TestSpeedCap(t *testing.T) {
+	const ticks = 600
+	run := func(check bool) (fastest [2]float64) {
+		g := NewGame(1)
+		g.chain = []point{{300, 200}, {900, 420}, {500, 600}}
+		for tick := 0; tick < ticks; tick++ {
+			if tick%60 == 0 {
+				g.spawnBurst(ScreenWidth/2, ScreenHeight/2, 1200)
+			}
+			g.step()
+			for _, p := range g.particles {
+				if !p.active {
+					continue
+				}
+				sp := math.Hypot(p.vx, p.vy)
+				fastest[p.kind] = math.Max(fastest[p.kind], sp)
+				if limit := KindMaxSpeed[p.kind]; check && limit > 0 && sp > limit*(1+1e-12) {
+					t.Fatalf("tick %d: %s moving %.3f px/tick, cap %.3f", tick, p.kind, sp, limit)
+				}
+			}
+		}
+		return fastest
+	}
+	capped := run(true)
+	saved := KindMaxSpeed
+	defer func() { KindMaxSpeed = saved }()
+	KindMaxSpeed = [len(KindMaxSpeed)]float64{}
+	free := run(false)
+	for k := range saved {
+		t.Logf("%s: fastest %.2f px/tick capped at %.2f, %.2f uncapped", PKind(k), capped[k], saved[k], free[k])
+	}
+}
+
+// TestWobble checks that an ember's wobble depends only on its seed: the
+// same ember follows the same path whatever else draws random numbers
+// between its updates and a different seed gives a different path. Two
+// shows built from the same seed must then end in the same state.
+func TestWobble(t *testing.T) {
+	const ticks = 600
+	path := func(seed uint64, other *rand.Rand) []float64 {
+		p := Particle{active: true, kind: KindEmber, maxLife: ticks + 1, seed: seed}
+		xs := make([]float64, 0, ticks)
+		for tick := 0; tick < ticks; tick++ {
+			other.Float64() // unrelated draws between updates
+			p.update()
+			xs = append(xs, p.x)
+		}
+		return xs
+	}
+	a, b := path(42, rand.New(rand.NewSource(1))), path(42, rand.New(rand.NewSource(2)))
+	if !slices.Equal(a, b) {
+		t.Error("ember with seed 42 took different paths between different unrelated draws")
+	}
+	if slices.Equal(a, path(43, rand.New(rand.NewSource(1)))) {
+		t.Error("embers with seeds 42 and 43 took the same path")
+	}
+
+	run := func() gameState {
+		g := NewGame(1)
+		for tick := 0; tick < ticks; tick++ {
+			g.step()
+		}
+		return g.state()
+	}
+	if s := run(); !reflect.DeepEqual(s, run()) {
+		t.Errorf("two runs with the same seed diverged within %d ticks", ticks)
+	}
+}
+
+// TestLayers checks that emitters get their layer from their depth and
+// that every particle an emitter spawns (queued or not, surprise bursts
+// included) lands in its emitter's layer while bursts go to the front.
+func TestLayers(t *testing.T) {
+	for _, c := range []struct {
+		cz   float64
+		want Layer
+	}{{-0.6, LayerBack}, {0, LayerMid}, {0.5, LayerFront}} {
+		if got := layerForDepth(c.cz); got != c.want {
+			t.Errorf("emitter at depth %+.1f in layer %v, want %v", c.cz, got, c.want)
+		}
+	}
+
+	for _, smooth := range []bool{true, false} {
+		g := NewGame(1)
+		g.SmoothSpawns = smooth
+		for i, e := range g.emitters {
+			e.layer = Layer(i % int(numLayers))
+			e.kind = KindFire
+			// one emitter per layer, parked apart, so a particle's layer
+			// can be told from where it spawned
+			e.cx, e.cy, e.radius, e.offsetY = 200+float64(i)*300, 360, 0, 0
+		}
+		g.emitters = g.emitters[:min(len(g.emitters), int(numLayers))]
+		g.initialEmitters = g.initialEmitters[:len(g.emitters)]
+		for i := range g.initialEmitters {
+			g.initialEmitters[i] = *g.emitters[i]
+		}
+		g.reset()
+		g.step()
+		g.spawnBurst(ScreenWidth/2, 100, 50)
+		seen := 0
+		for _, p := range g.particles {
+			if !p.active {
+				continue
+			}
+			want := LayerFront // the burst
+			if p.y > 200 {
+				want = Layer(int(math.Round((p.x - 200) / 300)))
+			}
+			if p.layer != want {
+				t.Fatalf("smoothing %v: particle at (%.0f, %.0f) in layer %v, want %v", smooth, p.x, p.y, p.layer, want)
+			}
+			seen++
+		}
+		if seen <= 50 {
+			t.Errorf("smoothing %v: only %d particles spawned", smooth, seen)
+		}
+	}
+}
+
+func TestParseLayerStyles(t *testing.T) {
+	saved := layerStyles
+	defer func() { layerStyles = saved }()
+	if err := ParseLayerStyles("back=0.4:6, front=0.9:1"); err != nil {
+		t.Fatal(err)
+	}
+	if layerStyles[LayerBack] != (layerStyle{0.4, 6}) || layerStyles[LayerFront] != (layerStyle{0.9, 1}) || layerStyles[LayerMid] != saved[LayerMid] {
+		t.Errorf("parsed styles %+v", layerStyles)
+	}
+	for _, bad := range []string{"back=0.4", "far=0.5:2", "mid=1.5:1", "front=1:0", "back=0.5:x"} {
+		if err := ParseLayerStyles(bad); err == nil {
+			t.Errorf("layer style %q parsed", bad)
+		}
+	}
+}
+
+// TestPath runs a path emitter on a square outline and checks that the
+// head walks and that path particles spawn on the outline at the sampling
+// rate. Parsing, fitting and interpolation are tested in package outline.
+func TestPath(t *testing.T) {
+	pts, err := outline.Parse(strings.NewReader("0,0\n1,0\n1,1\n0,1\n0,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(1)
+	g.emitters = g.emitters[:0]
+	if err := g.SetPath(pts, 40, 5, 0); err != nil {
+		t.Fatal(err)
+	}
+	pe := g.path
+	side := pathFit * ScreenHeight
+	corner := point{ScreenWidth/2 - side/2, ScreenHeight/2 - side/2}
+
+	g.reset()
+	const ticks = 20
+	for tick := 0; tick < ticks; tick++ {
+		g.step()
+	}
+	if math.Abs(pe.head-ticks*pe.speed) > 1e-9 {
+		t.Errorf("head at %.1f after %d ticks, want %.1f", pe.head, ticks, ticks*pe.speed)
+	}
+	n := 0
+	for _, p := range g.particles {
+		if !p.active {
+			continue
+		}
+		n++
+		if p.lifetime > 1 {
+			continue // buoyancy has lifted it since
+		}
+		// distance to the nearest side; spawnAt jitters by up to 6 px
+		dx := math.Min(math.Abs(p.x-corner.x), math.Abs(p.x-corner.x-side))
+		dy := math.Min(math.Abs(p.y-corner.y), math.Abs(p.y-corner.y-side))
+		if d := math.Min(dx, dy); d > 6*math.Sqrt2 {
+			t.Fatalf("path particle at (%.1f, %.1f) is %.1f px off the outline", p.x, p.y, d)
+		}
+	}
+	if n < ticks*pe.samples/2 {
+		t.Errorf("%d path particles alive after %d ticks of %d, want most of them", n, ticks, pe.samples)
+	}
+}
+
+// TestPad drives the gamepad mapping with synthetic input, since a pad
+// cannot be scripted: the stick turns the follow emitter on, steers it and
+// stops at the screen edge, the follow emitter spawns fire where it sits,
+// the right trigger bursts there, and the palette and intensity steps wrap
+// and clamp.
+func TestPad(t *testing.T) {
+	g := NewGame(1)
+	// spawn at once and uncapped, so the emitters can't crowd out the
+	// follow emitter
+	g.SmoothSpawns, g.SpawnPerFrame = false, 0
+	start := g.follow
+	g.applyPad(padInput{stickX: 1})
+	if !g.followOn || g.follow.x != start.x+followStickSpeed || g.follow.y != start.y {
+		t.Fatalf("full right stick: follow emitter on=%v at %v, want on at %v", g.followOn, g.follow, point{start.x + followStickSpeed, start.y})
+	}
+	for i := 0; i < 1000; i++ {
+		g.applyPad(padInput{stickX: -0.7, stickY: 0.7})
+	}
+	if g.follow != (point{0, ScreenHeight}) {
+		t.Errorf("stick held down-left: follow emitter at %v, want clamped to %v", g.follow, point{0, ScreenHeight})
+	}
+
+	g.follow = point{300, 200}
+	g.step()
+	near := 0
+	for _, p := range g.particles {
+		if p.active && math.Hypot(p.x-300, p.y-200) < 20 {
+			near++
+		}
+	}
+	if near < followSpawn {
+		t.Errorf("follow emitter at (300, 200): %d particles spawned there in a tick, want at least %d", near, followSpawn)
+	}
+	g.reset()
+	g.applyPad(padInput{burstFollow: true})
+	if g.spawned < 1200 && g.spawned < g.particleLimit {
+		t.Errorf("right trigger spawned %d particles, want a 1200 burst", g.spawned)
+	}
+	g.applyPad(padInput{toggleFollow: true})
+	if g.followOn {
+		t.Error("stick press left the follow emitter on")
+	}
+
+	g.applyPad(padInput{paletteStep: -1})
+	if last := len(depthPalettes) - 1; g.paletteIdx != last || !reflect.DeepEqual(g.ramp, palette.Presets[depthPalettes[last]]) {
+		t.Errorf("palette back from the first: %s, want %s", depthPalettes[g.paletteIdx], depthPalettes[last])
+	}
+	g.applyPad(padInput{paletteStep: 1})
+	if g.paletteIdx != 0 || !reflect.DeepEqual(g.ramp, depthRamp) {
+		t.Errorf("palette forward from the last: %s, want depth", depthPalettes[g.paletteIdx])
+	}
+
+	for i := 0; i < 20; i++ {
+		g.applyPad(padInput{intensityStep: 1})
+	}
+	if g.intensity != maxIntensity {
+		t.Errorf("intensity after 20 steps up: %g, want %g", g.intensity, maxIntensity)
+	}
+	for i := 0; i < 20; i++ {
+		g.applyPad(padInput{intensityStep: -1})
+	}
+	if g.intensity != minIntensity {
+		t.Errorf("intensity after 20 steps down: %g, want %g", g.intensity, minIntensity)
+	}
+}
+
+// TestSpawnReplay records a seeded show driven the way a live session is
+// (bursts and edits from input between ticks, a script, the follow and
+// path emitters, a preset change, smoothing switched off, a restart), then
+// replays the recording in games with other emitters and other seeds and
+// checks that each ends with exactly the live particles. Every event line
+// must also survive being parsed and written again unchanged.
+func TestSpawnReplay(t *testing.T) {
+	const ticks = 600
+	path := filepath.Join(t.TempDir(), "spawns.txt")
+
+	live := NewGame(1)
+	if err := live.SetPath([]outline.Point{{}, {X: 100}, {X: 50, Y: 80}}, 8, DefaultPathSpeed, DefaultPathDrift); err != nil {
+		t.Fatal(err)
+	}
+	if err := live.LoadScript(strings.NewReader("1 burst 640 360 300\n1.5 chain 200 600\n2.5 clearchain\n")); err != nil {
+		t.Fatal(err)
+	}
+	live.followOn = true
+	if err := live.RecordSpawns(path); err != nil {
+		t.Fatal(err)
+	}
+	for tick := 0; tick < ticks+30; tick++ {
+		// input between ticks, as Update applies it; the restart at 30
+		// drops everything before it from the take
+		switch tick {
+		case 10:
+			live.spawnBurst(100, 100, 500)
+		case 20:
+			live.addChainPoint(point{300, 200})
+			live.addKillZone(killZone{1000, 0, 1280, 120})
+		case 30:
+			live.reset()
+		case 40:
+			live.spawnBurst(400, 300, 900)
+			live.addChainPoint(point{900, 400})
+		case 60:
+			live.addKillZone(killZone{0, 0, 250, 200})
+		case 80:
+			if err := live.ApplyPreset("low"); err != nil {
+				t.Fatal(err)
+			}
+		case 100:
+			live.removeKillZone()
+			live.follow = point{800, 500}
+		case 120:
+			live.SmoothSpawns = false
+			live.drainSpawns(len(live.pending))
+		}
+		live.step()
+	}
+	events, err := live.StopSpawnRecording()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := live.state()
+	if len(want.Particles) == 0 {
+		t.Fatal("no live particles to compare")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]int{}
+	var buf bytes.Buffer
+	for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "take ") {
+			seen["take"]++
+			continue
+		}
+		ev, err := spawnlog.Parse(line)
+		if err != nil {
+			t.Fatalf("recording line %d: %v", i+1, err)
+		}
+		buf.Reset()
+		ev.Write(&buf)
+		if got := strings.TrimSuffix(buf.String(), "\n"); got != line {
+			t.Fatalf("recording line %d: %q written back as %q", i+1, line, got)
+		}
+		seen[ev.Op]++
+	}
+	if seen["take"] != 2 {
+		t.Errorf("%d header lines after the restart, want the comment and one take", seen["take"])
+	}
+	for op := range spawnlog.Args {
+		if seen[op] == 0 {
+			t.Errorf("the session recorded no %s events", op)
+		}
+	}
+
+	for _, r := range []struct {
+		seed     uint64
+		emitters int
+	}{{2, 1}, {3, 0}} {
+		g := NewGame(r.seed)
+		g.emitters, g.initialEmitters = g.emitters[:r.emitters], g.initialEmitters[:r.emitters]
+		g.followOn = true
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = g.ReplaySpawns(f)
+		for err == nil && g.tick < live.tick {
+			g.step()
+		}
+		f.Close()
+		if err == nil {
+			err = g.ReplayErr()
+		}
+		if err != nil {
+			t.Fatalf("replay with seed %d: %v", r.seed, err)
+		}
+		if !g.replay.done || g.replay.applied != events {
+			t.Fatalf("replay with seed %d applied %d of %d events", r.seed, g.replay.applied, events)
+		}
+		got := g.state()
+		if !reflect.DeepEqual(got.Particles, want.Particles) {
+			t.Errorf("replay with seed %d and %d emitters: %d particles differ from the live %d",
+				r.seed, r.emitters, len(got.Particles), len(want.Particles))
+		}
+		if !reflect.DeepEqual(got.Chain, want.Chain) || !reflect.DeepEqual(got.KillZones, want.KillZones) || got.SpawnRNG != want.SpawnRNG {
+			t.Errorf("replay with seed %d: chain, kill zones or spawn RNG differ from the live show", r.seed)
+		}
+	}
+}
+
+// BenchmarkDraw draws a filled-up show offscreen; run it with -benchmem
+// for Draw's heap allocations per frame. PerFrameOverlay is what
+// allocating the vignette overlay every frame, as Draw used to, costs on
+// its own.
+func BenchmarkDraw(b *testing.B) {
+	gametest.Require(b)
+	LoadTextures(1.4)
+	g := NewGame(1)
+	for i := 0; i < 120; i++ {
+		g.step()
+	}
+	screen := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	g.Draw(screen) // warm the batch buffers
+	b.Run("Draw", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g.Draw(screen)
+		}
+	})
+	b.Run("PerFrameOverlay", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			overlay := ebiten.NewImage(ScreenWidth, ScreenHeight)
+			overlay.Fill(color.RGBA{0, 0, 0, 40})
+			screen.DrawImage(overlay, nil)
+		}
+	})
+}
+
+// BenchmarkSpawnSmoothing steps the same seeded show with and without
+// spawn smoothing, one tick per op, and reports the spread of spawns per
+// tick next to the step time.
+func BenchmarkSpawnSmoothing(b *testing.B) {
+	for _, mode := range []struct {
+		name   string
+		smooth bool
+	}{
+		{"Immediate", false},
+		{"Smoothed", true},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			g := NewGame(1)
+			g.SmoothSpawns = mode.smooth
+			spawns := make([]float64, 0, b.N)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.step()
+				spawns = append(spawns, float64(g.spawned))
+			}
+			b.StopTimer()
+			var mean, sd float64
+			for _, x := range spawns {
+				mean += x
+			}
+			mean /= float64(len(spawns))
+			for _, x := range spawns {
+				sd += (x - mean) * (x - mean)
+			}
+			sort.Float64s(spawns)
+			b.ReportMetric(mean, "spawns/op")
+			b.ReportMetric(math.Sqrt(sd/float64(len(spawns))), "spawns-sd")
+			b.ReportMetric(spawns[len(spawns)*99/100], "spawns-p99")
+			b.ReportMetric(spawns[len(spawns)-1], "spawns-max")
+		})
+	}
+}
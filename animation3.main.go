@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bytes"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -13,6 +13,8 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/arcesoftware/GO_Examples/fireburst"
 )
 
 const (
@@ -21,18 +23,22 @@ const (
 	maxParticles = 8000
 	defaultTexW  = 32
 	defaultTexH  = 32
+
+	// maxSpawnSpeed is roughly the largest |v| a fireburst particle spawns with (6.0 * 0.7).
+	maxSpawnSpeed = 4.2
+	// speedColorWeight controls how much the spawn-speed color overrides the lifetime gradient.
+	speedColorWeight = 0.6
 )
 
 var (
-	fireImage  *ebiten.Image
-	fireImageW float64
-	fireImageH float64
+	fireImage *ebiten.Image
+
+	// background gradient colors (-bgtop, -bgbottom)
+	bgTop    = fireburst.HexColor{RGBA: color.RGBA{10, 10, 20, 255}}
+	bgBottom = fireburst.HexColor{RGBA: color.RGBA{44, 18, 40, 255}}
 )
 
 func init() {
-	// Use math/rand for seeding, but we will use rand.Float64() for values.
-	rand.Seed(time.Now().UnixNano()) 
-
 	// Procedural circular alpha texture (A soft, fading circle for glow)
 	img := image.NewRGBA(image.Rect(0, 0, defaultTexW, defaultTexH))
 	cx, cy := defaultTexW/2.0, defaultTexH/2.0
@@ -51,213 +57,227 @@ func init() {
 		}
 	}
 	fireImage = ebiten.NewImageFromImage(img)
-	fireImageW = float64(fireImage.Bounds().Dx())
-	fireImageH = float64(fireImage.Bounds().Dy())
 }
 
-// Particle represents a single element in the system.
-type Particle struct {
-	x, y, z             float64
-	vx, vy, vz          float64
-	lifetime, maxLife   int
-	baseScale           float64
-	angle               float64
-	angularVelocity     float64
-	active              bool
+// Game holds the main state and resources.
+type Game struct {
+	sys *fireburst.System
+
+	// vertical gradient drawn first each frame, built once
+	background *ebiten.Image
+
+	// speedColorMode blends a temperature color based on spawn speed into the lifetime gradient.
+	speedColorMode bool
+
+	// this frame's new touches, read into a reused buffer; left clicks go
+	// through slingshot (plain) and directedClick (shifted) instead
+	tapReader fireburst.TapReader
+	taps      []image.Point
+
+	// pool exhaustion feedback: dropped spawns and the POOL FULL indicator
+	pool fireburst.PoolMeter
+
+	// last left-click position, which shift-click blasts aim away from
+	lastClick     image.Point
+	haveLastClick bool
+
+	// slingshot: whether the left button is held for a gather, and for how
+	// many frames
+	gathering    bool
+	gatherFrames int
 }
 
-// update handles the physics and life of the particle.
-func (p *Particle) update() {
-	if !p.active {
-		return
-	}
-	p.lifetime++
-	if p.lifetime >= p.maxLife {
-		p.active = false
-		return
-	}
+func NewGame() *Game {
+	g := &Game{background: fireburst.GradientBackground(screenWidth, screenHeight, bgTop.RGBA, bgBottom.RGBA)}
+	g.sys = fireburst.NewSystem(maxParticles, rand.New(rand.NewSource(time.Now().UnixNano())), lifetimeColor)
+	// Sort near-to-far so that the particles are drawn far-to-near (painter's algorithm)
+	g.sys.Sort = fireburst.SortByDepthExchange
+	return g
+}
 
-	// Apply physics: movement, gentle upward drift (Y), and damping (Z)
-	p.x += p.vx
-	p.y += p.vy
-	p.z += p.vz
+// lifetimeColor shifts particles from blue at spawn to yellow at death and
+// fades them out quickly (exponential dissipation).
+func lifetimeColor(rate, z float64) (r, g, b, a float32) {
+	r = float32(rate)       // Red increases with life (0 -> 1)
+	g = float32(rate)       // Green increases with life (0 -> 1)
+	b = float32(1.0 - rate) // Blue decreases with life (1 -> 0)
+	a = float32(1.0 - math.Pow(rate, 1.5))
+	return
+}
 
-	p.angle += p.angularVelocity
-	p.vy += 0.02 
-	p.vz *= 0.98 
+// speedTint is the optional temperature tint: fast core particles read
+// white-hot, slow debris cooler.
+func speedTint(p *fireburst.Particle) (r, g, b, w float32) {
+	r, g, b = speedColor(p.SpawnSpeed)
+	return r, g, b, speedColorWeight
 }
 
-// Game holds the main state and resources.
-type Game struct {
-	particles []*Particle
-	vertices  []ebiten.Vertex
-	indices   []uint16
+// directedSpread is the cone width of a shift-click blast.
+const directedSpread = math.Pi / 6
+
+// spawnDirectedExplosion spawns count particles at (x, y) inside a cone
+// spread radians wide around dir, recording any the full pool drops.
+func (g *Game) spawnDirectedExplosion(x, y, dir, spread float64, count int) {
+	g.pool.Record(count - g.sys.ExplodeDirected(x, y, dir, spread, count))
 }
 
-func NewGame() *Game {
-	g := &Game{
-		particles: make([]*Particle, 0, maxParticles),
-		vertices:  make([]ebiten.Vertex, 0, maxParticles*4),
-		indices:   make([]uint16, 0, maxParticles*6),
+// directedClick handles left clicks: it remembers each click, and a
+// shift-click fires a directed blast from the cursor toward the previous
+// click (straight up when there is none).
+func (g *Game) directedClick() {
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
 	}
-	// Initialize object pool
-	for i := 0; i < maxParticles; i++ {
-		g.particles = append(g.particles, &Particle{})
+	mx, my := ebiten.CursorPosition()
+	click := image.Pt(mx, my)
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		dir := -math.Pi / 2
+		if g.haveLastClick && g.lastClick != click {
+			dir = math.Atan2(float64(g.lastClick.Y-my), float64(g.lastClick.X-mx))
+		}
+		g.spawnDirectedExplosion(float64(mx), float64(my), dir, directedSpread, fireburst.BurstSize)
 	}
-	return g
+	g.lastClick, g.haveLastClick = click, true
 }
 
-// allocateParticle finds the next available (inactive) particle from the pool.
-func (g *Game) allocateParticle() *Particle {
-	for _, p := range g.particles {
-		if !p.active {
-			return p
+// Slingshot tuning. Holding left gathers particles near the cursor; a press
+// shorter than slingMinFrames is a plain click and explodes on release.
+const (
+	slingMinFrames = 12
+	slingMaxFrames = 120   // gather time beyond this adds no more impulse
+	slingRadius    = 220.0 // reach of both the pull and the fling, px
+	slingPull      = 0.3   // attraction toward the cursor, px/frame²
+	slingDamp      = 0.92  // velocity kept per gathered frame, so particles settle instead of orbiting
+	slingImpulse   = 0.06  // outward speed added per gathered frame, px/frame
+)
+
+// slingshot tracks a held (unshifted) left button. While held past
+// slingMinFrames it pulls nearby particles toward the cursor; on release it
+// either flings them outward or, for a short press, appends the click to
+// pts as a plain explosion.
+func (g *Game) slingshot(pts []image.Point) []image.Point {
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.gathering, g.gatherFrames = true, 0
+	}
+	if !g.gathering {
+		return pts
+	}
+	mx, my := ebiten.CursorPosition()
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		g.gathering = false
+		if g.gatherFrames < slingMinFrames {
+			return append(pts, image.Pt(mx, my))
 		}
+		g.fling(float64(mx), float64(my), g.gatherFrames)
+		return pts
 	}
-	return nil
+	g.gatherFrames++
+	if g.gatherFrames >= slingMinFrames {
+		g.gather(float64(mx), float64(my))
+	}
+	return pts
 }
 
-// newFireParticle initializes a particle with explosion-specific properties.
-func newFireParticle(x, y float64) *Particle {
-	p := &Particle{
-		active:          true,
-		x:               x + rand.Float64()*4 - 2,
-		y:               y + rand.Float64()*4 - 2,
-		z:               rand.Float64()*2 - 1, // Start depth: -1 (far) to +1 (near)
-		angle:           rand.Float64() * 2 * math.Pi,
-		angularVelocity: (rand.Float64()*2 - 1) * 0.1,
-		maxLife:         rand.Intn(40) + 40,
-		baseScale:       rand.Float64()*0.1 + 0.2,
+// gather pulls every active particle within slingRadius of (x, y) toward it.
+func (g *Game) gather(x, y float64) {
+	for _, p := range g.sys.Particles {
+		if !p.Active {
+			continue
+		}
+		dx, dy := x-p.X, y-p.Y
+		d := math.Hypot(dx, dy)
+		if d > slingRadius || d == 0 {
+			continue
+		}
+		p.VX = p.VX*slingDamp + dx/d*slingPull
+		p.VY = p.VY*slingDamp + dy/d*slingPull
 	}
-	// Radial outward velocity for explosion
-	ang := rand.Float64() * 2 * math.Pi
-	speed := rand.Float64()*4.0 + 2.0
-	p.vx = math.Cos(ang) * speed * 0.3
-	p.vy = math.Sin(ang) * speed * 0.7 
-	p.vz = (rand.Float64()*2 - 1) * 0.5
-	return p
 }
 
-// spawnExplosion creates a large burst of particles at the given screen coordinates.
-func (g *Game) spawnExplosion(x, y float64) {
-	// Spawn 600 particles per click
-	for i := 0; i < 600; i++ {
-		if p := g.allocateParticle(); p != nil {
-			*p = *newFireParticle(x, y)
-		} else {
-			break
+// fling gives every active particle within slingRadius of (x, y) an outward
+// radial impulse proportional to how long it was gathered.
+func (g *Game) fling(x, y float64, frames int) {
+	if frames > slingMaxFrames {
+		frames = slingMaxFrames
+	}
+	impulse := slingImpulse * float64(frames)
+	for _, p := range g.sys.Particles {
+		if !p.Active {
+			continue
+		}
+		dx, dy := p.X-x, p.Y-y
+		d := math.Hypot(dx, dy)
+		if d > slingRadius {
+			continue
+		}
+		if d == 0 {
+			dx, dy, d = 0, -1, 1
 		}
+		p.VX += dx / d * impulse
+		p.VY += dy / d * impulse
 	}
 }
 
-func (g *Game) Update() error {
-	// Handle input: Left Mouse Button spawns an explosion
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		mx, my := ebiten.CursorPosition()
-		g.spawnExplosion(float64(mx), float64(my))
+// speedColor maps a particle speed to a temperature color:
+// slow debris is a cool dark red, fast particles are white-hot.
+func speedColor(speed float64) (r, g, b float32) {
+	t := speed / maxSpawnSpeed
+	r = float32(0.6 + 0.4*clamp01(t*2))
+	g = float32(clamp01(t*1.6 - 0.2))
+	b = float32(clamp01(t*2 - 1))
+	return
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
 	}
+	return v
+}
 
-	// Update all active particles
-	for _, p := range g.particles {
-		if p.active {
-			p.update()
+func (g *Game) Update() error {
+	// Handle input: a short left click or any new touch spawns an explosion,
+	// holding left gathers particles for the slingshot
+	g.pool.Tick()
+	g.taps = g.tapReader.Append(g.taps[:0])
+	g.taps = g.slingshot(g.taps)
+	g.pool.Record(g.sys.ExplodeAt(g.taps))
+	g.directedClick()
+
+	// V toggles the spawn-speed (temperature) color mode
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		g.speedColorMode = !g.speedColorMode
+		g.sys.Tint = nil
+		if g.speedColorMode {
+			g.sys.Tint = speedTint
 		}
 	}
+
+	// Update all active particles
+	g.sys.Step()
 	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
 	// Dark background for maximum glow contrast
-	screen.Fill(color.RGBA{10, 10, 20, 255}) 
-
-	g.vertices = g.vertices[:0]
-	g.indices = g.indices[:0]
-	fireVertexCount := 0
-
-	sx0, sy0 := 0.0, 0.0
-	sx1, sy1 := fireImageW, fireImageH
-	halfW, halfH := fireImageW/2.0, fireImageH/2.0
-
-	// Sort particles by Z-depth to ensure correct drawing order (near particles draw last)
-	// NOTE: This simple sort is only for visual accuracy and can be costly, but is crucial for 3D illusion.
-	// For massive scale, sorting would be optional or handled with a depth buffer.
-	activeParticles := make([]*Particle, 0, len(g.particles))
-	for _, p := range g.particles {
-		if p.active {
-			activeParticles = append(activeParticles, p)
-		}
-	}
-	// Sort near-to-far so that the particles are drawn far-to-near (painter's algorithm)
-	for i := range activeParticles {
-		for j := i + 1; j < len(activeParticles); j++ {
-			if activeParticles[i].z > activeParticles[j].z {
-				activeParticles[i], activeParticles[j] = activeParticles[j], activeParticles[i]
-			}
-		}
-	}
+	screen.DrawImage(g.background, nil)
 
-	for _, p := range activeParticles {
-		rate := float64(p.lifetime) / float64(p.maxLife)
-		
-		// --- 1. Lifetime Color Transition (Blue -> Yellow) ---
-		// rate = 0 (Start) -> R=0.0, G=0.0, B=1.0 (Pure Blue)
-		// rate = 1 (End)   -> R=1.0, G=1.0, B=0.0 (Pure Yellow)
-		r := float32(rate)           // Red increases with life (0 -> 1)
-		gcol := float32(rate)        // Green increases with life (0 -> 1) <--- MODIFIED LINE
-		b := float32(1.0 - rate)     // Blue decreases with life (1 -> 0)
-		
-		// Alpha fade out (Exponential fade for a quick dissipation)
-		alpha := float32(1.0 - math.Pow(rate, 1.5)) 
-
-		// --- 2. 3D Scaling (Depth) ---
-		// Far (negative Z) particles are smaller; Near (positive Z) particles are larger.
-		// The scale is combined with the growth over life.
-		depthScale := float64(1.0 / (1.0 + p.z*0.5)) // Simple perspective scale
-		scale := p.baseScale * (1.0 + 0.5*rate) * depthScale
-
-		// --- 3. Geometry Calculation ---
-		var geo ebiten.GeoM
-		geo.Translate(-halfW, -halfH)
-		geo.Rotate(p.angle)
-		geo.Scale(scale, scale)
-		geo.Translate(p.x, p.y)
-
-		// --- 4. Batching Vertices ---
-		vIndex := uint16(fireVertexCount)
-		fireVertexCount += 4
-		
-		// Map texture coordinates (SrcX/Y) to screen coordinates (DstX/Y)
-		corners := []struct{ dx, dy, sx, sy float64 }{
-			{0, 0, sx0, sy0},
-			{0, fireImageH, sx0, sy1},
-			{fireImageW, 0, sx1, sy0},
-			{fireImageW, fireImageH, sx1, sy1},
-		}
-		for _, c := range corners {
-			vx, vy := geo.Apply(c.dx, c.dy)
-			// Premultiply color by alpha for correct blending
-			g.vertices = append(g.vertices, ebiten.Vertex{
-				DstX: float32(vx), DstY: float32(vy),
-				SrcX: float32(c.sx), SrcY: float32(c.sy),
-				ColorR: r * alpha,
-				ColorG: gcol * alpha,
-				ColorB: b * alpha,
-				ColorA: alpha, // Alpha component is critical for Additive Blending
-			})
-		}
-		// Indices for the two triangles that form the quad
-		g.indices = append(g.indices, vIndex, vIndex+1, vIndex+2, vIndex+1, vIndex+3, vIndex+2)
-	}
-
-	// --- Final Batch Draw Call ---
-	if len(g.vertices) > 0 && len(g.indices) > 0 {
-		// CompositeModeLighter is Additive Blending: required for fire/glow effects
-		op := &ebiten.DrawTrianglesOptions{CompositeMode: ebiten.CompositeModeLighter}
-		screen.DrawTriangles(g.vertices, g.indices, fireImage, op)
-	}
+	n := g.sys.Draw(screen, fireImage)
 
 	// Debug statistics display
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("Particles: %d/%d\n[LMB] Explosion (Color: Blue→Yellow over Life)", len(activeParticles), maxParticles))
+	colorMode := "Blue→Yellow over Life"
+	if g.speedColorMode {
+		colorMode += " + Speed"
+	}
+	msg := fmt.Sprintf("Particles: %d/%d\nDropped spawns: %d\n[LMB] Explosion (Color: %s)\n[Hold LMB] Gather, release to fling\n[Shift+LMB] Blast toward the previous click\n[V] Toggle speed color", n, maxParticles, g.pool.Dropped, colorMode)
+	if g.gathering && g.gatherFrames >= slingMinFrames {
+		msg += fmt.Sprintf("\nSlingshot charge: %d%%", 100*min(g.gatherFrames, slingMaxFrames)/slingMaxFrames)
+	}
+	ebitenutil.DebugPrint(screen, msg)
+	g.pool.Draw(screen)
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -265,6 +285,10 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func main() {
+	flag.Var(&bgTop, "bgtop", "background color at the top of the screen, rrggbb")
+	flag.Var(&bgBottom, "bgbottom", "background color at the bottom of the screen, rrggbb")
+	flag.Parse()
+
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("🔥 3D Depth Particles: Lifetime Color Shift (Blue→Yellow)")
 	ebiten.SetTPS(60)
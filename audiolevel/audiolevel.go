@@ -0,0 +1,53 @@
+// Package audiolevel measures the loudness of raw signed 16-bit
+// little-endian PCM, the format platform recorders such as arecord write,
+// for demos that react to sound. Ebiten's audio package only plays sound,
+// so capture is left to those tools.
+package audiolevel
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// BlockRMS is the RMS of s16le samples as a fraction of full scale. A
+// trailing odd byte is ignored.
+func BlockRMS(block []byte) float64 {
+	n := len(block) / 2
+	if n == 0 {
+		return 0
+	}
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		v := float64(int16(binary.LittleEndian.Uint16(block[2*i:]))) / 32768
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// Paced returns a reader that hands out r's bytes no faster than rate
+// bytes per second, counted from the first Read, so a recording on disk
+// plays back in real time like a live capture instead of being consumed
+// all at once.
+func Paced(r io.Reader, rate float64) io.Reader {
+	return &pacedReader{r: r, rate: rate}
+}
+
+type pacedReader struct {
+	r     io.Reader
+	rate  float64
+	start time.Time
+	n     int64 // bytes handed out so far
+}
+
+func (p *pacedReader) Read(b []byte) (int, error) {
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	k, err := p.r.Read(b)
+	p.n += int64(k)
+	due := p.start.Add(time.Duration(float64(p.n) / p.rate * float64(time.Second)))
+	time.Sleep(time.Until(due))
+	return k, err
+}
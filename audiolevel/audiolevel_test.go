@@ -0,0 +1,61 @@
+package audiolevel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// samples encodes vs as s16le.
+func samples(vs ...int16) []byte {
+	b := make([]byte, 2*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+func TestBlockRMS(t *testing.T) {
+	sine := make([]int16, 44100)
+	for i := range sine {
+		sine[i] = int16(0.5 * 32767 * math.Sin(2*math.Pi*440*float64(i)/44100))
+	}
+	for _, c := range []struct {
+		name  string
+		block []byte
+		want  float64
+	}{
+		{"empty", nil, 0},
+		{"one odd byte", []byte{0x7f}, 0},
+		{"silence", samples(0, 0, 0, 0), 0},
+		{"negative full scale", samples(-32768, -32768), 1},
+		{"square wave", samples(16384, -16384, 16384, -16384), 0.5},
+		{"trailing byte ignored", append(samples(16384, -16384), 0x7f), 0.5},
+		{"0.5 sine", samples(sine...), 0.5 / math.Sqrt2},
+	} {
+		if got := BlockRMS(c.block); math.Abs(got-c.want) > 1e-4 {
+			t.Errorf("%s: RMS %.5f, want %.5f", c.name, got, c.want)
+		}
+	}
+}
+
+// TestPaced reads a quarter second of data at its pace and checks it takes
+// about that long and arrives intact.
+func TestPaced(t *testing.T) {
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, 1000)
+	start := time.Now()
+	got, err := io.ReadAll(Paced(bytes.NewReader(data), float64(len(data))*4))
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("paced reader returned %d bytes, want the %d written", len(got), len(data))
+	}
+	if elapsed < 250*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("reading a quarter second of data took %v", elapsed)
+	}
+}
@@ -0,0 +1,473 @@
+package bubbles
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/arcesoftware/GO_Examples/orbitcam"
+)
+
+// neighborRadius is the world-space radius used for neighbor queries. It is
+// twice the largest bubble radius (baseSize < 5), so the same query finds
+// every bubble a separation pass could overlap.
+const neighborRadius = 30.0
+
+// MaxSeparation bounds the separation strength set with [ and ].
+const MaxSeparation = 0.5
+
+// Occlusion (O, -occlusion) darkens bubbles by how crowded they are.
+// Each neighbor within neighborRadius adds 1 - d/neighborRadius, and
+// crowdingSaturation of that is fully crowded. defaultOcclusion is how much
+// a fully crowded bubble darkens.
+const (
+	crowdingSaturation = 1.5
+	defaultOcclusion   = 0.5
+)
+
+// defaultWrapScale is the default wrap bound as a multiple of worldRadius.
+// wrapHardScale is how far past that bound a visible bubble may drift
+// before it is wrapped anyway.
+const (
+	defaultWrapScale = 1.0
+	wrapHardScale    = 1.25
+)
+
+// Options configures a game built by NewGame.
+type Options struct {
+	Seed int64 // seeds every spawn
+
+	// perspective: camera distance from the cloud center and focal length
+	// in pixels
+	CameraDist  float64
+	FocalLength float64
+
+	// orbit camera; nil orbits with orbitcam's defaults
+	Camera *orbitcam.Camera
+
+	Antialias         bool    // anti-aliased circles
+	AAMinSize         float64 // radius in pixels below which circles are aliased
+	Separation        float64 // soft separation strength, 0 to MaxSeparation
+	Wrap              bool    // toroidal world
+	WrapScale         float64 // wrap bound as a multiple of the spawn radius
+	Occlusion         bool    // darken crowded bubbles
+	OcclusionStrength float64 // brightness a fully crowded bubble loses, 0 to 1
+}
+
+// DefaultOptions returns the demo's starting settings.
+func DefaultOptions() Options {
+	return Options{
+		Seed:              1,
+		CameraDist:        defaultCameraDist,
+		FocalLength:       defaultFocalLength,
+		Antialias:         true,
+		WrapScale:         defaultWrapScale,
+		OcclusionStrength: defaultOcclusion,
+	}
+}
+
+// Game is one bubble cloud and its camera.
+type Game struct {
+	particles []*Particle
+	tick      int
+
+	// orbit camera (-orbit-speed, -pitch-amp), advanced by wall-clock time
+	// so its speed doesn't follow the tick rate
+	cam        *orbitcam.Camera
+	lastUpdate time.Time
+
+	// perspective: camera distance from the cloud center and focal length in pixels
+	cameraDist  float64
+	focalLength float64
+
+	hash         *spatialHash
+	neighbors    []int   // scratch buffer for hash queries
+	avgNeighbors float64 // mean neighbor count within neighborRadius this frame
+
+	sortScratch []drawItem // radix sort buffer
+
+	glow bool // additive neon mode (G)
+
+	// ambient-occlusion-like darkening (O, -occlusion): crowded bubbles
+	// lose up to occlusionStrength (-occlusionstrength) of their brightness
+	occlusion         bool
+	occlusionStrength float64
+
+	// anti-aliased circles (A, -aa), skipped for bubbles with a radius
+	// under aaMinSize pixels (-aaminsize), where the edge is barely visible
+	antialias bool
+	aaMinSize float64
+
+	// soft separation ([/], -separation): the fraction of an overlap
+	// between two bubbles' drawn radii turned into a velocity pushing
+	// them apart each tick; 0 = off
+	separation float64
+
+	// toroidal world (W, -wrap): a bubble past ±worldRadius*wrapScale on
+	// any axis re-enters through the opposite face with its velocity kept.
+	// wraps and poppedWraps count how many wrapped, and how many of those
+	// had to while visible.
+	wrap        bool
+	wrapScale   float64
+	wraps       int
+	poppedWraps int
+
+	rng *rand.Rand // drives every spawn
+}
+
+// NewGame builds an empty cloud from opts; it fills over the first ticks.
+func NewGame(opts Options) *Game {
+	cam := opts.Camera
+	if cam == nil {
+		cam = orbitcam.New()
+	}
+	return &Game{
+		cameraDist:        opts.CameraDist,
+		focalLength:       opts.FocalLength,
+		cam:               cam,
+		antialias:         opts.Antialias,
+		aaMinSize:         opts.AAMinSize,
+		separation:        opts.Separation,
+		wrap:              opts.Wrap,
+		wrapScale:         opts.WrapScale,
+		occlusion:         opts.Occlusion,
+		occlusionStrength: opts.OcclusionStrength,
+		rng:               rand.New(rand.NewSource(opts.Seed)),
+	}
+}
+
+// Count is how many bubbles are alive.
+func (g *Game) Count() int {
+	return len(g.particles)
+}
+
+// discSize is the diameter of the cached disc sprite used by glow mode.
+const discSize = 64
+
+// discImage is a white anti-aliased disc. Glow mode draws every bubble as a
+// tinted copy of it with additive blending, which vector.DrawFilledCircle
+// can't do because it always composites source-over.
+var discImage *ebiten.Image
+
+func disc() *ebiten.Image {
+	if discImage == nil {
+		discImage = ebiten.NewImage(discSize, discSize)
+		vector.DrawFilledCircle(discImage, discSize/2, discSize/2, discSize/2, color.White, true)
+	}
+	return discImage
+}
+
+// antialiasFor reports whether a bubble of the given radius is drawn
+// anti-aliased.
+func (g *Game) antialiasFor(size float64) bool {
+	return g.antialias && size >= g.aaMinSize
+}
+
+// drawItem is one projected bubble queued for drawing.
+type drawItem struct {
+	x, y, size, depth, alpha float64
+	col                      color.RGBA
+}
+
+// radixMinItems is the size below which sortByDepth uses sort.SliceStable.
+const radixMinItems = 256
+
+// sortByDepth orders items far to near (descending depth). Larger sets use a
+// stable LSD radix sort on the float32 bits of the depth, which is linear and
+// keeps equal depths in their original order so they never swap between frames.
+func (g *Game) sortByDepth(items []drawItem) {
+	if len(items) < radixMinItems {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].depth > items[j].depth })
+		return
+	}
+	if cap(g.sortScratch) < len(items) {
+		g.sortScratch = make([]drawItem, len(items))
+	}
+	src, dst := items, g.sortScratch[:len(items)]
+	// Depths are positive (Project rejects z <= 10), so the IEEE bits order
+	// like the values; inverting them gives descending order.
+	key := func(d float64) uint32 { return ^math.Float32bits(float32(d)) }
+	for shift := uint(0); shift < 32; shift += 8 {
+		var count [257]int
+		for _, it := range src {
+			count[(key(it.depth)>>shift)&0xff+1]++
+		}
+		for i := 1; i < 257; i++ {
+			count[i] += count[i-1]
+		}
+		for _, it := range src {
+			b := (key(it.depth) >> shift) & 0xff
+			dst[count[b]] = it
+			count[b]++
+		}
+		src, dst = dst, src
+	}
+	// four passes: the result is back in items
+}
+
+// buildHash rebuilds the spatial hash over the current particle positions.
+func (g *Game) buildHash() {
+	if g.hash == nil {
+		g.hash = newSpatialHash(neighborRadius)
+	}
+	g.hash.reset()
+	for i, p := range g.particles {
+		g.hash.insert(i, p.x, p.y, p.z)
+	}
+}
+
+func (g *Game) spawn(n int) {
+	for i := 0; i < n && len(g.particles) < maxParticles; i++ {
+		g.particles = append(g.particles, NewParticle(g.rng))
+	}
+}
+
+// step advances the simulation one tick: spawning, camera motion by dt
+// seconds and particle updates.
+func (g *Game) step(dt float64) {
+	g.tick++
+	if g.tick%2 == 0 {
+		g.spawn(spawnPerTick)
+	}
+	g.cam.Update(dt)
+
+	write := 0
+	for _, p := range g.particles {
+		if p.Update() {
+			g.particles[write] = p
+			write++
+		}
+	}
+	g.particles = g.particles[:write]
+	if g.wrap {
+		for _, p := range g.particles {
+			g.wrapParticle(p)
+		}
+	}
+
+	// occasionally inject new ones from center so cloud regenerates
+	if len(g.particles) < maxParticles/3 {
+		g.spawn(40)
+	}
+}
+
+// wrapCoord folds v back into [-bound, bound] through the opposite side.
+func wrapCoord(v, bound float64) float64 {
+	if v > bound {
+		return v - 2*bound
+	}
+	if v < -bound {
+		return v + 2*bound
+	}
+	return v
+}
+
+// wrapParticle moves a bubble that has left the wrap cube to the opposite
+// face, keeping its velocity. To avoid visible popping it waits until the
+// bubble is hidden both where it is and where it would land, unless it has
+// drifted past the hard bound.
+func (g *Game) wrapParticle(p *Particle) {
+	bound := worldRadius * g.wrapScale
+	if math.Abs(p.x) <= bound && math.Abs(p.y) <= bound && math.Abs(p.z) <= bound {
+		return
+	}
+	moved := *p
+	moved.x, moved.y, moved.z = wrapCoord(p.x, bound), wrapCoord(p.y, bound), wrapCoord(p.z, bound)
+	hard := bound * wrapHardScale
+	forced := math.Abs(p.x) > hard || math.Abs(p.y) > hard || math.Abs(p.z) > hard
+	hidden := g.hidden(p) && g.hidden(&moved)
+	if !hidden && !forced {
+		return
+	}
+	p.x, p.y, p.z = moved.x, moved.y, moved.z
+	g.wraps++
+	if !hidden {
+		g.poppedWraps++
+	}
+}
+
+// hidden reports whether Draw would show nothing of p: it is behind the
+// camera, too faded to draw, or entirely off screen.
+func (g *Game) hidden(p *Particle) bool {
+	sx, sy, scale, depth, ok := p.Project(g.cam.Yaw, g.cam.Pitch, g.cameraDist, g.focalLength)
+	if !ok || uint8(255*p.alpha(depth)) < minDrawAlpha {
+		return true
+	}
+	r := p.radius() * scale
+	return sx+r < 0 || sx-r > ScreenWidth || sy+r < 0 || sy-r > ScreenHeight
+}
+
+// separate nudges bubble i apart from each overlapping neighbor in ids with
+// equal and opposite velocity changes. Each pair is handled once, by its
+// lower index.
+func (g *Game) separate(i int, ids []int) {
+	p := g.particles[i]
+	for _, j := range ids {
+		if j <= i {
+			continue
+		}
+		q := g.particles[j]
+		dx, dy, dz := q.x-p.x, q.y-p.y, q.z-p.z
+		d := math.Sqrt(dx*dx + dy*dy + dz*dz)
+		overlap := p.radius() + q.radius() - d
+		if overlap <= 0 || d == 0 {
+			continue
+		}
+		push := g.separation * overlap / 2 / d
+		p.vx, p.vy, p.vz = p.vx-dx*push, p.vy-dy*push, p.vz-dz*push
+		q.vx, q.vy, q.vz = q.vx+dx*push, q.vy+dy*push, q.vz+dz*push
+	}
+}
+
+// crowdingOf is bubble i's crowding factor from the neighbors in ids (which
+// may include i itself): the summed closeness of each, saturating at 1.
+func (g *Game) crowdingOf(i int, ids []int) float64 {
+	p := g.particles[i]
+	sum := 0.0
+	for _, j := range ids {
+		if j == i {
+			continue
+		}
+		q := g.particles[j]
+		dx, dy, dz := q.x-p.x, q.y-p.y, q.z-p.z
+		d := math.Sqrt(dx*dx + dy*dy + dz*dz)
+		sum += math.Max(1-d/neighborRadius, 0)
+	}
+	return math.Min(sum/crowdingSaturation, 1)
+}
+
+// shade is the color p is drawn with: darkened by its crowding when
+// occlusion is on.
+func (g *Game) shade(p *Particle) color.RGBA {
+	c := p.color
+	if !g.occlusion {
+		return c
+	}
+	k := 1 - g.occlusionStrength*p.crowding
+	c.R, c.G, c.B = uint8(float64(c.R)*k), uint8(float64(c.G)*k), uint8(float64(c.B)*k)
+	return c
+}
+
+// updateNeighbors rebuilds the spatial hash and runs the per-bubble
+// neighbor pass: the average neighbor count, separation and crowding.
+func (g *Game) updateNeighbors() {
+	g.buildHash()
+	total := 0
+	for i, p := range g.particles {
+		g.neighbors = g.hash.query(p.x, p.y, p.z, neighborRadius, g.neighbors[:0])
+		total += len(g.neighbors) - 1 // minus the particle itself
+		if g.separation > 0 {
+			g.separate(i, g.neighbors)
+		}
+		p.crowding = 0
+		if g.occlusion {
+			p.crowding = g.crowdingOf(i, g.neighbors)
+		}
+	}
+	g.avgNeighbors = 0
+	if len(g.particles) > 0 {
+		g.avgNeighbors = float64(total) / float64(len(g.particles))
+	}
+}
+
+func (g *Game) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		g.glow = !g.glow
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		g.antialias = !g.antialias
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		g.wrap = !g.wrap
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		g.occlusion = !g.occlusion
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+		g.separation = math.Min(g.separation+0.01, MaxSeparation)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+		g.separation = math.Max(g.separation-0.01, 0)
+	}
+
+	// Perspective controls: Up/Down dolly the camera, =/- change the focal length
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		g.cameraDist = math.Max(g.cameraDist-5, 250)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		g.cameraDist = math.Min(g.cameraDist+5, 3000)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyEqual) {
+		g.focalLength = math.Min(g.focalLength*1.01, 4000)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyMinus) {
+		g.focalLength = math.Max(g.focalLength/1.01, 50)
+	}
+
+	now := time.Now()
+	dt := 0.0
+	if !g.lastUpdate.IsZero() {
+		dt = math.Min(now.Sub(g.lastUpdate).Seconds(), maxFrameDelta)
+	}
+	g.lastUpdate = now
+
+	g.step(dt)
+	g.updateNeighbors()
+	return nil
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{10, 14, 28, 255})
+
+	items := make([]drawItem, 0, len(g.particles))
+
+	for _, p := range g.particles {
+		sx, sy, scale, depth, ok := p.Project(g.cam.Yaw, g.cam.Pitch, g.cameraDist, g.focalLength)
+		if !ok {
+			continue
+		}
+		size := p.radius() * scale
+
+		items = append(items, drawItem{sx, sy, size, depth, p.alpha(depth), g.shade(p)})
+	}
+
+	g.sortByDepth(items)
+
+	for _, it := range items {
+		c := it.col
+		a := uint8(255 * it.alpha)
+		if a < minDrawAlpha {
+			continue
+		}
+		if g.glow {
+			// overlapping bubbles add up and brighten toward white
+			op := &ebiten.DrawImageOptions{CompositeMode: ebiten.CompositeModeLighter}
+			s := it.size * 2 / discSize
+			op.GeoM.Translate(-discSize/2, -discSize/2)
+			op.GeoM.Scale(s, s)
+			op.GeoM.Translate(it.x, it.y)
+			op.ColorScale.Scale(float32(c.R)/0xff, float32(c.G)/0xff, float32(c.B)/0xff, 1)
+			op.ColorScale.ScaleAlpha(float32(it.alpha) * 0.6)
+			screen.DrawImage(disc(), op)
+			continue
+		}
+		c.A = a
+		vector.DrawFilledCircle(screen, float32(it.x), float32(it.y), float32(it.size), c, g.antialiasFor(it.size))
+	}
+
+	// horizontal field of view equivalent to the focal length
+	fov := 2 * math.Atan(ScreenWidth/2/g.focalLength) * 180 / math.Pi
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("Particles: %d\nTPS: %.2f\nAvg neighbors (r=%.0f): %.1f\nCamera (Up/Down): %.0f  Focal (=/-): %.0fpx  FOV: %.1f deg\nGlow (G): %v  Anti-aliasing (A): %v (radius >= %.1fpx)\nSeparation ([/]): %.2f  Occlusion (O): %v (strength %.2f)\nWrap (W): %v at ±%.0f (%d wraps, %d visible)",
+		len(g.particles), ebiten.ActualTPS(), neighborRadius, g.avgNeighbors, g.cameraDist, g.focalLength, fov, g.glow, g.antialias, g.aaMinSize, g.separation, g.occlusion, g.occlusionStrength,
+		g.wrap, worldRadius*g.wrapScale, g.wraps, g.poppedWraps))
+}
+
+func (g *Game) Layout(ow, oh int) (int, int) { return ScreenWidth, ScreenHeight }
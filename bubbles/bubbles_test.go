@@ -0,0 +1,354 @@
+package bubbles
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/arcesoftware/GO_Examples/gametest"
+)
+
+func TestMain(m *testing.M) {
+	gametest.Main(m)
+}
+
+// tickDelta is the seconds per step the tests advance the camera by.
+const tickDelta = 1.0 / 60
+
+// warmedUp is a default cloud from seed 1, with opts applied, stepped
+// through ticks ticks.
+func warmedUp(opts Options, ticks int) *Game {
+	g := NewGame(opts)
+	for t := 0; t < ticks; t++ {
+		g.step(tickDelta)
+	}
+	return g
+}
+
+// TestPopulation runs the simulation headless and checks that after a
+// warm-up the particle count stays within [lo, hi] on every tick. It then
+// empties the cloud and checks that the refill below maxParticles/3
+// brings it back into range within the warm-up.
+func TestPopulation(t *testing.T) {
+	const warmup, ticks = 300, 3000
+	lo, hi := maxParticles/4, maxParticles
+	g := warmedUp(DefaultOptions(), warmup)
+	for tick := 0; tick < ticks; tick++ {
+		g.step(tickDelta)
+		if n := len(g.particles); n < lo || n > hi {
+			t.Fatalf("tick %d: population %d outside [%d, %d]", warmup+tick, n, lo, hi)
+		}
+	}
+
+	g.particles = g.particles[:0]
+	g.step(tickDelta)
+	if n := len(g.particles); n < 40 {
+		t.Fatalf("emptied cloud refilled to %d particles, want at least 40", n)
+	}
+	for refill := 1; len(g.particles) < lo; refill++ {
+		if refill == warmup {
+			t.Fatalf("emptied cloud only reached %d of %d particles after %d ticks", len(g.particles), lo, warmup)
+		}
+		g.step(tickDelta)
+	}
+}
+
+// TestWrap runs the simulation headless with wrapping on and checks that
+// no bubble ends a tick past the hard bound and that some did wrap.
+func TestWrap(t *testing.T) {
+	const ticks = 3000
+	opts := DefaultOptions()
+	opts.Wrap = true
+	g := NewGame(opts)
+	hard := worldRadius * g.wrapScale * wrapHardScale
+	for tick := 0; tick < ticks; tick++ {
+		g.step(tickDelta)
+		for _, p := range g.particles {
+			if math.Abs(p.x) > hard || math.Abs(p.y) > hard || math.Abs(p.z) > hard {
+				t.Fatalf("tick %d: bubble at (%.1f, %.1f, %.1f), past the hard bound %.1f", tick, p.x, p.y, p.z, hard)
+			}
+		}
+	}
+	if g.wraps == 0 {
+		t.Fatalf("no bubble wrapped in %d ticks", ticks)
+	}
+	t.Logf("%d wraps over %d ticks, %d of them visible", g.wraps, ticks, g.poppedWraps)
+}
+
+// TestOcclusion checks that a lone bubble isn't darkened, that two
+// neighbors d away crowd a bubble by 2(1-d/neighborRadius)/crowdingSaturation
+// and darken it by that times occlusionStrength, and that a packed cluster
+// saturates and loses exactly occlusionStrength of its brightness.
+func TestOcclusion(t *testing.T) {
+	base := color.RGBA{200, 200, 255, 255}
+	at := func(x float64) *Particle {
+		return &Particle{x: x, life: 1, maxLife: 1, baseSize: 2, color: base}
+	}
+	scaled := func(k float64) color.RGBA {
+		return color.RGBA{uint8(float64(base.R) * k), uint8(float64(base.G) * k), uint8(float64(base.B) * k), base.A}
+	}
+	for _, strength := range []float64{0, 0.25, defaultOcclusion, 1} {
+		g := &Game{occlusion: true, occlusionStrength: strength}
+		g.particles = []*Particle{at(0), at(1000)}
+		g.updateNeighbors()
+		if c := g.particles[0].crowding; c != 0 {
+			t.Errorf("lone bubble has crowding %v, want 0", c)
+		}
+		if c := g.shade(g.particles[0]); c != base {
+			t.Errorf("strength %v: lone bubble drawn %v, want its own color %v", strength, c, base)
+		}
+		for _, d := range []float64{25, 15, 5} {
+			g.particles = []*Particle{at(0), at(d), at(-d)}
+			g.updateNeighbors()
+			want := math.Min(2*(1-d/neighborRadius)/crowdingSaturation, 1)
+			if c := g.particles[0].crowding; math.Abs(c-want) > 1e-12 {
+				t.Errorf("neighbors %v away: crowding %v, want %v", d, c, want)
+			}
+			if c, want := g.shade(g.particles[0]), scaled(1-strength*want); c != want {
+				t.Errorf("strength %v, neighbors %v away: drawn %v, want %v", strength, d, c, want)
+			}
+		}
+		g.particles = []*Particle{at(0), at(8), at(-8), at(1000)}
+		for k := 0; k < 4; k++ {
+			q := at(0)
+			q.y, q.z = 8*math.Cos(float64(k)*math.Pi/2), 8*math.Sin(float64(k)*math.Pi/2)
+			g.particles = append(g.particles, q)
+		}
+		g.updateNeighbors()
+		if c := g.particles[0].crowding; c != 1 {
+			t.Errorf("bubble packed among 6 neighbors has crowding %v, want 1", c)
+		}
+		if c, want := g.shade(g.particles[0]), scaled(1-strength); c != want {
+			t.Errorf("strength %v: fully crowded bubble drawn %v, want %v", strength, c, want)
+		}
+	}
+}
+
+// TestOcclusionCloud checks that in a warmed-up cloud the crowding stays
+// in [0, 1] and only crowded bubbles are darkened, never brightened.
+func TestOcclusionCloud(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Occlusion = true
+	g := warmedUp(opts, 300)
+	g.updateNeighbors()
+	darkened := 0
+	for _, p := range g.particles {
+		if p.crowding < 0 || p.crowding > 1 {
+			t.Fatalf("crowding %v outside [0, 1]", p.crowding)
+		}
+		c := g.shade(p)
+		if p.crowding == 0 && c != p.color {
+			t.Fatalf("uncrowded bubble drawn %v, want its own color %v", c, p.color)
+		}
+		if c.R > p.color.R || c.G > p.color.G || c.B > p.color.B || c.A != p.color.A {
+			t.Fatalf("bubble with crowding %v drawn %v, brighter than its color %v", p.crowding, c, p.color)
+		}
+		if p.crowding > 0 {
+			darkened++
+		}
+	}
+	if darkened == 0 {
+		t.Errorf("no bubble of %d darkened", len(g.particles))
+	}
+}
+
+// TestSpawnDistribution draws many particles from a seeded generator and
+// checks they fill the worldRadius sphere uniformly: every one inside it,
+// the eight octants evenly populated, an eighth of them within half the
+// radius, velocities pointing outward, and the same seed reproducing the
+// same particle.
+func TestSpawnDistribution(t *testing.T) {
+	const n = 100000
+	rng := rand.New(rand.NewSource(1))
+	var octants [8]int
+	inner := 0
+	for i := 0; i < n; i++ {
+		p := NewParticle(rng)
+		r := math.Sqrt(p.x*p.x + p.y*p.y + p.z*p.z)
+		switch {
+		case r > worldRadius+1e-9:
+			t.Fatalf("particle at radius %v, outside the %v sphere", r, worldRadius)
+		case p.vx*p.x+p.vy*p.y+p.vz*p.z < 0:
+			t.Fatalf("particle at (%.1f, %.1f, %.1f) moves inward", p.x, p.y, p.z)
+		case p.maxLife < 100 || p.maxLife >= 220 || p.life != p.maxLife:
+			t.Fatalf("life %d/%d, want a full life in [100, 220)", p.life, p.maxLife)
+		case p.baseSize < 2 || p.baseSize >= 5:
+			t.Fatalf("size %v outside [2, 5)", p.baseSize)
+		}
+		o := 0
+		if p.x > 0 {
+			o |= 1
+		}
+		if p.y > 0 {
+			o |= 2
+		}
+		if p.z > 0 {
+			o |= 4
+		}
+		octants[o]++
+		if r < worldRadius/2 {
+			inner++
+		}
+	}
+	// each count is binomial(n, 1/8); allow four standard deviations, so the
+	// check neither fails by chance nor goes slack
+	want := float64(n) / 8
+	tol := 4 * math.Sqrt(want*(1-1.0/8))
+	for i, c := range octants {
+		if math.Abs(float64(c)-want) > tol {
+			t.Errorf("octant %d holds %d of %d particles, want %.0f ± %.0f", i, c, n, want, tol)
+		}
+	}
+	// uniform in volume: (1/2)^3 of the particles lie within half the radius
+	if math.Abs(float64(inner)-want) > tol {
+		t.Errorf("%d of %d particles within half the radius, want %.0f ± %.0f", inner, n, want, tol)
+	}
+
+	a := NewParticle(rand.New(rand.NewSource(42)))
+	b := NewParticle(rand.New(rand.NewSource(42)))
+	if *a != *b {
+		t.Errorf("same seed gave different particles: %+v vs %+v", *a, *b)
+	}
+}
+
+// randomDepths is n draw items at random depths from a seeded generator.
+func randomDepths(n int) []drawItem {
+	rng := rand.New(rand.NewSource(1))
+	items := make([]drawItem, n)
+	for i := range items {
+		items[i].depth = 200 + rng.Float64()*1000
+	}
+	return items
+}
+
+// TestSortByDepth checks that sortByDepth orders items far to near on both
+// sides of radixMinItems.
+func TestSortByDepth(t *testing.T) {
+	g := &Game{}
+	for _, n := range []int{radixMinItems / 2, maxParticles} {
+		items := randomDepths(n)
+		g.sortByDepth(items)
+		// the radix sort keys on float32 depths, so depths equal at that
+		// precision may come out in either order; compare at the same
+		// precision
+		for i := 1; i < n; i++ {
+			if float32(items[i].depth) > float32(items[i-1].depth) {
+				t.Fatalf("%d items: out of order at %d: %v before %v", n, i, items[i-1].depth, items[i].depth)
+			}
+		}
+	}
+}
+
+// BenchmarkDepthSort times sort.Slice against sortByDepth on a full cloud
+// of random depths.
+func BenchmarkDepthSort(b *testing.B) {
+	base := randomDepths(maxParticles)
+	items := make([]drawItem, len(base))
+	b.Run("SortSlice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(items, base)
+			sort.Slice(items, func(i, j int) bool { return items[i].depth > items[j].depth })
+		}
+	})
+	b.Run("Radix", func(b *testing.B) {
+		g := &Game{}
+		for i := 0; i < b.N; i++ {
+			copy(items, base)
+			g.sortByDepth(items)
+		}
+	})
+}
+
+// spawned is n particles from seed 1.
+func spawned(n int) []*Particle {
+	rng := rand.New(rand.NewSource(1))
+	ps := make([]*Particle, n)
+	for i := range ps {
+		ps[i] = NewParticle(rng)
+	}
+	return ps
+}
+
+// bruteNeighbors counts, for every particle, the particles within
+// neighborRadius of it (itself included) by comparing every pair.
+func bruteNeighbors(ps []*Particle, counts []int) {
+	for i, a := range ps {
+		counts[i] = 0
+		for _, b := range ps {
+			dx, dy, dz := a.x-b.x, a.y-b.y, a.z-b.z
+			if dx*dx+dy*dy+dz*dz <= neighborRadius*neighborRadius {
+				counts[i]++
+			}
+		}
+	}
+}
+
+// hashNeighbors is bruteNeighbors through the game's spatial hash.
+func hashNeighbors(g *Game, counts []int) {
+	g.buildHash()
+	for i, p := range g.particles {
+		g.neighbors = g.hash.query(p.x, p.y, p.z, neighborRadius, g.neighbors[:0])
+		counts[i] = len(g.neighbors)
+	}
+}
+
+// TestNeighbors checks that the spatial hash finds the same neighbor
+// counts as brute force.
+func TestNeighbors(t *testing.T) {
+	ps := spawned(maxParticles)
+	brute, hashed := make([]int, len(ps)), make([]int, len(ps))
+	bruteNeighbors(ps, brute)
+	hashNeighbors(&Game{particles: ps}, hashed)
+	for i := range brute {
+		if brute[i] != hashed[i] {
+			t.Fatalf("particle %d: brute force found %d neighbors, hash found %d", i, brute[i], hashed[i])
+		}
+	}
+}
+
+// BenchmarkNeighbors compares brute-force O(n^2) neighbor search with the
+// spatial hash (build plus queries) on a full cloud.
+func BenchmarkNeighbors(b *testing.B) {
+	ps := spawned(maxParticles)
+	counts := make([]int, len(ps))
+	b.Run("Brute", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bruteNeighbors(ps, counts)
+		}
+	})
+	b.Run("Hash", func(b *testing.B) {
+		g := &Game{particles: ps}
+		for i := 0; i < b.N; i++ {
+			hashNeighbors(g, counts)
+		}
+	})
+}
+
+// BenchmarkAntialias times Draw offscreen on a warmed-up cloud with
+// anti-aliasing on, off, and limited to radii of at least 8 pixels. The
+// times cover building the draw commands, not the GPU fill they cause.
+func BenchmarkAntialias(b *testing.B) {
+	gametest.Require(b)
+	g := warmedUp(DefaultOptions(), 300)
+	screen := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	for _, mode := range []struct {
+		name    string
+		aa      bool
+		minSize float64
+	}{
+		{"On", true, 0},
+		{"From8px", true, 8},
+		{"Off", false, 0},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			g.antialias, g.aaMinSize = mode.aa, mode.minSize
+			for i := 0; i < b.N; i++ {
+				g.Draw(screen)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package bubbles
+
+import "math"
+
+// spatialHash buckets points by quantized (x,y,z) so neighbors within a
+// radius can be found by scanning only the surrounding cells.
+type spatialHash struct {
+	cell  float64
+	cells map[[3]int][]hashEntry
+}
+
+type hashEntry struct {
+	id      int
+	x, y, z float64
+}
+
+func newSpatialHash(cell float64) *spatialHash {
+	return &spatialHash{cell: cell, cells: make(map[[3]int][]hashEntry)}
+}
+
+func (h *spatialHash) key(x, y, z float64) [3]int {
+	return [3]int{int(math.Floor(x / h.cell)), int(math.Floor(y / h.cell)), int(math.Floor(z / h.cell))}
+}
+
+// reset empties every bucket but keeps their storage for the next frame.
+func (h *spatialHash) reset() {
+	for k, v := range h.cells {
+		h.cells[k] = v[:0]
+	}
+}
+
+func (h *spatialHash) insert(id int, x, y, z float64) {
+	k := h.key(x, y, z)
+	h.cells[k] = append(h.cells[k], hashEntry{id, x, y, z})
+}
+
+// query appends to dst the ids of all points within r of (x,y,z).
+func (h *spatialHash) query(x, y, z, r float64, dst []int) []int {
+	lo, hi := h.key(x-r, y-r, z-r), h.key(x+r, y+r, z+r)
+	r2 := r * r
+	for i := lo[0]; i <= hi[0]; i++ {
+		for j := lo[1]; j <= hi[1]; j++ {
+			for k := lo[2]; k <= hi[2]; k++ {
+				for _, e := range h.cells[[3]int{i, j, k}] {
+					dx, dy, dz := e.x-x, e.y-y, e.z-z
+					if dx*dx+dy*dy+dz*dz <= r2 {
+						dst = append(dst, e.id)
+					}
+				}
+			}
+		}
+	}
+	return dst
+}
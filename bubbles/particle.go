@@ -0,0 +1,126 @@
+// Package bubbles is the bubbles demo: a cloud of 3D bubbles spawned
+// inside a sphere and drifting outward, seen through an orbiting
+// perspective camera, with neighbor queries on a spatial hash driving soft
+// separation and occlusion-like darkening, optional toroidal wrapping,
+// and a depth-sorted, optionally glowing draw. The demo only reads flags
+// into Options and runs a Game.
+package bubbles
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+const (
+	ScreenWidth        = 1024
+	ScreenHeight       = 768
+	maxParticles       = 1200
+	spawnPerTick       = 8
+	defaultFocalLength = 450.0
+	defaultCameraDist  = 600.0
+	worldRadius        = 220.0
+
+	maxFrameDelta = 0.1 // clamp on the camera's time step after a stall (seconds)
+)
+
+type Particle struct {
+	x, y, z       float64
+	vx, vy, vz    float64
+	life, maxLife int
+	baseSize      float64
+	color         color.RGBA
+
+	// crowding from close neighbors, 0 (alone) to 1, set each Update for
+	// the occlusion darkening
+	crowding float64
+}
+
+// NewParticle spawns a particle uniformly inside the worldRadius sphere,
+// drifting outward, drawing every random value from rng.
+func NewParticle(rng *rand.Rand) *Particle {
+	phi := rng.Float64() * 2 * math.Pi
+	costheta := rng.Float64()*2 - 1
+	u := rng.Float64()
+	r := worldRadius * math.Cbrt(u)
+
+	x := r * math.Cos(phi) * math.Sqrt(1-costheta*costheta)
+	y := r * math.Sin(phi) * math.Sqrt(1-costheta*costheta)
+	z := r * costheta
+
+	speed := rng.Float64()*1.5 + 0.5
+	vx := x / (worldRadius + 1) * speed * 0.5
+	vy := y / (worldRadius + 1) * speed * 0.5
+	vz := z / (worldRadius + 1) * speed * 0.5
+
+	maxLife := 100 + rng.Intn(120)
+	col := color.RGBA{
+		uint8(180 + rng.Intn(70)),
+		uint8(180 + rng.Intn(70)),
+		uint8(255),
+		255,
+	}
+
+	return &Particle{
+		x: x, y: y, z: z,
+		vx: vx, vy: vy, vz: vz,
+		life: maxLife, maxLife: maxLife,
+		baseSize: rng.Float64()*3 + 2,
+		color:    col,
+	}
+}
+
+// bubbleRadiusScale converts baseSize to the bubble's drawn radius in world
+// units; Draw multiplies it by the projection scale to get pixels.
+const bubbleRadiusScale = 3.0
+
+// radius is the bubble's drawn radius in world units.
+func (p *Particle) radius() float64 {
+	return p.baseSize * bubbleRadiusScale
+}
+
+func (p *Particle) Update() bool {
+	p.x += p.vx
+	p.y += p.vy
+	p.z += p.vz
+	p.vx *= 0.99
+	p.vy *= 0.99
+	p.vz *= 0.99
+	p.life--
+	return p.life > 0
+}
+
+// minDrawAlpha is the opacity (0-255) below which Draw skips a bubble.
+const minDrawAlpha = 10
+
+// alpha is the bubble's opacity at the given camera depth: it fades with
+// age and distance.
+func (p *Particle) alpha(depth float64) float64 {
+	lifeRatio := float64(p.life) / float64(p.maxLife)
+	depthFade := 1.0 - (depth-200)/1200
+	if depthFade < 0.2 {
+		depthFade = 0.2
+	}
+	return lifeRatio * depthFade
+}
+
+func (p *Particle) Project(yaw, pitch, cameraDist, focalLength float64) (sx, sy, scale, depth float64, visible bool) {
+	siny, cosy := math.Sin(yaw), math.Cos(yaw)
+	x1 := p.x*cosy + p.z*siny
+	z1 := -p.x*siny + p.z*cosy
+
+	sinp, cosp := math.Sin(pitch), math.Cos(pitch)
+	y1 := p.y*cosp - z1*sinp
+	z2 := p.y*sinp + z1*cosp + cameraDist // camera offset
+
+	if z2 <= 10 {
+		return 0, 0, 0, z2, false
+	}
+
+	f := focalLength / z2
+	sx = x1*f + ScreenWidth/2
+	sy = y1*f + ScreenHeight/2
+	scale = f
+	depth = z2
+	return sx, sy, scale, depth, true
+}
@@ -0,0 +1,271 @@
+// Package concert is the Concert demo: a pooled 3D fire-particle explosion
+// colored by depth, with shockwave rings and switchable blending and depth
+// sorting. The demo only reads flags into Options and runs a Game; the
+// deterministic snapshot its -golden check and the package's golden test
+// compare lives here too.
+package concert
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/arcesoftware/GO_Examples/fireburst"
+	"github.com/arcesoftware/GO_Examples/spritebatch"
+)
+
+const (
+	ScreenWidth  = 800
+	ScreenHeight = 600
+	MaxParticles = 8000
+	defaultTexW  = 32
+	defaultTexH  = 32
+)
+
+// Options configures a game built by NewGame.
+type Options struct {
+	Seed    int64   // seeds the particle RNG
+	Falloff float64 // particle texture falloff exponent (higher = harder edge)
+
+	BgTop, BgBottom color.RGBA // background gradient
+
+	// explosion shockwave rings: color, growth in pixels per frame and
+	// alpha lost per frame
+	RingColor color.RGBA
+	RingSpeed float64
+	RingFade  float64
+
+	AlphaBlend bool     // draw with SourceOver instead of additive blending
+	DepthSort  SortMode // whether particles are sorted by z first
+}
+
+// DefaultOptions returns Concert's starting settings.
+func DefaultOptions() Options {
+	return Options{
+		Seed:      1,
+		Falloff:   2,
+		BgTop:     color.RGBA{10, 10, 20, 255},
+		BgBottom:  color.RGBA{44, 18, 40, 255},
+		RingColor: color.RGBA{255, 220, 160, 255},
+		RingSpeed: 9.0,
+		RingFade:  0.05,
+	}
+}
+
+// FireTexture builds the particle texture with the given falloff exponent.
+func FireTexture(falloff float64) *image.RGBA {
+	return spritebatch.RadialAlpha(defaultTexW, defaultTexH, falloff)
+}
+
+// Game is one Concert scene and its window state.
+type Game struct {
+	sys  *fireburst.System
+	opts Options
+
+	fireImage *ebiten.Image
+
+	// vertical gradient drawn first each frame, built once
+	background *ebiten.Image
+
+	// this frame's new presses, read into a reused buffer
+	tapReader fireburst.TapReader
+	taps      []image.Point
+
+	// pool exhaustion feedback: dropped spawns and the POOL FULL indicator
+	pool fireburst.PoolMeter
+
+	// alphaBlend draws with SourceOver instead of additive blending (B);
+	// depthSort decides whether particles are sorted by z first (S)
+	alphaBlend bool
+	depthSort  SortMode
+
+	// expanding rings, one per explosion, removed once faded
+	shockwaves []shockwave
+}
+
+// SortMode is the depth-sort setting: auto sorts only when the blend mode
+// depends on draw order.
+type SortMode int
+
+const (
+	SortAuto SortMode = iota
+	SortOn
+	SortOff
+)
+
+var sortModeNames = [...]string{"auto", "on", "off"}
+
+func (m SortMode) String() string { return sortModeNames[m] }
+
+func ParseSortMode(s string) (SortMode, error) {
+	for i, name := range sortModeNames {
+		if s == name {
+			return SortMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sort mode %q (want auto, on or off)", s)
+}
+
+// NewGame builds a game from opts.
+func NewGame(opts Options) *Game {
+	g := &Game{
+		sys:        fireburst.NewSystem(MaxParticles, rand.New(rand.NewSource(opts.Seed)), fireColor),
+		opts:       opts,
+		fireImage:  ebiten.NewImageFromImage(FireTexture(opts.Falloff)),
+		background: fireburst.GradientBackground(ScreenWidth, ScreenHeight, opts.BgTop, opts.BgBottom),
+		alphaBlend: opts.AlphaBlend,
+		depthSort:  opts.DepthSort,
+	}
+	g.applyDrawMode()
+	return g
+}
+
+// sorting reports whether particles are depth sorted before drawing.
+func (g *Game) sorting() bool {
+	return g.depthSort == SortOn || g.depthSort == SortAuto && g.alphaBlend
+}
+
+// applyDrawMode hands the blend and sort settings to the particle system.
+// Additive blending sums colors, so order doesn't matter; alpha blending
+// needs far particles drawn first.
+func (g *Game) applyDrawMode() {
+	g.sys.Mode = ebiten.CompositeModeLighter
+	if g.alphaBlend {
+		g.sys.Mode = ebiten.CompositeModeSourceOver
+	}
+	g.sys.Sort = nil
+	if g.sorting() {
+		g.sys.Sort = fireburst.SortByDepthSlice
+	}
+}
+
+// fireColor colors particles by depth and fades them out over their life.
+func fireColor(rate, z float64) (r, g, b, a float32) {
+	r, g, b = depthColor(z)
+	return r, g, b, float32(1.0 - math.Pow(rate, 1.5))
+}
+
+// explodeAt spawns one explosion and shockwave at each tap position,
+// recording any spawns the full pool could not take.
+func (g *Game) explodeAt(taps []image.Point) {
+	for _, t := range taps {
+		g.shockwaves = append(g.shockwaves, shockwave{x: float64(t.X), y: float64(t.Y), alpha: 1})
+	}
+	g.pool.Record(g.sys.ExplodeAt(taps))
+}
+
+// Blue (far) → Red (near)
+func depthColor(z float64) (r, g, b float32) {
+	// Normalize z from -2 (far) to +2 (near)
+	t := float32((z + 2.0) / 4.0)
+	if t < 0 {
+		t = 0
+	}
+	if t > 1 {
+		t = 1
+	}
+
+	// Interpolate blue → purple → red
+	r = t
+	g = 0.0
+	b = 1.0 - t
+	return
+}
+
+func (g *Game) Update() error {
+	g.pool.Tick()
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		g.alphaBlend = !g.alphaBlend
+		g.applyDrawMode()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		g.depthSort = (g.depthSort + 1) % SortMode(len(sortModeNames))
+		g.applyDrawMode()
+	}
+	g.taps = g.tapReader.Append(g.taps[:0])
+	g.stepShockwaves()
+	g.explodeAt(g.taps)
+
+	g.sys.Step()
+	return nil
+}
+
+// runFrames advances the simulation n ticks without reading input.
+func runFrames(g *Game, n int) {
+	for i := 0; i < n; i++ {
+		g.sys.Step()
+	}
+}
+
+// renderToImage draws the current state into a new offscreen image.
+func renderToImage(g *Game) *ebiten.Image {
+	img := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	g.Draw(img)
+	return img
+}
+
+// Snapshot renders a fixed scene: one explosion at the center of the
+// screen, frames ticks later. From the same Options it is the same image,
+// up to how the GPU rasterizes. It must run inside the game loop.
+func Snapshot(g *Game, frames int) *image.RGBA {
+	g.sys.Explode(ScreenWidth/2, ScreenHeight/2)
+	runFrames(g, frames)
+
+	pix := make([]byte, 4*ScreenWidth*ScreenHeight)
+	renderToImage(g).ReadPixels(pix)
+	return &image.RGBA{Pix: pix, Stride: 4 * ScreenWidth, Rect: image.Rect(0, 0, ScreenWidth, ScreenHeight)}
+}
+
+// CountChangedPixels returns how many pixels differ by more than tol in any channel.
+func CountChangedPixels(got, want image.Image, tol int) int {
+	if got.Bounds() != want.Bounds() {
+		return got.Bounds().Dx() * got.Bounds().Dy()
+	}
+	changed := 0
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+			if channelDiff(r1, r2) > tol || channelDiff(g1, g2) > tol ||
+				channelDiff(b1, b2) > tol || channelDiff(a1, a2) > tol {
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+// channelDiff compares two 16-bit color channels at 8-bit precision.
+func channelDiff(a, b uint32) int {
+	d := int(a>>8) - int(b>>8)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	screen.DrawImage(g.background, nil)
+
+	n := g.sys.Draw(screen, g.fireImage)
+	g.drawShockwaves(screen)
+
+	blend := "additive"
+	if g.alphaBlend {
+		blend = "alpha"
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("Particles: %d/%d\nDropped spawns: %d\nBlend [B]: %s  Depth sort [S]: %v (%v)\n[LMB] Explosion (Depth Color: Blue→Red)",
+		n, MaxParticles, g.pool.Dropped, blend, g.depthSort, g.sorting()))
+	g.pool.Draw(screen)
+}
+
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return ScreenWidth, ScreenHeight
+}
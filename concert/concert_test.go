@@ -0,0 +1,137 @@
+package concert
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/arcesoftware/GO_Examples/gametest"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/golden.png instead of comparing against it")
+
+func TestMain(m *testing.M) {
+	gametest.Main(m)
+}
+
+// The golden scene: Snapshot of a default game goldenFrames ticks after
+// its explosion.
+//
+// A pixel counts as changed when any channel is off by more than
+// goldenTolerance, and the test allows goldenMaxChanged of them: GPUs
+// rasterize the antialiased ring and the text edges a little differently,
+// and the particle positions round differently where Go fuses
+// multiply-adds. A change to the simulation or the palette moves far more.
+// testdata/golden.png was rendered with Mesa's software rasterizer.
+const (
+	goldenFrames     = 30
+	goldenTolerance  = 2
+	goldenMaxChanged = ScreenWidth * ScreenHeight / 1000
+)
+
+// TestGolden renders the golden scene and compares it against
+// testdata/golden.png, or rewrites the file with -update.
+func TestGolden(t *testing.T) {
+	gametest.Require(t)
+	const path = "testdata/golden.png"
+	got := Snapshot(NewGame(DefaultOptions()), goldenFrames)
+	if *update {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, got); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", path, err)
+	}
+	if n := CountChangedPixels(got, want, goldenTolerance); n > goldenMaxChanged {
+		t.Errorf("%d pixels differ from %s by more than %d, want at most %d", n, path, goldenTolerance, goldenMaxChanged)
+	}
+}
+
+// TestShockwaves fires two overlapping explosions and checks both rings
+// grow by RingSpeed and fade by RingFade each frame, then disappear.
+func TestShockwaves(t *testing.T) {
+	opts := DefaultOptions()
+	g := NewGame(opts)
+	g.explodeAt([]image.Point{{300, 300}, {340, 300}})
+	if len(g.shockwaves) != 2 {
+		t.Fatalf("%d shockwaves after two explosions, want 2", len(g.shockwaves))
+	}
+	for frame := 1; len(g.shockwaves) > 0; frame++ {
+		g.stepShockwaves()
+		want := 1 - float64(frame)*opts.RingFade
+		if want <= 0 {
+			if len(g.shockwaves) != 0 {
+				t.Fatalf("frame %d: %d shockwaves left after fading out", frame, len(g.shockwaves))
+			}
+			break
+		}
+		if len(g.shockwaves) != 2 {
+			t.Fatalf("frame %d: %d shockwaves, want 2 until they fade", frame, len(g.shockwaves))
+		}
+		for _, w := range g.shockwaves {
+			if math.Abs(w.radius-float64(frame)*opts.RingSpeed) > 1e-9 || math.Abs(w.alpha-want) > 1e-9 {
+				t.Fatalf("frame %d: ring radius %v alpha %v, want %v and %v", frame, w.radius, w.alpha, float64(frame)*opts.RingSpeed, want)
+			}
+		}
+	}
+}
+
+// TestDepthColor pins down the depthColor contract: exact colors at the far
+// (z=-2), middle and near (z=2) planes, red rising and blue falling across
+// the range, and clamping (channels stay in [0,1]) for z outside it.
+func TestDepthColor(t *testing.T) {
+	cases := []struct {
+		z       float64
+		r, g, b float32
+	}{
+		{-2, 0, 0, 1},
+		{0, 0.5, 0, 0.5},
+		{2, 1, 0, 0},
+		{-5, 0, 0, 1}, // clamps to far
+		{5, 1, 0, 0},  // clamps to near
+		{math.Inf(-1), 0, 0, 1},
+		{math.Inf(1), 1, 0, 0},
+	}
+	for _, c := range cases {
+		r, g, b := depthColor(c.z)
+		if r != c.r || g != c.g || b != c.b {
+			t.Errorf("depthColor(%v) = (%v, %v, %v), want (%v, %v, %v)", c.z, r, g, b, c.r, c.g, c.b)
+		}
+	}
+
+	prevR, _, prevB := depthColor(-2)
+	for z := -1.95; z <= 2; z += 0.05 {
+		r, _, b := depthColor(z)
+		if r <= prevR || b >= prevB {
+			t.Fatalf("depthColor(%.2f) = r %v, b %v: red must rise and blue fall (previous r %v, b %v)", z, r, b, prevR, prevB)
+		}
+		prevR, prevB = r, b
+	}
+
+	for z := -10.0; z <= 10; z += 0.25 {
+		r, g, b := depthColor(z)
+		for _, v := range []float32{r, g, b} {
+			if v < 0 || v > 1 {
+				t.Fatalf("depthColor(%v) = (%v, %v, %v): channel outside [0,1]", z, r, g, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package concert
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// ringWidth is the stroke width of a shockwave ring.
+const ringWidth = 2
+
+// shockwave is a ring expanding from an explosion while it fades out.
+type shockwave struct {
+	x, y, radius, alpha float64
+}
+
+// stepShockwaves grows and fades every ring, dropping the faded ones.
+func (g *Game) stepShockwaves() {
+	live := g.shockwaves[:0]
+	for _, w := range g.shockwaves {
+		w.radius += g.opts.RingSpeed
+		w.alpha -= g.opts.RingFade
+		if w.alpha > 0 {
+			live = append(live, w)
+		}
+	}
+	g.shockwaves = live
+}
+
+// drawShockwaves strokes every ring; overlapping rings simply stack.
+func (g *Game) drawShockwaves(screen *ebiten.Image) {
+	c0 := g.opts.RingColor
+	for _, w := range g.shockwaves {
+		c := color.NRGBA{c0.R, c0.G, c0.B, uint8(w.alpha * 255)}
+		vector.StrokeCircle(screen, float32(w.x), float32(w.y), float32(w.radius), ringWidth, c, true)
+	}
+}
@@ -0,0 +1,53 @@
+package fireburst
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// GradientBackground returns a w×h image shading vertically from top to
+// bottom. Build it once and draw it first each frame instead of Fill.
+func GradientBackground(w, h int, top, bottom color.RGBA) *ebiten.Image {
+	pix := make([]byte, 4*w*h)
+	for y := 0; y < h; y++ {
+		t := 0.0
+		if h > 1 {
+			t = float64(y) / float64(h-1)
+		}
+		lerp := func(a, b uint8) byte {
+			return byte(float64(a) + (float64(b)-float64(a))*t + 0.5)
+		}
+		c := [4]byte{lerp(top.R, bottom.R), lerp(top.G, bottom.G), lerp(top.B, bottom.B), lerp(top.A, bottom.A)}
+		row := pix[4*w*y : 4*w*(y+1)]
+		for x := 0; x < w; x++ {
+			copy(row[4*x:], c[:])
+		}
+	}
+	img := ebiten.NewImage(w, h)
+	img.WritePixels(pix)
+	return img
+}
+
+// HexColor is an opaque color flag.Value written as rrggbb (a leading # is
+// allowed).
+type HexColor struct {
+	color.RGBA
+}
+
+func (c *HexColor) String() string {
+	return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (c *HexColor) Set(s string) error {
+	s = strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(s, 16, 32)
+	if len(s) != 6 || err != nil {
+		return fmt.Errorf("color %q: want rrggbb", s)
+	}
+	c.RGBA = color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+	return nil
+}
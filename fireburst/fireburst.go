@@ -0,0 +1,274 @@
+// Package fireburst is the pooled 3D fire-particle explosion shared by the
+// Concert and animation3 demos. The particle physics, spawning and batched
+// drawing live here, along with the gradient backdrop both draw behind the
+// fire and the tap input that sets off bursts; each demo only supplies how a
+// particle is colored.
+package fireburst
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// BurstSize is how many particles one explosion spawns.
+const BurstSize = 600
+
+// Particle is one fire particle. Z is depth: -1 (far) to +1 (near) at spawn.
+type Particle struct {
+	X, Y, Z           float64
+	VX, VY, VZ        float64
+	Lifetime, MaxLife int
+	BaseScale         float64
+	Angle             float64
+	AngularVelocity   float64
+	SpawnSpeed        float64 // |v| at spawn
+	Active            bool
+}
+
+// Rate is the fraction of the particle's life used up, 0 at spawn to 1.
+func (p *Particle) Rate() float64 {
+	return float64(p.Lifetime) / float64(p.MaxLife)
+}
+
+// Update advances the particle by one tick and deactivates it when its life
+// runs out.
+func (p *Particle) Update() {
+	if !p.Active {
+		return
+	}
+	p.Lifetime++
+	if p.Lifetime >= p.MaxLife {
+		p.Active = false
+		return
+	}
+
+	// semi-implicit Euler: update velocity first, then move with the new one
+	p.VY += 0.02 // gentle upward drift
+	p.VZ *= 0.98 // slow damping in depth
+
+	p.X += p.VX
+	p.Y += p.VY
+	p.Z += p.VZ
+
+	p.Angle += p.AngularVelocity
+}
+
+// ColorFunc colors a particle from its life fraction (0..1) and depth. The
+// returned color is straight (not premultiplied); a is its opacity.
+type ColorFunc func(rate, z float64) (r, g, b, a float32)
+
+// System is a fixed-size particle pool with reusable draw buffers.
+type System struct {
+	Particles []*Particle
+	Rand      *rand.Rand
+	Color     ColorFunc
+
+	// Tint, when set, is blended over Color with weight w for each particle;
+	// it sees the whole particle (e.g. its spawn speed).
+	Tint func(p *Particle) (r, g, b, w float32)
+
+	// Sort, when set, orders the active particles near-to-far before drawing
+	// so nearer ones are drawn last (painter's algorithm).
+	Sort func([]*Particle)
+
+	// Mode is how particles composite onto the screen. NewSystem sets
+	// additive (Lighter), which is order-independent; SourceOver needs Sort
+	// to look right.
+	Mode ebiten.CompositeMode
+
+	vertices []ebiten.Vertex
+	indices  []uint16
+	active   []*Particle
+}
+
+// NewSystem returns a pool of capacity inactive particles. capacity*4 must fit
+// in the uint16 vertex indices.
+func NewSystem(capacity int, rng *rand.Rand, color ColorFunc) *System {
+	s := &System{
+		Particles: make([]*Particle, capacity),
+		Rand:      rng,
+		Color:     color,
+		Mode:      ebiten.CompositeModeLighter,
+		vertices:  make([]ebiten.Vertex, 0, capacity*4),
+		indices:   make([]uint16, 0, capacity*6),
+		active:    make([]*Particle, 0, capacity),
+	}
+	for i := range s.Particles {
+		s.Particles[i] = &Particle{}
+	}
+	return s
+}
+
+// Allocate returns the first inactive particle, or nil if the pool is full.
+func (s *System) Allocate() *Particle {
+	for _, p := range s.Particles {
+		if !p.Active {
+			return p
+		}
+	}
+	return nil
+}
+
+// spawn initializes p as a fresh explosion particle near (x, y), flying
+// within spread radians of dir. A full circle (spread >= 2π) is the plain
+// radial burst, flattened horizontally; narrower cones keep their shape.
+func (s *System) spawn(p *Particle, x, y, dir, spread float64) {
+	rng := s.Rand
+	*p = Particle{
+		Active:          true,
+		X:               x + rng.Float64()*4 - 2,
+		Y:               y + rng.Float64()*4 - 2,
+		Z:               rng.Float64()*2 - 1,
+		Angle:           rng.Float64() * 2 * math.Pi,
+		AngularVelocity: (rng.Float64()*2 - 1) * 0.1,
+		MaxLife:         rng.Intn(40) + 40,
+		BaseScale:       rng.Float64()*0.1 + 0.2,
+	}
+	// Radial outward velocity, flattened horizontally
+	ang := rng.Float64() * 2 * math.Pi
+	speed := rng.Float64()*4.0 + 2.0
+	p.VX = math.Cos(ang) * speed * 0.3
+	p.VY = math.Sin(ang) * speed * 0.7
+	if spread < 2*math.Pi {
+		// the same draw, mapped into the cone
+		ang = dir + (ang/(2*math.Pi)-0.5)*spread
+		p.VX = math.Cos(ang) * speed * 0.7
+		p.VY = math.Sin(ang) * speed * 0.7
+	}
+	p.VZ = (rng.Float64()*2 - 1) * 0.5
+	p.SpawnSpeed = math.Sqrt(p.VX*p.VX + p.VY*p.VY + p.VZ*p.VZ)
+}
+
+// Explode spawns up to BurstSize particles at (x, y) and returns how many it
+// placed; fewer means the pool ran out.
+func (s *System) Explode(x, y float64) int {
+	return s.ExplodeDirected(x, y, 0, 2*math.Pi, BurstSize)
+}
+
+// ExplodeAt spawns one Explode burst at each point and returns how many
+// spawns the full pool could not take.
+func (s *System) ExplodeAt(pts []image.Point) (dropped int) {
+	for _, pt := range pts {
+		dropped += BurstSize - s.Explode(float64(pt.X), float64(pt.Y))
+	}
+	return dropped
+}
+
+// ExplodeDirected spawns up to count particles at (x, y) flying within a
+// cone spread radians wide centered on dir (radians, screen coordinates),
+// and returns how many it placed.
+func (s *System) ExplodeDirected(x, y, dir, spread float64, count int) int {
+	for i := 0; i < count; i++ {
+		p := s.Allocate()
+		if p == nil {
+			return i
+		}
+		s.spawn(p, x, y, dir, spread)
+	}
+	return count
+}
+
+// Step advances every active particle by one tick.
+func (s *System) Step() {
+	for _, p := range s.Particles {
+		if p.Active {
+			p.Update()
+		}
+	}
+}
+
+// Draw batches every active particle into one DrawTriangles call using tex
+// as the sprite and s.Mode to composite, and returns how many particles it
+// drew.
+func (s *System) Draw(screen, tex *ebiten.Image) int {
+	s.vertices = s.vertices[:0]
+	s.indices = s.indices[:0]
+	s.active = s.active[:0]
+	for _, p := range s.Particles {
+		if p.Active {
+			s.active = append(s.active, p)
+		}
+	}
+	if s.Sort != nil {
+		s.Sort(s.active)
+	}
+
+	texW := float64(tex.Bounds().Dx())
+	texH := float64(tex.Bounds().Dy())
+	corners := [4]struct{ dx, dy float64 }{{0, 0}, {0, texH}, {texW, 0}, {texW, texH}}
+
+	for _, p := range s.active {
+		rate := p.Rate()
+		r, g, b, a := s.Color(rate, p.Z)
+		if s.Tint != nil {
+			tr, tg, tb, w := s.Tint(p)
+			r = r*(1-w) + tr*w
+			g = g*(1-w) + tg*w
+			b = b*(1-w) + tb*w
+		}
+
+		// Perspective: far (negative z) particles are smaller; all grow over life
+		depthScale := 1.0 / (1.0 + p.Z*0.5)
+		scale := p.BaseScale * (1.0 + 0.5*rate) * depthScale
+
+		var geo ebiten.GeoM
+		geo.Translate(-texW/2, -texH/2)
+		geo.Rotate(p.Angle)
+		geo.Scale(scale, scale)
+		geo.Translate(p.X, p.Y)
+
+		vIndex := uint16(len(s.vertices))
+		for _, c := range corners {
+			vx, vy := geo.Apply(c.dx, c.dy)
+			// Premultiply color by alpha for correct blending
+			s.vertices = append(s.vertices, ebiten.Vertex{
+				DstX: float32(vx), DstY: float32(vy),
+				SrcX: float32(c.dx), SrcY: float32(c.dy),
+				ColorR: r * a,
+				ColorG: g * a,
+				ColorB: b * a,
+				ColorA: a,
+			})
+		}
+		s.indices = append(s.indices, vIndex, vIndex+1, vIndex+2, vIndex+1, vIndex+3, vIndex+2)
+	}
+
+	if len(s.indices) > 0 {
+		op := &ebiten.DrawTrianglesOptions{CompositeMode: s.Mode}
+		screen.DrawTriangles(s.vertices, s.indices, tex, op)
+	}
+	return len(s.active)
+}
+
+// SortByDepthExchange is a simple O(n²) exchange sort by z.
+func SortByDepthExchange(ps []*Particle) {
+	for i := range ps {
+		for j := i + 1; j < len(ps); j++ {
+			if ps[i].Z > ps[j].Z {
+				ps[i], ps[j] = ps[j], ps[i]
+			}
+		}
+	}
+}
+
+// SortByDepthSlice orders particles by z using the standard library.
+func SortByDepthSlice(ps []*Particle) {
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Z < ps[j].Z })
+}
+
+// SortByDepthInsertion is O(n) on nearly sorted input, which is what
+// frame-to-frame depth data looks like when the previous order is kept.
+func SortByDepthInsertion(ps []*Particle) {
+	for i := 1; i < len(ps); i++ {
+		p := ps[i]
+		j := i - 1
+		for ; j >= 0 && ps[j].Z > p.Z; j-- {
+			ps[j+1] = ps[j]
+		}
+		ps[j+1] = p
+	}
+}
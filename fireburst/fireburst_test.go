@@ -0,0 +1,194 @@
+package fireburst
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestExplodeAtMultiTap feeds two simultaneous taps through ExplodeAt and
+// checks that each produced its own full burst centered on its position.
+func TestExplodeAtMultiTap(t *testing.T) {
+	s := NewSystem(4*BurstSize, rand.New(rand.NewSource(1)), nil)
+	taps := []image.Point{{120, 140}, {520, 360}}
+	if n := s.ExplodeAt(taps); n != 0 {
+		t.Fatalf("ExplodeAt dropped %d spawns with room for both bursts", n)
+	}
+
+	counts := make([]int, len(taps))
+	for _, p := range s.Particles {
+		if !p.Active {
+			continue
+		}
+		hit := false
+		for i, tap := range taps {
+			if math.Abs(p.X-float64(tap.X)) <= 2 && math.Abs(p.Y-float64(tap.Y)) <= 2 {
+				counts[i]++
+				hit = true
+			}
+		}
+		if !hit {
+			t.Fatalf("particle spawned at (%.1f, %.1f), away from every tap", p.X, p.Y)
+		}
+	}
+	for i, tap := range taps {
+		if counts[i] != BurstSize {
+			t.Errorf("tap %d at %v spawned %d particles, want %d", i, tap, counts[i], BurstSize)
+		}
+	}
+}
+
+// TestExplodeAtDropped checks that ExplodeAt reports the spawns a full pool
+// could not take.
+func TestExplodeAtDropped(t *testing.T) {
+	s := NewSystem(BurstSize+BurstSize/2, rand.New(rand.NewSource(1)), nil)
+	if n := s.ExplodeAt([]image.Point{{0, 0}, {10, 10}}); n != BurstSize/2 {
+		t.Errorf("ExplodeAt dropped %d spawns, want %d", n, BurstSize/2)
+	}
+}
+
+// TestExplodeFillsPool checks that bursts land where requested and fill the
+// pool without overrunning it.
+func TestExplodeFillsPool(t *testing.T) {
+	const capacity = BurstSize + BurstSize/2
+	s := NewSystem(capacity, rand.New(rand.NewSource(1)), nil)
+
+	if n := s.Explode(100, 200); n != BurstSize {
+		t.Fatalf("first burst spawned %d particles, want %d", n, BurstSize)
+	}
+	for _, p := range s.Particles[:BurstSize] {
+		if !p.Active || math.Abs(p.X-100) > 2 || math.Abs(p.Y-200) > 2 {
+			t.Fatalf("burst particle at (%.1f, %.1f) active=%v, want active within 2px of (100, 200)", p.X, p.Y, p.Active)
+		}
+		if p.Z < -1 || p.Z >= 1 || p.MaxLife < 40 || p.MaxLife >= 80 {
+			t.Fatalf("burst particle z=%.2f maxLife=%d out of range", p.Z, p.MaxLife)
+		}
+	}
+	if n := s.Explode(300, 50); n != capacity-BurstSize {
+		t.Fatalf("second burst spawned %d particles into a pool with %d free, want them all", n, capacity-BurstSize)
+	}
+	if s.Allocate() != nil {
+		t.Fatal("Allocate returned a particle from a full pool")
+	}
+}
+
+// TestStep checks that particles move and expire on schedule and that
+// expired slots are reused.
+func TestStep(t *testing.T) {
+	s := NewSystem(BurstSize, rand.New(rand.NewSource(1)), nil)
+	s.Explode(100, 200)
+
+	// One tick: velocity picks up the upward drift before moving the particle.
+	p := s.Particles[0]
+	x, y, vy := p.X, p.Y, p.VY
+	s.Step()
+	if p.Lifetime != 1 || p.X != x+p.VX || p.VY != vy+0.02 || p.Y != y+p.VY {
+		t.Fatalf("after one step: lifetime %d, moved to (%v, %v), want lifetime 1 at (%v, %v)", p.Lifetime, p.X, p.Y, x+p.VX, y+vy+0.02)
+	}
+
+	// Every particle lives fewer than 80 ticks.
+	for i := 0; i < 80; i++ {
+		s.Step()
+	}
+	for _, p := range s.Particles {
+		if p.Active {
+			t.Fatalf("particle still active after %d ticks (maxLife %d)", p.Lifetime, p.MaxLife)
+		}
+	}
+	if n := s.Explode(0, 0); n != BurstSize {
+		t.Errorf("burst into the drained pool spawned %d particles, want %d", n, BurstSize)
+	}
+}
+
+// TestSpawnBounds checks that over many spawns the jitter stays within ±2px
+// of the center and each radial velocity lies on the spawn ellipse:
+// (VX/0.3)² + (VY/0.7)² is speed², with speed in [2, 6).
+func TestSpawnBounds(t *testing.T) {
+	s := NewSystem(BurstSize, rand.New(rand.NewSource(2)), nil)
+	for burst := 0; burst < 20; burst++ {
+		for _, p := range s.Particles {
+			p.Active = false
+		}
+		s.Explode(400, 300)
+		for _, p := range s.Particles {
+			if math.Abs(p.X-400) > 2 || math.Abs(p.Y-300) > 2 {
+				t.Fatalf("spawn jitter put a particle at (%.3f, %.3f), more than 2px from (400, 300)", p.X, p.Y)
+			}
+			speed := math.Hypot(p.VX/0.3, p.VY/0.7)
+			if speed < 2-1e-9 || speed >= 6+1e-9 {
+				t.Fatalf("spawn velocity (%.3f, %.3f) has speed %.3f, want [2, 6)", p.VX, p.VY, speed)
+			}
+		}
+	}
+}
+
+// TestExplodeDirected checks that a directed blast stays inside its cone.
+func TestExplodeDirected(t *testing.T) {
+	s := NewSystem(100, rand.New(rand.NewSource(1)), nil)
+	const dir, spread = math.Pi / 2, math.Pi / 6
+	if n := s.ExplodeDirected(0, 0, dir, spread, 100); n != 100 {
+		t.Fatalf("directed blast spawned %d particles, want 100", n)
+	}
+	for _, p := range s.Particles {
+		if a := math.Atan2(p.VY, p.VX); math.Abs(a-dir) > spread/2+1e-9 {
+			t.Fatalf("directed particle flies at %.3f rad, outside %.3f ± %.3f", a, dir, spread/2)
+		}
+		if v := math.Hypot(p.VX, p.VY); v < 2*0.7-1e-9 || v >= 6*0.7+1e-9 {
+			t.Fatalf("directed particle speed %.3f, want [1.4, 4.2)", v)
+		}
+	}
+}
+
+// TestPoolMeter checks that drops are counted and raise the indicator for
+// exactly PoolFullFrames ticks, and that recording no drops leaves it down.
+func TestPoolMeter(t *testing.T) {
+	var m PoolMeter
+	m.Record(0)
+	if m.Dropped != 0 || m.show != 0 {
+		t.Fatalf("Record(0) left Dropped %d, show %d; want both 0", m.Dropped, m.show)
+	}
+	m.Record(5)
+	m.Record(3)
+	if m.Dropped != 8 {
+		t.Errorf("Dropped = %d after recording 5 and 3, want 8", m.Dropped)
+	}
+	for i := 0; i < PoolFullFrames-1; i++ {
+		m.Tick()
+	}
+	if m.show == 0 {
+		t.Fatalf("indicator down after %d ticks, want it up for %d", PoolFullFrames-1, PoolFullFrames)
+	}
+	m.Tick()
+	if m.show != 0 {
+		t.Errorf("indicator still up after %d ticks", PoolFullFrames)
+	}
+}
+
+// benchmarkSortByDepth times sort over a fixed set of particles whose depths
+// are perturbed slightly before every pass. The draw order carries over
+// between passes, as it does between frames, so each one starts nearly
+// sorted. Every strategy sees the same seeded data.
+func benchmarkSortByDepth(b *testing.B, sort func([]*Particle)) {
+	const n = 2000
+	rng := rand.New(rand.NewSource(1))
+	pool := make([]*Particle, n)
+	for i := range pool {
+		pool[i] = &Particle{Z: rng.Float64()*2 - 1, Active: true}
+	}
+	order := append([]*Particle(nil), pool...)
+	sort(order)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for _, p := range pool {
+			p.Z += (rng.Float64()*2 - 1) * 0.01
+		}
+		b.StartTimer()
+		sort(order)
+	}
+}
+
+func BenchmarkSortByDepthExchange(b *testing.B)  { benchmarkSortByDepth(b, SortByDepthExchange) }
+func BenchmarkSortByDepthSortSlice(b *testing.B) { benchmarkSortByDepth(b, SortByDepthSlice) }
+func BenchmarkSortByDepthInsertion(b *testing.B) { benchmarkSortByDepth(b, SortByDepthInsertion) }
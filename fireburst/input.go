@@ -0,0 +1,31 @@
+package fireburst
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// TapReader finds the positions of each frame's new presses: every newly
+// pressed touch, so several fingers burst at once, plus a left click when
+// Mouse is set. The zero value reads touches only.
+type TapReader struct {
+	Mouse bool
+
+	ids []ebiten.TouchID // reused touch ID buffer
+}
+
+// Append appends this frame's new press positions to pts.
+func (r *TapReader) Append(pts []image.Point) []image.Point {
+	if r.Mouse && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		mx, my := ebiten.CursorPosition()
+		pts = append(pts, image.Pt(mx, my))
+	}
+	r.ids = inpututil.AppendJustPressedTouchIDs(r.ids[:0])
+	for _, id := range r.ids {
+		x, y := ebiten.TouchPosition(id)
+		pts = append(pts, image.Pt(x, y))
+	}
+	return pts
+}
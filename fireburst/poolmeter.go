@@ -0,0 +1,41 @@
+package fireburst
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// PoolFullFrames is how long the POOL FULL indicator stays up after a drop.
+const PoolFullFrames = 60
+
+// PoolMeter is pool exhaustion feedback: it counts spawns lost to a full
+// pool and shows a POOL FULL indicator for PoolFullFrames after each loss.
+type PoolMeter struct {
+	Dropped int // spawns lost so far
+
+	show int // frames left to show the indicator
+}
+
+// Record adds n dropped spawns; any at all raise the indicator.
+func (m *PoolMeter) Record(n int) {
+	if n > 0 {
+		m.Dropped += n
+		m.show = PoolFullFrames
+	}
+}
+
+// Tick counts the indicator down by one frame.
+func (m *PoolMeter) Tick() {
+	if m.show > 0 {
+		m.show--
+	}
+}
+
+// Draw prints POOL FULL centered on screen while the indicator is up.
+func (m *PoolMeter) Draw(screen *ebiten.Image) {
+	if m.show > 0 {
+		const msg = "POOL FULL"
+		b := screen.Bounds()
+		ebitenutil.DebugPrintAt(screen, msg, (b.Dx()-len(msg)*6)/2, b.Dy()/2-8)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -9,19 +10,21 @@ import (
 	_ "image/png"
 	"log"
 	"math"
-	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/arcesoftware/GO_Examples/fireworks"
+	"github.com/arcesoftware/GO_Examples/spritebatch"
 )
 
 const (
 	screenWidth   = 640
 	screenHeight  = 480
-	maxParticles  = 10000 // safe for uint16 indices (max vertices = 4*maxParticles)
 	defaultTexW   = 32
 	defaultTexH   = 32
 )
@@ -32,10 +35,9 @@ var (
 	smokeImageH   float64
 )
 
-func init() {
-	// seed RNG
-	rand.Seed(time.Now().UnixNano())
-
+// loadTextures loads the smoke image from disk, falling back to a procedural
+// radial texture with the given falloff exponent.
+func loadTextures(falloff float64) {
 	// Try to load an external image first
 	path := "_resources/images/smoke.png"
 	if _, err := os.Stat(path); err == nil {
@@ -51,21 +53,7 @@ func init() {
 	}
 	// If loading failed, create a small procedural smoke texture (radial alpha)
 	if smokeImage == nil {
-		img := image.NewRGBA(image.Rect(0, 0, defaultTexW, defaultTexH))
-		cx, cy := defaultTexW/2.0, defaultTexH/2.0
-		maxR := math.Hypot(cx, cy)
-		for y := 0; y < defaultTexH; y++ {
-			for x := 0; x < defaultTexW; x++ {
-				d := math.Hypot(float64(x)-cx, float64(y)-cy)
-				t := 1.0 - d/maxR
-				if t < 0 {
-					t = 0
-				}
-				a := uint8((t * t) * 255) // soft falloff
-				// white-ish smoke texture (alpha varies)
-				img.SetRGBA(x, y, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: a})
-			}
-		}
+		img := spritebatch.RadialAlpha(defaultTexW, defaultTexH, falloff)
 		smokeImage = ebiten.NewImageFromImage(img)
 		// Optional: write fallback to disk for debugging
 		var buf bytes.Buffer
@@ -77,192 +65,168 @@ func init() {
 	smokeImageH = float64(smokeImage.Bounds().Dy())
 }
 
-// ParticleType defines the behavior and blending mode.
-type ParticleType int
-
-const (
-	TypeSmoke ParticleType = iota // Alpha Blending, long life, slow
-	TypeFire                      // Additive Blending, short life, high velocity
+// Fire texture atlas (-atlas): atlasFrames equal frames side by side in
+// fireAtlas. Fire particles draw their early frame until atlasCrossover of
+// their life, then their late one. Without -atlas the atlas is smokeImage
+// as a single frame.
+var (
+	fireAtlas                *ebiten.Image
+	atlasFrames              = 1
+	atlasFrameW, atlasFrameH float64
+	atlasCrossover           = 0.35
 )
 
-// Particle struct for both smoke and fire.
-type Particle struct {
-	x, y             float64
-	vx, vy           float64
-	lifetime         int
-	maxLife          int
-	baseScale        float64
-	angle            float64
-	angularVelocity  float64
-	col              color.RGBA
-	pType            ParticleType
-	active           bool
+// builtinAtlas is a two-frame atlas: a bright hard core, then a dim, soft
+// ember, so fire visibly cools as it ages.
+func builtinAtlas(falloff float64) *image.RGBA {
+	core := spritebatch.RadialAlpha(defaultTexW, defaultTexH, falloff*2)
+	ember := spritebatch.RadialAlpha(defaultTexW, defaultTexH, falloff*0.6)
+	img := image.NewRGBA(image.Rect(0, 0, 2*defaultTexW, defaultTexH))
+	for y := 0; y < defaultTexH; y++ {
+		for x := 0; x < defaultTexW; x++ {
+			img.SetRGBA(x, y, core.RGBAAt(x, y))
+			c := ember.RGBAAt(x, y)
+			dim := func(v uint8) uint8 { return uint8(float64(v) * 0.45) }
+			img.SetRGBA(defaultTexW+x, y, color.RGBA{dim(c.R), dim(c.G), dim(c.B), dim(c.A)})
+		}
+	}
+	return img
 }
 
-func (p *Particle) update() {
-	if !p.active {
-		return
-	}
-	p.lifetime++
-	if p.lifetime >= p.maxLife {
-		p.active = false
-		return
+// loadAtlas sets up fireAtlas from spec: "" keeps the single smoke frame,
+// "builtin" uses builtinAtlas, anything else is a PNG of frames frames in a
+// row (0 = square frames). Call it after loadTextures.
+func loadAtlas(spec string, frames int, falloff float64) error {
+	fireAtlas, atlasFrames = smokeImage, 1
+	switch spec {
+	case "":
+	case "builtin":
+		fireAtlas, atlasFrames = ebiten.NewImageFromImage(builtinAtlas(falloff)), 2
+	default:
+		f, err := os.Open(spec)
+		if err != nil {
+			return err
+		}
+		img, _, err := image.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", spec, err)
+		}
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		if frames == 0 {
+			frames = w / max(h, 1)
+		}
+		if frames < 1 || w%frames != 0 {
+			return fmt.Errorf("%s: %dpx wide doesn't split into %d frames", spec, w, frames)
+		}
+		fireAtlas, atlasFrames = ebiten.NewImageFromImage(img), frames
 	}
-	p.x += p.vx
-	p.y += p.vy
-	p.angle += p.angularVelocity
-	// small upward force/drag typical of smoke/fire
-	p.vy += 0.05
+	atlasFrameW = float64(fireAtlas.Bounds().Dx() / atlasFrames)
+	atlasFrameH = float64(fireAtlas.Bounds().Dy())
+	return nil
 }
 
-// Emitter spawns particles at a given rate.
-type Emitter struct {
-	x, y   float64
-	rate   int // spawn every `rate` ticks (1 = every tick)
-	pType  ParticleType
-	counter int
+// blendModes are the composite modes B (fire) and N (smoke) cycle through.
+var blendModes = []struct {
+	name string
+	mode ebiten.CompositeMode
+}{
+	{"alpha", ebiten.CompositeModeSourceOver},
+	{"additive", ebiten.CompositeModeLighter},
+	{"multiply", ebiten.CompositeModeMultiply},
 }
 
-func (e *Emitter) spawn(g *Game) {
-	e.counter++
-	if e.rate <= 0 {
-		e.rate = 1
-	}
-	if e.counter%e.rate != 0 {
-		return
-	}
-	// burst 2 particles
-	for i := 0; i < 2; i++ {
-		if p := g.allocateParticle(); p != nil {
-			*p = *newParticle(e.x, e.y, e.pType)
-		}
-	}
-}
+// Default blend modes: fire glows additively, smoke is alpha blended.
+const (
+	defaultFireBlend  = 1
+	defaultSmokeBlend = 0
+)
 
-func newParticle(emitterX, emitterY float64, pType ParticleType) *Particle {
-	p := &Particle{
-		active: true,
-		pType:  pType,
-		x:      emitterX + rand.Float64()*4 - 2,
-		y:      emitterY + rand.Float64()*4 - 2,
-		angle:  rand.Float64() * 2 * math.Pi,
-		angularVelocity: (rand.Float64()*2 - 1) * 0.05,
-	}
-	switch pType {
-	case TypeSmoke:
-		p.maxLife = rand.Intn(60) + 240 // ~4-5s
-		angle := rand.Float64()*math.Pi/3.0 + math.Pi/2.0
-		speed := rand.Float64()*0.4 + 0.1
-		p.vx = math.Cos(angle) * speed
-		p.vy = math.Sin(angle) * speed - 1.0
-
-		r := uint8(0xc0 + rand.Intn(0x3f))
-		g := uint8(0xc0 + rand.Intn(0x3f))
-		b := uint8(0xc0 + rand.Intn(0x3f))
-		p.col = color.RGBA{R: r, G: g, B: b, A: 0xff}
-		p.baseScale = rand.Float64()*0.1 + 0.3
-
-	case TypeFire:
-		p.maxLife = rand.Intn(30) + 45 // short life
-		ang := rand.Float64()*math.Pi/4.0
-		if rand.Intn(2) == 0 {
-			ang = -ang
+// parseBlend maps a -fireblend/-smokeblend value to its index in blendModes.
+func parseBlend(name string) (int, error) {
+	var names []string
+	for i, b := range blendModes {
+		if b.name == name {
+			return i, nil
 		}
-		ang += math.Pi / 2.0
-		speed := rand.Float64()*1.5 + 1.0
-		p.vx = math.Cos(ang) * speed * 0.5
-		p.vy = math.Sin(ang) * speed * 2.0
-
-		p.col = color.RGBA{R: 0xff, G: 0x90, B: 0x00, A: 0xff}
-		p.baseScale = rand.Float64()*0.05 + 0.15
+		names = append(names, b.name)
 	}
-	return p
+	return 0, fmt.Errorf("unknown blend mode %q (want %s)", name, strings.Join(names, ", "))
 }
 
-// Game holds particles, emitters and batching buffers.
+const (
+	windStep = 0.002 // wind change per tick while Left/Right is held
+	maxWind  = 0.1
+)
+
+// Game draws a particle system with batching buffers.
 type Game struct {
-	particles []*Particle
-	emitters  []*Emitter
+	sys *fireworks.System
 
 	smokeVertices []ebiten.Vertex
 	fireVertices  []ebiten.Vertex
 	smokeIndices  []uint16
 	fireIndices   []uint16
-	// pool cursor not strictly necessary, allocateParticle scans
+
+	// blendModes indices the fire (B, -fireblend) and smoke (N,
+	// -smokeblend) batches are drawn with
+	fireBlend, smokeBlend int
 }
 
-func NewGame() *Game {
-	g := &Game{
-		particles:     make([]*Particle, 0, maxParticles),
-		smokeVertices: make([]ebiten.Vertex, 0, maxParticles*4),
-		fireVertices:  make([]ebiten.Vertex, 0, maxParticles*4),
-		smokeIndices:  make([]uint16, 0, maxParticles*6),
-		fireIndices:   make([]uint16, 0, maxParticles*6),
-		emitters:      make([]*Emitter, 0, 4),
+func NewGame(sys *fireworks.System) *Game {
+	return &Game{
+		sys:           sys,
+		smokeVertices: make([]ebiten.Vertex, 0, fireworks.MaxParticles*4),
+		fireVertices:  make([]ebiten.Vertex, 0, fireworks.MaxParticles*4),
+		smokeIndices:  make([]uint16, 0, fireworks.MaxParticles*6),
+		fireIndices:   make([]uint16, 0, fireworks.MaxParticles*6),
+		fireBlend:     defaultFireBlend,
+		smokeBlend:    defaultSmokeBlend,
 	}
-	// Pre-create a pool of inactive particles so allocateParticle can reuse without nils.
-	for i := 0; i < maxParticles; i++ {
-		g.particles = append(g.particles, &Particle{active: false})
-	}
-
-	// permanent smoke emitter at bottom-center
-	g.emitters = append(g.emitters, &Emitter{
-		x:     screenWidth / 2.0,
-		y:     screenHeight - 50.0,
-		rate:  3,
-		pType: TypeSmoke,
-	})
-	return g
 }
 
-func (g *Game) allocateParticle() *Particle {
-	for _, p := range g.particles {
-		if !p.active {
-			return p
-		}
-	}
-	// pool exhausted
+func (g *Game) Update() error {
+	g.handleInput()
+	g.sys.Step()
 	return nil
 }
 
-func (g *Game) Update() error {
+// handleInput applies mouse and keyboard controls.
+func (g *Game) handleInput() {
 	// Input: left click to spawn explosion
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
 		mx, my := ebiten.CursorPosition()
-		g.spawnExplosion(float64(mx), float64(my))
+		g.sys.SpawnExplosion(float64(mx), float64(my))
 	}
 
-	// spawn from emitters
-	for _, e := range g.emitters {
-		e.spawn(g)
+	// Force controls: steer wind, toggle turbulence, zero all forces
+	s := g.sys
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		s.WindX = math.Max(s.WindX-windStep, -maxWind)
 	}
-
-	// update particles
-	for _, p := range g.particles {
-		if p.active {
-			p.update()
-			// Optionally deactivate particles that go off screen far away
-			if p.x < -100 || p.x > screenWidth+100 || p.y < -200 || p.y > screenHeight+200 {
-				p.active = false
-			}
-		}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		s.WindX = math.Min(s.WindX+windStep, maxWind)
 	}
-	return nil
-}
-
-func (g *Game) spawnExplosion(x, y float64) {
-	// spawn many fire particles in an explosion
-	for i := 0; i < 500; i++ {
-		if p := g.allocateParticle(); p != nil {
-			*p = *newParticle(x, y, TypeFire)
-			blastAngle := rand.Float64() * 2 * math.Pi
-			blastSpeed := rand.Float64()*7.0 + 3.0
-			p.vx = math.Cos(blastAngle) * blastSpeed
-			p.vy = math.Sin(blastAngle) * blastSpeed
-		} else {
-			// pool exhausted; stop spawning
-			break
-		}
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		s.TurbulenceOn = !s.TurbulenceOn
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
+		s.ForcesOn = !s.ForcesOn
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		s.Reset()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		s.ToggleFountain()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		s.LaunchRocket()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		g.fireBlend = (g.fireBlend + 1) % len(blendModes)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+		g.smokeBlend = (g.smokeBlend + 1) % len(blendModes)
 	}
 }
 
@@ -284,16 +248,16 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	halfW, halfH := smokeImageW/2.0, smokeImageH/2.0
 
 	// iterate particles and push vertices/indices into the correct buffer
-	for _, p := range g.particles {
-		if !p.active {
+	for _, p := range g.sys.Particles {
+		if !p.Active {
 			continue
 		}
 		activeCount++
-		rate := float64(p.lifetime) / float64(p.maxLife)
-		scale := p.baseScale * (1.0 + 1.0*rate)
+		rate := p.Rate()
+		scale := p.BaseScale * (1.0 + 1.0*rate)
 
 		var alpha float32 = 1.0
-		if p.pType == TypeFire {
+		if p.Type == fireworks.TypeFire {
 			alpha = float32(1.0 - math.Pow(rate, 2))
 		} else { // smoke alpha envelope (fade in, then out)
 			if rate < 0.2 {
@@ -303,28 +267,39 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			}
 		}
 
-		cr := float32(p.col.R) / 0xff * alpha
-		cg := float32(p.col.G) / 0xff * alpha
-		cb := float32(p.col.B) / 0xff * alpha
+		cr := float32(p.Col.R) / 0xff * alpha
+		cg := float32(p.Col.G) / 0xff * alpha
+		cb := float32(p.Col.B) / 0xff * alpha
 		ca := alpha
 
-		// Build GeoM-like transform (apply manually for speed)
+		// Build GeoM-like transform (apply manually for speed); fire quads
+		// are one atlas frame in size
 		var geo ebiten.GeoM
-		geo.Translate(-halfW, -halfH)
-		geo.Rotate(p.angle)
+		if p.Type == fireworks.TypeFire {
+			geo.Translate(-atlasFrameW/2, -atlasFrameH/2)
+		} else {
+			geo.Translate(-halfW, -halfH)
+		}
+		geo.Rotate(p.Angle)
 		geo.Scale(scale, scale)
-		geo.Translate(p.x, p.y)
+		geo.Translate(p.X, p.Y)
 
 		// choose target buffer
-		if p.pType == TypeFire {
+		if p.Type == fireworks.TypeFire {
 			vIndex := uint16(fireVertexCount)
 			fireVertexCount += 4
+			// the atlas frame for this point in the particle's life
+			frame := p.EarlyFrame
+			if rate >= atlasCrossover {
+				frame = p.LateFrame
+			}
+			fx0 := float64(frame) * atlasFrameW
 			// corners: top-left, bottom-left, top-right, bottom-right (matching UV coords)
 			corners := []struct{ dx, dy, sx, sy float64 }{
-				{0, 0, sx0, sy0},
-				{0, smokeImageH, sx0, sy1},
-				{smokeImageW, 0, sx1, sy0},
-				{smokeImageW, smokeImageH, sx1, sy1},
+				{0, 0, fx0, 0},
+				{0, atlasFrameH, fx0, atlasFrameH},
+				{atlasFrameW, 0, fx0 + atlasFrameW, 0},
+				{atlasFrameW, atlasFrameH, fx0 + atlasFrameW, atlasFrameH},
 			}
 			for _, c := range corners {
 				vx, vy := geo.Apply(c.dx, c.dy)
@@ -357,21 +332,22 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 	}
 
-	// Draw fire first with additive blending (lighter)
+	// Draw fire first, additive (lighter) by default
 	if len(g.fireVertices) > 0 && len(g.fireIndices) > 0 {
-		op := &ebiten.DrawTrianglesOptions{CompositeMode: ebiten.CompositeModeLighter}
+		op := &ebiten.DrawTrianglesOptions{CompositeMode: blendModes[g.fireBlend].mode}
 		// DrawTriangles expects indices referencing the vertex slice starting at 0.
-		screen.DrawTriangles(g.fireVertices, g.fireIndices, smokeImage, op)
+		screen.DrawTriangles(g.fireVertices, g.fireIndices, fireAtlas, op)
 	}
 
-	// Draw smoke with normal alpha composite
+	// Draw smoke, normal alpha composite by default
 	if len(g.smokeVertices) > 0 && len(g.smokeIndices) > 0 {
-		op := &ebiten.DrawTrianglesOptions{CompositeMode: ebiten.CompositeModeSourceOver}
+		op := &ebiten.DrawTrianglesOptions{CompositeMode: blendModes[g.smokeBlend].mode}
 		screen.DrawTriangles(g.smokeVertices, g.smokeIndices, smokeImage, op)
 	}
 
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %0.2f\nActive Particles: %d/%d\nLMB: Trigger Explosion",
-		ebiten.ActualTPS(), activeCount, maxParticles))
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %0.2f\nActive Particles: %d/%d\nLMB: Trigger Explosion  Space: Launch Rocket  R: Reset\nWind (Left/Right): %+.3f  Turbulence (T): %v  Forces (Z): %v  Fountain (F): %v\nBlend: fire (B) %s  smoke (N) %s",
+		ebiten.ActualTPS(), activeCount, fireworks.MaxParticles, g.sys.WindX, g.sys.TurbulenceOn, g.sys.ForcesOn, g.sys.FountainOn(),
+		blendModes[g.fireBlend].name, blendModes[g.smokeBlend].name))
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -379,11 +355,56 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func main() {
+	crackle := flag.Bool("crackle", false, "explosions throw seeds that pop into delayed secondary bursts")
+	seed := flag.Int64("seed", 0, "seed the particle RNG (0 = time-based)")
+	emitterSpec := flag.String("emitters", "", `emitters as "x,y,type,rate[,#rrggbb]; ..." (type: smoke, fire or fountain)`)
+	defaultEmitter := flag.Bool("default-emitter", true, "add the bottom-center smoke emitter when -emitters is empty")
+	falloff := flag.Float64("falloff", 2, "procedural smoke texture falloff exponent (higher = harder edge)")
+	lifetime := flag.String("lifetime", "uniform", "particle lifetime distribution: uniform, normal or exponential")
+	atlas := flag.String("atlas", "", `fire texture atlas: a PNG of frames in a row, or "builtin" for a core/ember pair (default: the smoke texture as one frame)`)
+	atlasFrameCount := flag.Int("atlasframes", 0, "frames in the -atlas PNG (0 = square frames)")
+	fireBlend := flag.String("fireblend", blendModes[defaultFireBlend].name, "fire blend mode: alpha, additive or multiply (B cycles)")
+	smokeBlend := flag.String("smokeblend", blendModes[defaultSmokeBlend].name, "smoke blend mode: alpha, additive or multiply (N cycles)")
+	flag.Float64Var(&atlasCrossover, "crossover", atlasCrossover, "life fraction at which fire switches from its early to its late atlas frame")
+	flag.Parse()
+
+	dist, err := fireworks.ParseLifetimeDist(*lifetime)
+	if err != nil {
+		log.Fatalf("-lifetime: %v", err)
+	}
+	fireMode, err := parseBlend(*fireBlend)
+	if err != nil {
+		log.Fatalf("-fireblend: %v", err)
+	}
+	smokeMode, err := parseBlend(*smokeBlend)
+	if err != nil {
+		log.Fatalf("-smokeblend: %v", err)
+	}
+
+	loadTextures(*falloff)
+	if err := loadAtlas(*atlas, *atlasFrameCount, *falloff); err != nil {
+		log.Fatalf("-atlas: %v", err)
+	}
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Particle System — smoke & fire (fixed)")
 	ebiten.SetTPS(60)
 
-	g := NewGame()
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	sys := fireworks.New(screenWidth, screenHeight, *seed)
+	sys.Crackle, sys.LifeDist, sys.AtlasFrames = *crackle, dist, atlasFrames
+	if *emitterSpec != "" {
+		emitters, err := fireworks.ParseEmitters(*emitterSpec)
+		if err != nil {
+			log.Fatalf("-emitters: %v", err)
+		}
+		sys.Emitters = emitters
+	} else if !*defaultEmitter {
+		sys.Emitters = sys.Emitters[:0]
+	}
+	g := NewGame(sys)
+	g.fireBlend, g.smokeBlend = fireMode, smokeMode
 
 	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
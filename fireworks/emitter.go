@@ -0,0 +1,167 @@
+package fireworks
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Emitter spawns particles at a given rate.
+type Emitter struct {
+	x, y    float64
+	rate    int // spawn every `rate` ticks (1 = every tick)
+	pType   ParticleType
+	col     color.RGBA // base color; zero value keeps the type default
+	counter int
+
+	// fountain variant: launches fire drops that arc up, fall back under
+	// gravity and settle into smoke where they land
+	fountain bool
+
+	// rocket variant: moves with its own velocity and gravity, leaving a
+	// sparse trail, and bursts into an explosion at its apex or when fuse
+	// runs out
+	vx, vy  float64
+	gravity float64
+	fuse    int // ticks left before bursting; 0 = a fixed emitter that never bursts
+}
+
+// newFountainEmitter returns a fountain at (x, y); its drops land back on y.
+func newFountainEmitter(x, y float64) *Emitter {
+	return &Emitter{x: x, y: y, rate: 2, pType: TypeFire, fountain: true}
+}
+
+// Fountain drop launch: upward speed range and sideways spread (px/tick),
+// and the smoke particles a landing drop turns into.
+const (
+	fountainMinSpeed = 3.2
+	fountainMaxSpeed = 4.2
+	fountainSpread   = 1.0
+	fountainLife     = 240 // longer than any arc, so drops land before they fade out
+	landingPuff      = 3
+)
+
+// Rocket launch (Space): upward speed range and sideways spread (px/tick),
+// its own gravity, and the fuse that bursts it if it never reaches an apex
+// (e.g. with forces off).
+const (
+	rocketMinSpeed = 7.0
+	rocketMaxSpeed = 9.0
+	rocketSpread   = 0.6
+	rocketGravity  = 0.12
+	rocketFuse     = 90
+	rocketRate     = 2 // a trail spark every other tick
+)
+
+// newRocketEmitter returns a rocket launched upward from (x, y).
+func (s *System) newRocketEmitter(x, y float64) *Emitter {
+	return &Emitter{
+		x: x, y: y,
+		vx:      (s.rng.Float64()*2 - 1) * rocketSpread,
+		vy:      -(rocketMinSpeed + s.rng.Float64()*(rocketMaxSpeed-rocketMinSpeed)),
+		gravity: rocketGravity,
+		fuse:    rocketFuse,
+		rate:    rocketRate,
+		pType:   TypeFire,
+	}
+}
+
+// move advances a rocket one tick and reports whether it bursts: once it
+// stops rising or its fuse burns out. Fixed emitters never move or burst.
+func (e *Emitter) move(forcesOn bool) bool {
+	if e.fuse == 0 {
+		return false
+	}
+	if forcesOn {
+		e.vy += e.gravity
+	}
+	e.x += e.vx
+	e.y += e.vy
+	e.fuse--
+	return e.fuse == 0 || e.vy >= 0
+}
+
+func (e *Emitter) spawn(s *System) {
+	e.counter++
+	if e.rate <= 0 {
+		e.rate = 1
+	}
+	if e.counter%e.rate != 0 {
+		return
+	}
+	if e.fuse > 0 {
+		// rocket trail: one small, short-lived spark left behind
+		if p := s.allocateParticle(); p != nil {
+			*p = *s.newParticle(e.x, e.y, TypeFire, e.col)
+			p.VX = (s.rng.Float64()*2 - 1) * 0.3
+			p.VY = s.rng.Float64() * 0.5
+			p.MaxLife = s.sampleLife(15, 15)
+			p.BaseScale *= 0.6
+		}
+		return
+	}
+	// burst 2 particles
+	for i := 0; i < 2; i++ {
+		if p := s.allocateParticle(); p != nil {
+			*p = *s.newParticle(e.x, e.y, e.pType, e.col)
+			if e.fountain {
+				p.VX = (s.rng.Float64()*2 - 1) * fountainSpread
+				p.VY = -(fountainMinSpeed + s.rng.Float64()*(fountainMaxSpeed-fountainMinSpeed))
+				p.MaxLife = fountainLife
+				p.landY = e.y
+			}
+		}
+	}
+}
+
+// ParseEmitters parses "x,y,type,rate[,#rrggbb]; ..." where type is smoke,
+// fire or fountain and the optional color overrides the type default.
+func ParseEmitters(spec string) ([]*Emitter, error) {
+	var out []*Emitter
+	for i, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		f := strings.Split(entry, ",")
+		if len(f) != 4 && len(f) != 5 {
+			return nil, fmt.Errorf("emitter %d %q: want x,y,type,rate[,#rrggbb]", i+1, entry)
+		}
+		for k := range f {
+			f[k] = strings.TrimSpace(f[k])
+		}
+		x, err := strconv.ParseFloat(f[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("emitter %d: bad x %q", i+1, f[0])
+		}
+		y, err := strconv.ParseFloat(f[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("emitter %d: bad y %q", i+1, f[1])
+		}
+		e := &Emitter{x: x, y: y}
+		switch strings.ToLower(f[2]) {
+		case "smoke":
+			e.pType = TypeSmoke
+		case "fire":
+			e.pType = TypeFire
+		case "fountain":
+			e.pType, e.fountain = TypeFire, true
+		default:
+			return nil, fmt.Errorf("emitter %d: type %q is not smoke, fire or fountain", i+1, f[2])
+		}
+		e.rate, err = strconv.Atoi(f[3])
+		if err != nil || e.rate < 1 {
+			return nil, fmt.Errorf("emitter %d: rate %q must be a positive integer", i+1, f[3])
+		}
+		if len(f) == 5 {
+			var r, g, b uint8
+			if _, err := fmt.Sscanf(f[4], "#%02x%02x%02x", &r, &g, &b); err != nil || len(f[4]) != 7 {
+				return nil, fmt.Errorf("emitter %d: color %q is not #rrggbb", i+1, f[4])
+			}
+			e.col = color.RGBA{R: r, G: g, B: b, A: 0xff}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
@@ -0,0 +1,272 @@
+package fireworks
+
+import (
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/hashes.txt instead of comparing against it")
+
+const screenWidth, screenHeight = 640, 480
+
+// The hashed scene: hashSeed drives a scripted run of hashFrames ticks
+// with crackle, turbulence and the fountain on, hashed every hashEvery
+// ticks.
+const (
+	hashSeed   = 1
+	hashFrames = 600
+	hashEvery  = 60
+)
+
+// stateHash is an FNV-1a hash of every active particle's slot, exact
+// float bits and remaining life, so any drift in any particle changes it.
+func stateHash(s *System) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	put := func(v uint64) {
+		for i := range buf {
+			buf[i] = byte(v >> (8 * i))
+		}
+		h.Write(buf[:])
+	}
+	for i, p := range s.Particles {
+		if !p.Active {
+			continue
+		}
+		put(uint64(i))
+		for _, f := range [...]float64{p.X, p.Y, p.VX, p.VY, p.Angle, p.BaseScale} {
+			put(math.Float64bits(f))
+		}
+		put(uint64(p.Lifetime))
+	}
+	return h.Sum64()
+}
+
+// hashScene runs the scripted scene and returns one hash per checkpoint.
+func hashScene() []uint64 {
+	s := New(screenWidth, screenHeight, hashSeed)
+	s.TurbulenceOn, s.Crackle = true, true
+	s.ToggleFountain()
+	var out []uint64
+	for t := 1; t <= hashFrames; t++ {
+		if t%40 == 1 {
+			s.SpawnExplosion(screenWidth*(0.25+0.5*s.rng.Float64()), screenHeight*(0.25+0.25*s.rng.Float64()))
+		}
+		s.Step()
+		if t%hashEvery == 0 {
+			out = append(out, stateHash(s))
+		}
+	}
+	return out
+}
+
+// hashArch is GOARCH plus its instruction set level when the build
+// recorded one (GOAMD64, GOARM64, ...), e.g. "amd64/v1".
+func hashArch() string {
+	arch := runtime.GOARCH
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "GO"+strings.ToUpper(arch) {
+				arch += "/" + s.Value
+			}
+		}
+	}
+	return arch
+}
+
+// TestHashes compares hashScene against the "frame hash" lines in
+// testdata/hashes.txt, or rewrites the file with -update. On a mismatch it
+// reruns the scene to tell shared mutable state (two runs here disagree)
+// from a change in the simulation.
+//
+// The hashes cover exact float bits, so they are only portable within an
+// architecture: Go may fuse x*y+z into one multiply-add on arm64, ppc64,
+// s390x or amd64 at GOAMD64=v3. The file's "arch" line records where it
+// was generated, and the test skips elsewhere.
+func TestHashes(t *testing.T) {
+	got := hashScene()
+	const path = "testdata/hashes.txt"
+	if *update {
+		var b strings.Builder
+		fmt.Fprintf(&b, "arch %s\n", hashArch())
+		for i, h := range got {
+			fmt.Fprintf(&b, "%d %016x\n", (i+1)*hashEvery, h)
+		}
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arch, rest, ok := strings.Cut(string(data), "\n")
+	if f := strings.Fields(arch); !ok || len(f) != 2 || f[0] != "arch" {
+		t.Fatalf("%s: first line %q, want \"arch <GOARCH>\"", path, arch)
+	} else if here := hashArch(); f[1] != here {
+		t.Skipf("%s was recorded on %s, running on %s; regenerate it here with -update to compare", path, f[1], here)
+	}
+	want := strings.Fields(rest)
+	if len(want) != 2*len(got) {
+		t.Fatalf("%s has %d values, want %d", path, len(want), 2*len(got))
+	}
+	for i, h := range got {
+		w, err := strconv.ParseUint(want[2*i+1], 16, 64)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if h == w {
+			continue
+		}
+		frame := (i + 1) * hashEvery
+		cause := "the simulation changed"
+		if again := hashScene(); again[i] != h {
+			cause = "two runs in this process disagree, so some state outlives a run"
+		}
+		t.Fatalf("frame %d (first %d frames match): hash %016x, want %016x; %s", frame, frame-hashEvery, h, w, cause)
+	}
+}
+
+// replayState runs a scripted scene from seed for n ticks and returns the
+// positions of every pool slot.
+func replayState(seed int64, n int) [][2]float64 {
+	s := New(screenWidth, screenHeight, seed)
+	for t := 0; t < n; t++ {
+		if t%40 == 0 {
+			s.SpawnExplosion(screenWidth*(0.25+0.5*s.rng.Float64()), screenHeight*(0.25+0.25*s.rng.Float64()))
+		}
+		s.Step()
+	}
+	out := make([][2]float64, len(s.Particles))
+	for i, p := range s.Particles {
+		if p.Active {
+			out[i] = [2]float64{p.X, p.Y}
+		}
+	}
+	return out
+}
+
+// TestReplay runs the scripted scene twice with the same seed and reports
+// the first pool slot whose position differs.
+func TestReplay(t *testing.T) {
+	const n = 600
+	a, b := replayState(1, n), replayState(1, n)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("slot %d differs after %d frames: %v vs %v", i, n, a[i], b[i])
+		}
+	}
+}
+
+// TestRocket launches one rocket into an otherwise empty scene and
+// verifies that it leaves a trail while rising, bursts at its apex (or by
+// its fuse with forces off) into a full explosion, and removes itself.
+func TestRocket(t *testing.T) {
+	for _, forces := range []bool{true, false} {
+		s := New(screenWidth, screenHeight, 1)
+		s.ForcesOn = forces
+		s.Emitters = s.Emitters[:0]
+		s.LaunchRocket()
+		e := s.Emitters[0]
+		startY, speed := e.y, -e.vy
+		ticks, trail := 0, 0
+		for len(s.Emitters) > 0 {
+			if ticks == rocketFuse {
+				t.Fatalf("forces %v: rocket still flying after %d ticks", forces, ticks)
+			}
+			before := s.Active()
+			s.Step()
+			ticks++
+			if len(s.Emitters) > 0 {
+				trail = max(trail, s.Active())
+				continue
+			}
+			// trail sparks dying this tick offset some of the 500
+			if burst := s.Active() - before; burst < 500-trail {
+				t.Errorf("forces %v: burst added %d particles, want about 500", forces, burst)
+			}
+		}
+		if trail == 0 {
+			t.Errorf("forces %v: rocket left no trail", forces)
+		}
+		rise := startY - e.y
+		if forces {
+			// apex of a launch at speed under rocketGravity, within a tick
+			want := speed * speed / (2 * rocketGravity)
+			if math.Abs(rise-want) > speed {
+				t.Errorf("rocket burst %.1f px up, apex is %.1f", rise, want)
+			}
+		} else if ticks != rocketFuse {
+			t.Errorf("forces off: rocket burst after %d ticks, want the %d-tick fuse", ticks, rocketFuse)
+		}
+	}
+}
+
+// gravityEnergyDrift launches a fire particle upward under gravity alone
+// and advances it the given number of ticks, returning the change in its
+// energy per unit mass, kinetic plus gravitational. semi drives it through
+// update (semi-implicit Euler); otherwise the same acceleration is applied
+// with explicit Euler for comparison.
+func gravityEnergyDrift(semi bool, steps int) float64 {
+	s := New(screenWidth, screenHeight, 1)
+	p := &Particle{X: screenWidth / 2, Y: screenHeight / 2, VX: 1, VY: -5, Type: TypeFire, MaxLife: steps + 1, Active: true}
+	grav, _ := p.Type.forceScale()
+	g := s.Gravity * grav
+	energy := func() float64 { return (p.VX*p.VX+p.VY*p.VY)/2 - g*p.Y }
+	e0 := energy()
+	for i := 0; i < steps; i++ {
+		if semi {
+			s.update(p)
+			continue
+		}
+		ax, ay := s.acceleration(p)
+		p.X += p.VX
+		p.Y += p.VY
+		p.VX += ax
+		p.VY += ay
+	}
+	return energy() - e0
+}
+
+// TestIntegrator compares explicit and semi-implicit Euler under constant
+// gravity. Both err by g²/2 per tick, but in opposite directions: explicit
+// Euler moves with the velocity from before the kick and gains energy, so a
+// spark climbs higher than it was launched, while update moves with the
+// kicked velocity and only ever loses it.
+func TestIntegrator(t *testing.T) {
+	const steps = 200
+	explicit := gravityEnergyDrift(false, steps)
+	semi := gravityEnergyDrift(true, steps)
+	g := New(screenWidth, screenHeight, 1).Gravity
+	bound := steps * g * g / 2 * (1 + 1e-9)
+	if explicit <= 0 {
+		t.Errorf("explicit Euler drifted %g, want a gain", explicit)
+	}
+	if semi > 0 || -semi > bound {
+		t.Errorf("semi-implicit Euler drifted %g, want a loss of at most %g", semi, bound)
+	}
+}
+
+func TestParseEmitters(t *testing.T) {
+	es, err := ParseEmitters("320, 430, smoke, 3; 100,400,fountain,2,#ff8000;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 2 || es[0].pType != TypeSmoke || es[0].rate != 3 || !es[1].fountain || es[1].col.G != 0x80 {
+		t.Errorf("ParseEmitters = %+v, %+v", es[0], es[1])
+	}
+	for _, bad := range []string{"1,2,smoke", "x,2,smoke,1", "1,2,lava,1", "1,2,fire,0", "1,2,fire,1,orange"} {
+		if _, err := ParseEmitters(bad); err == nil {
+			t.Errorf("ParseEmitters(%q) accepted", bad)
+		}
+	}
+}
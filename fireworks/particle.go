@@ -0,0 +1,214 @@
+// Package fireworks is the fireworks demo's particle simulation: a fixed
+// pool of smoke and fire particles fed by emitters, rockets, explosions,
+// crackle pops and fountain drops, moved by gravity, wind and turbulence.
+// Every setting and the random generator live on a System, so a system
+// built from the same seed replays exactly. It has no ebiten dependency;
+// the demo draws the pool.
+package fireworks
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// ParticleType defines the behavior and blending mode.
+type ParticleType int
+
+const (
+	TypeSmoke ParticleType = iota // Alpha Blending, long life, slow
+	TypeFire                      // Additive Blending, short life, high velocity
+)
+
+// Particle is one pool slot, smoke or fire.
+type Particle struct {
+	X, Y              float64
+	VX, VY            float64
+	Lifetime, MaxLife int
+	BaseScale         float64
+	Angle             float64
+	AngularVelocity   float64
+	Col               color.RGBA
+	Type              ParticleType
+	EarlyFrame        int // fire atlas frame before the crossover of its life
+	LateFrame         int // fire atlas frame after it
+	Active            bool
+
+	fuse  int     // ticks until a crackle seed pops; 0 = no fuse
+	landY float64 // fountain drops land when falling past this y; 0 = never
+}
+
+// Rate is the fraction of the particle's life used up, 0 at spawn to 1.
+func (p *Particle) Rate() float64 {
+	return float64(p.Lifetime) / float64(p.MaxLife)
+}
+
+// LifetimeDist (-lifetime) is how particle lifetimes are drawn from each
+// type's range.
+type LifetimeDist int
+
+const (
+	LifeUniform     LifetimeDist = iota // evenly over [lo, lo+span)
+	LifeNormal                          // bell around the range's middle, sd span/4
+	LifeExponential                     // same mean; many short-lived, a few long tails
+)
+
+// ParseLifetimeDist maps a -lifetime flag value to its distribution.
+func ParseLifetimeDist(s string) (LifetimeDist, error) {
+	switch s {
+	case "uniform":
+		return LifeUniform, nil
+	case "normal":
+		return LifeNormal, nil
+	case "exponential":
+		return LifeExponential, nil
+	}
+	return 0, fmt.Errorf("unknown distribution %q (want uniform, normal or exponential)", s)
+}
+
+// sampleLife draws a lifetime in ticks for a type whose uniform range is
+// [lo, lo+span). Every distribution has the same mean; the result is at
+// least 1 tick and the exponential tail is cut at four times the mean.
+func (s *System) sampleLife(lo, span int) int {
+	mean := float64(lo) + float64(span-1)/2
+	var life float64
+	switch s.LifeDist {
+	case LifeNormal:
+		life = mean + s.rng.NormFloat64()*float64(span)/4
+	case LifeExponential:
+		life = math.Min(1+s.rng.ExpFloat64()*(mean-1), 4*mean)
+	default:
+		return lo + s.rng.Intn(span)
+	}
+	return max(int(math.Round(life)), 1)
+}
+
+// forceScale returns how strongly gravity and wind act on a type: smoke is
+// light and drifts, fire is heavy and falls.
+func (t ParticleType) forceScale() (grav, wind float64) {
+	if t == TypeSmoke {
+		return 0.2, 1.0
+	}
+	return 1.0, 0.4
+}
+
+// acceleration sums the forces acting on p this tick.
+func (s *System) acceleration(p *Particle) (ax, ay float64) {
+	if !s.ForcesOn {
+		return 0, 0
+	}
+	grav, wind := p.Type.forceScale()
+	ay += s.Gravity * grav
+	ax += s.WindX * wind
+	if s.TurbulenceOn {
+		ax += (s.rng.Float64()*2 - 1) * s.Turbulence
+		ay += (s.rng.Float64()*2 - 1) * s.Turbulence
+	}
+	return ax, ay
+}
+
+// particleEvent is what update reports besides moving the particle.
+type particleEvent int
+
+const (
+	eventNone particleEvent = iota
+	eventPop                // a crackle seed's fuse burned out
+	eventLand               // a fountain drop fell back to its landing line
+)
+
+// update advances p by one tick. A popped crackle seed or a landed fountain
+// drop is spent; the caller spawns the pop or the landing puff.
+func (s *System) update(p *Particle) particleEvent {
+	if !p.Active {
+		return eventNone
+	}
+	p.Lifetime++
+	if p.fuse > 0 {
+		p.fuse--
+		if p.fuse == 0 {
+			p.Active = false
+			return eventPop
+		}
+	}
+	if p.Lifetime >= p.MaxLife {
+		p.Active = false
+		return eventNone
+	}
+	// semi-implicit Euler: accelerate first, then move with the new velocity
+	ax, ay := s.acceleration(p)
+	p.VX += ax
+	p.VY += ay
+	p.X += p.VX
+	p.Y += p.VY
+	p.Angle += p.AngularVelocity
+	if p.landY != 0 && p.VY > 0 && p.Y >= p.landY {
+		p.Active = false
+		return eventLand
+	}
+	return eventNone
+}
+
+// jitterColor varies each channel of c by up to ±spread so particles from one
+// emitter don't look flat.
+func (s *System) jitterColor(c color.RGBA, spread int) color.RGBA {
+	j := func(v uint8) uint8 {
+		n := int(v) + s.rng.Intn(2*spread+1) - spread
+		if n < 0 {
+			n = 0
+		}
+		if n > 0xff {
+			n = 0xff
+		}
+		return uint8(n)
+	}
+	return color.RGBA{R: j(c.R), G: j(c.G), B: j(c.B), A: 0xff}
+}
+
+// newParticle creates a particle of the given type. A non-zero col overrides
+// the type's default coloring.
+func (s *System) newParticle(emitterX, emitterY float64, pType ParticleType, col color.RGBA) *Particle {
+	p := &Particle{
+		Active:          true,
+		Type:            pType,
+		X:               emitterX + s.rng.Float64()*4 - 2,
+		Y:               emitterY + s.rng.Float64()*4 - 2,
+		Angle:           s.rng.Float64() * 2 * math.Pi,
+		AngularVelocity: (s.rng.Float64()*2 - 1) * 0.05,
+	}
+	switch pType {
+	case TypeSmoke:
+		p.MaxLife = s.sampleLife(240, 60) // ~4-5s
+		angle := s.rng.Float64()*math.Pi/3.0 + math.Pi/2.0
+		speed := s.rng.Float64()*0.4 + 0.1
+		p.VX = math.Cos(angle) * speed
+		p.VY = math.Sin(angle)*speed - 1.0
+
+		r := uint8(0xc0 + s.rng.Intn(0x3f))
+		g := uint8(0xc0 + s.rng.Intn(0x3f))
+		b := uint8(0xc0 + s.rng.Intn(0x3f))
+		p.Col = color.RGBA{R: r, G: g, B: b, A: 0xff}
+		if col.A != 0 {
+			p.Col = s.jitterColor(col, 0x20)
+		}
+		p.BaseScale = s.rng.Float64()*0.1 + 0.3
+
+	case TypeFire:
+		p.MaxLife = s.sampleLife(45, 30) // short life
+		ang := s.rng.Float64() * math.Pi / 4.0
+		if s.rng.Intn(2) == 0 {
+			ang = -ang
+		}
+		ang += math.Pi / 2.0
+		speed := s.rng.Float64()*1.5 + 1.0
+		p.VX = math.Cos(ang) * speed * 0.5
+		p.VY = math.Sin(ang) * speed * 2.0
+
+		p.Col = color.RGBA{R: 0xff, G: 0x90, B: 0x00, A: 0xff}
+		if col.A != 0 {
+			p.Col = s.jitterColor(col, 0x10)
+		}
+		p.BaseScale = s.rng.Float64()*0.05 + 0.15
+		p.EarlyFrame, p.LateFrame = 0, s.AtlasFrames-1
+	}
+	return p
+}
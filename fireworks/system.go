@@ -0,0 +1,257 @@
+package fireworks
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// MaxParticles is the pool size, safe for uint16 indices (max vertices =
+// 4*MaxParticles).
+const MaxParticles = 10000
+
+// crackle: explosions throw seeds that pop into small bursts
+const (
+	crackleSeeds  = 8
+	crackleSparks = 40
+)
+
+// System is one particle scene: the pool, the emitters and every setting
+// the simulation reads, with its own random generator.
+type System struct {
+	Particles []*Particle // the pool; inactive slots are free
+	Emitters  []*Emitter
+
+	// Forces shared by every particle type. Z in the demo clears ForcesOn.
+	Gravity      float64 // downward acceleration per tick
+	WindX        float64 // horizontal acceleration, steered with Left/Right
+	Turbulence   float64 // magnitude of random jitter when TurbulenceOn
+	TurbulenceOn bool
+	ForcesOn     bool
+
+	Crackle     bool         // explosions throw seeds that pop into delayed secondary bursts
+	LifeDist    LifetimeDist // how lifetimes are drawn
+	AtlasFrames int          // frames in the fire atlas; fire ages from frame 0 to the last
+
+	width, height float64
+
+	rng *rand.Rand
+
+	// free holds the indices of inactive pool slots, used as a stack so the
+	// allocation order depends only on the sequence of spawns and deaths.
+	free []int
+
+	pops     [][2]float64 // crackle seeds that burned out this tick
+	landings [][2]float64 // fountain drops that landed this tick
+
+	// the bottom-center fountain (F), nil until first switched on
+	fountain *Emitter
+}
+
+// New returns a width x height scene drawing from seed, with the default
+// forces and the permanent smoke emitter at bottom-center.
+func New(width, height float64, seed int64) *System {
+	s := &System{
+		Particles:   make([]*Particle, 0, MaxParticles),
+		Emitters:    make([]*Emitter, 0, 4),
+		Gravity:     0.05,
+		Turbulence:  0.02,
+		ForcesOn:    true,
+		AtlasFrames: 1,
+		width:       width,
+		height:      height,
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+	// Pre-create a pool of inactive particles so allocateParticle can reuse without nils.
+	s.free = make([]int, 0, MaxParticles)
+	for i := 0; i < MaxParticles; i++ {
+		s.Particles = append(s.Particles, &Particle{})
+	}
+	s.resetPool()
+
+	// permanent smoke emitter at bottom-center
+	s.Emitters = append(s.Emitters, &Emitter{
+		x:     width / 2.0,
+		y:     height - 50.0,
+		rate:  3,
+		pType: TypeSmoke,
+	})
+	return s
+}
+
+// resetPool deactivates every particle and refills the free list, pushed in
+// reverse so slot 0 is handed out first.
+func (s *System) resetPool() {
+	for _, p := range s.Particles {
+		*p = Particle{}
+	}
+	s.free = s.free[:0]
+	for i := len(s.Particles) - 1; i >= 0; i-- {
+		s.free = append(s.free, i)
+	}
+}
+
+// Reset clears the scene for a fresh start: every particle is freed, pending
+// crackle pops and rockets in flight are dropped and emitters restart their
+// spawn cadence.
+func (s *System) Reset() {
+	s.resetPool()
+	s.pops = s.pops[:0]
+	s.landings = s.landings[:0]
+	s.Emitters = slices.DeleteFunc(s.Emitters, func(e *Emitter) bool { return e.fuse > 0 })
+	for _, e := range s.Emitters {
+		e.counter = 0
+	}
+}
+
+// Active is how many pool slots are in use.
+func (s *System) Active() int {
+	return len(s.Particles) - len(s.free)
+}
+
+func (s *System) allocateParticle() *Particle {
+	if len(s.free) == 0 {
+		// pool exhausted
+		return nil
+	}
+	i := s.free[len(s.free)-1]
+	s.free = s.free[:len(s.free)-1]
+	return s.Particles[i]
+}
+
+// LaunchRocket sends a rocket up from a random spot along the bottom edge.
+func (s *System) LaunchRocket() {
+	s.Emitters = append(s.Emitters, s.newRocketEmitter(s.width*(0.25+0.5*s.rng.Float64()), s.height))
+}
+
+// ToggleFountain adds the bottom-center fountain emitter, or removes it if
+// it is running.
+func (s *System) ToggleFountain() {
+	if i := slices.Index(s.Emitters, s.fountain); s.fountain != nil && i >= 0 {
+		s.Emitters = slices.Delete(s.Emitters, i, i+1)
+		return
+	}
+	if s.fountain == nil {
+		s.fountain = newFountainEmitter(s.width/2.0, s.height-50.0)
+	}
+	s.Emitters = append(s.Emitters, s.fountain)
+}
+
+// FountainOn reports whether the fountain is running.
+func (s *System) FountainOn() bool {
+	return s.fountain != nil && slices.Contains(s.Emitters, s.fountain)
+}
+
+// Step advances the simulation by one tick.
+func (s *System) Step() {
+	// spawn from emitters
+	for _, e := range s.Emitters {
+		e.spawn(s)
+	}
+
+	// update particles; crackle pops and fountain landings are collected
+	// and spawned afterwards so the new particles start moving next tick
+	s.pops = s.pops[:0]
+	s.landings = s.landings[:0]
+	for i, p := range s.Particles {
+		if p.Active {
+			switch s.update(p) {
+			case eventPop:
+				s.pops = append(s.pops, [2]float64{p.X, p.Y})
+			case eventLand:
+				s.landings = append(s.landings, [2]float64{p.X, p.landY})
+			}
+			// Optionally deactivate particles that go off screen far away
+			if p.X < -100 || p.X > s.width+100 || p.Y < -200 || p.Y > s.height+200 {
+				p.Active = false
+			}
+			if !p.Active {
+				s.free = append(s.free, i)
+			}
+		}
+	}
+	for _, pos := range s.pops {
+		s.spawnCrackle(pos[0], pos[1])
+	}
+	for _, pos := range s.landings {
+		s.spawnLandingPuff(pos[0], pos[1])
+	}
+
+	// move rockets; one that bursts is replaced by its explosion
+	kept := s.Emitters[:0]
+	for _, e := range s.Emitters {
+		if e.move(s.ForcesOn) {
+			s.SpawnExplosion(e.x, e.y)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	s.Emitters = kept
+}
+
+// SpawnExplosion bursts 500 fire particles out of (x, y), plus crackle
+// seeds when Crackle is on.
+func (s *System) SpawnExplosion(x, y float64) {
+	// spawn many fire particles in an explosion
+	for i := 0; i < 500; i++ {
+		if p := s.allocateParticle(); p != nil {
+			*p = *s.newParticle(x, y, TypeFire, color.RGBA{})
+			blastAngle := s.rng.Float64() * 2 * math.Pi
+			blastSpeed := s.rng.Float64()*7.0 + 3.0
+			p.VX = math.Cos(blastAngle) * blastSpeed
+			p.VY = math.Sin(blastAngle) * blastSpeed
+		} else {
+			// pool exhausted; stop spawning
+			break
+		}
+	}
+
+	// crackle: a few seeds fly out with the blast and pop later
+	if s.Crackle {
+		for i := 0; i < crackleSeeds; i++ {
+			p := s.allocateParticle()
+			if p == nil {
+				break
+			}
+			*p = *s.newParticle(x, y, TypeFire, color.RGBA{})
+			blastAngle := s.rng.Float64() * 2 * math.Pi
+			blastSpeed := s.rng.Float64()*4.0 + 2.0
+			p.VX = math.Cos(blastAngle) * blastSpeed
+			p.VY = math.Sin(blastAngle) * blastSpeed
+			p.fuse = 20 + s.rng.Intn(40)
+			p.MaxLife = p.fuse + 1
+		}
+	}
+}
+
+// spawnLandingPuff turns a landed fountain drop into a few slow smoke
+// particles.
+func (s *System) spawnLandingPuff(x, y float64) {
+	for i := 0; i < landingPuff; i++ {
+		p := s.allocateParticle()
+		if p == nil {
+			return
+		}
+		*p = *s.newParticle(x, y, TypeSmoke, color.RGBA{})
+		p.MaxLife = s.sampleLife(60, 40)
+		p.BaseScale *= 0.5
+	}
+}
+
+// spawnCrackle is the small secondary pop of a crackle seed.
+func (s *System) spawnCrackle(x, y float64) {
+	for i := 0; i < crackleSparks; i++ {
+		p := s.allocateParticle()
+		if p == nil {
+			return
+		}
+		*p = *s.newParticle(x, y, TypeFire, color.RGBA{R: 0xff, G: 0xf0, B: 0xc0, A: 0xff})
+		a := s.rng.Float64() * 2 * math.Pi
+		speed := s.rng.Float64()*2.0 + 1.0
+		p.VX = math.Cos(a) * speed
+		p.VY = math.Sin(a) * speed
+		p.MaxLife = s.sampleLife(15, 15)
+		p.BaseScale *= 0.5
+	}
+}
@@ -0,0 +1,103 @@
+// Package frameperf is the performance instrumentation shared by the particle
+// demos: an optional tick-rate cap, a vsync switch, and sampled per-frame
+// Update/Draw timing logged to stderr next to the active particle count, so
+// every demo is benchmarked the same way.
+package frameperf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Options are the instrumentation settings, filled in by flag.Parse.
+type Options struct {
+	FPSCap     int  // ticks per second (-fps-cap); 0 keeps ebiten's default
+	Vsync      bool // -vsync; off lets Draw run faster than the display
+	FrameTimes int  // log timing every this many frames (-frametimes); 0 = off
+}
+
+// RegisterFlags adds -fps-cap, -vsync and -frametimes to the default flag
+// set. Call it before flag.Parse.
+func RegisterFlags() *Options {
+	o := &Options{}
+	flag.IntVar(&o.FPSCap, "fps-cap", 0, "run Update at this many ticks per second (0 = ebiten default)")
+	flag.BoolVar(&o.Vsync, "vsync", true, "sync drawing to the display refresh")
+	flag.IntVar(&o.FrameTimes, "frametimes", 0, "log average and worst Update/Draw times to stderr every N frames (0 = off)")
+	return o
+}
+
+// Apply sets the tick rate and vsync. It fails on a negative cap or sample
+// interval.
+func (o *Options) Apply() error {
+	if o.FPSCap < 0 {
+		return fmt.Errorf("-fps-cap must not be negative")
+	}
+	if o.FrameTimes < 0 {
+		return fmt.Errorf("-frametimes must not be negative")
+	}
+	if o.FPSCap > 0 {
+		ebiten.SetTPS(o.FPSCap)
+	}
+	ebiten.SetVsyncEnabled(o.Vsync)
+	return nil
+}
+
+// Wrap returns g itself when frame timing is off, and otherwise a Game that
+// times g's Update and Draw calls. active reports the demo's current
+// particle count for each log line.
+func (o *Options) Wrap(g ebiten.Game, active func() int) ebiten.Game {
+	if o.FrameTimes == 0 {
+		return g
+	}
+	return &timedGame{Game: g, active: active, every: o.FrameTimes}
+}
+
+// timedGame accumulates Update and Draw durations and logs them every
+// `every` drawn frames.
+type timedGame struct {
+	ebiten.Game
+	active func() int
+	every  int
+
+	frames          int
+	updates         int
+	update, draw    time.Duration
+	maxUpd, maxDraw time.Duration
+}
+
+func (t *timedGame) Update() error {
+	start := time.Now()
+	err := t.Game.Update()
+	d := time.Since(start)
+	t.updates++
+	t.update += d
+	t.maxUpd = max(t.maxUpd, d)
+	return err
+}
+
+func (t *timedGame) Draw(screen *ebiten.Image) {
+	start := time.Now()
+	t.Game.Draw(screen)
+	d := time.Since(start)
+	t.frames++
+	t.draw += d
+	t.maxDraw = max(t.maxDraw, d)
+	if t.frames%t.every == 0 {
+		t.log()
+	}
+}
+
+func (t *timedGame) log() {
+	avgUpd := time.Duration(0)
+	if t.updates > 0 {
+		avgUpd = t.update / time.Duration(t.updates)
+	}
+	fmt.Fprintf(os.Stderr, "frame %d: %d active | update avg %v max %v (%d calls) | draw avg %v max %v | %.1f TPS %.1f FPS\n",
+		t.frames, t.active(), avgUpd, t.maxUpd, t.updates, t.draw/time.Duration(t.every), t.maxDraw,
+		ebiten.ActualTPS(), ebiten.ActualFPS())
+	t.updates, t.update, t.draw, t.maxUpd, t.maxDraw = 0, 0, 0, 0, 0
+}
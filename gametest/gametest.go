@@ -0,0 +1,63 @@
+// Package gametest runs a package's tests inside an ebiten game loop, so
+// they can draw to offscreen images and read them back. Images can only be
+// read once the loop has started; where it can't start (no display, no GPU)
+// the tests run without it and the drawing ones skip.
+package gametest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// running is set while the tests run inside the game loop.
+var running bool
+
+// runner runs the tests on the game loop's first Update and then ends it.
+type runner struct {
+	m    *testing.M
+	code int
+	done bool
+}
+
+func (r *runner) Update() error {
+	running = true
+	r.code = r.m.Run()
+	r.done = true
+	return ebiten.Termination
+}
+
+func (r *runner) Draw(screen *ebiten.Image) {}
+
+func (r *runner) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return outsideWidth, outsideHeight
+}
+
+// Main is a TestMain for a package with drawing tests: call it as
+// gametest.Main(m).
+func Main(m *testing.M) {
+	r := &runner{m: m}
+	ebiten.SetWindowSize(64, 64)
+	err := ebiten.RunGame(r)
+	if !r.done {
+		running = false
+		os.Exit(m.Run())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gametest:", err)
+		if r.code == 0 {
+			r.code = 1
+		}
+	}
+	os.Exit(r.code)
+}
+
+// Require skips tb unless the tests are running inside the game loop.
+func Require(tb testing.TB) {
+	tb.Helper()
+	if !running {
+		tb.Skip("no ebiten game loop (no display or GPU); skipping a test that draws")
+	}
+}
@@ -0,0 +1,77 @@
+// Package integrator holds the numerical methods physicsgame advances its
+// balls with. Every method is written as kicks, which change a body's
+// velocity by the acceleration acting on it, and drifts, which move it at
+// its velocity, so none needs state beyond position and velocity.
+package integrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// State is a body's position and velocity.
+type State struct {
+	X, Y   float64
+	VX, VY float64
+}
+
+// drift moves s at its current velocity for dt.
+func (s *State) drift(dt float64) {
+	s.X += s.VX * dt
+	s.Y += s.VY * dt
+}
+
+// Kick applies the acceleration acting on a body in state s to its
+// velocity over dt.
+type Kick func(s *State, dt float64)
+
+// Method is a numerical method for advancing a body by one step.
+type Method int
+
+const (
+	SemiImplicitEuler Method = iota // velocity first, then position with the new velocity
+	ExplicitEuler                   // position with the old velocity, then velocity
+	VelocityVerlet                  // half kick, drift, half kick at the new position
+	MidpointRK2                     // second-order Runge-Kutta through the half-step state
+	NumMethods
+)
+
+var names = [NumMethods]string{"semi", "euler", "verlet", "rk2"}
+
+func (m Method) String() string { return names[m] }
+
+// Parse looks a method up by its name as String returns it.
+func Parse(s string) (Method, error) {
+	for i, name := range names {
+		if s == name {
+			return Method(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown integrator %q (want one of %s)", s, strings.Join(names[:], ", "))
+}
+
+// Step advances s by dt with method m under the acceleration kick applies.
+func (m Method) Step(s *State, dt float64, kick Kick) {
+	switch m {
+	case ExplicitEuler:
+		vx, vy := s.VX, s.VY
+		kick(s, dt)
+		s.X += vx * dt
+		s.Y += vy * dt
+	case VelocityVerlet:
+		kick(s, dt/2)
+		s.drift(dt)
+		kick(s, dt/2)
+	case MidpointRK2:
+		x, y, vx, vy := s.X, s.Y, s.VX, s.VY
+		kick(s, dt/2) // velocity at the midpoint
+		vxMid, vyMid := s.VX, s.VY
+		s.X, s.Y = x+vx*dt/2, y+vy*dt/2
+		s.VX, s.VY = vx, vy
+		kick(s, dt) // full step with the midpoint acceleration
+		s.X, s.Y = x+vxMid*dt, y+vyMid*dt
+	default:
+		kick(s, dt)
+		s.drift(dt)
+	}
+}
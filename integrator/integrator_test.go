@@ -0,0 +1,83 @@
+package integrator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for m := Method(0); m < NumMethods; m++ {
+		if got, err := Parse(m.String()); err != nil || got != m {
+			t.Errorf("Parse(%q) = %v, %v; want %v", m.String(), got, err, m)
+		}
+	}
+	if _, err := Parse("leapfrog"); err == nil {
+		t.Error("Parse accepted an unknown name")
+	}
+}
+
+// TestFreeFall checks every method against the closed form under constant
+// acceleration: the second-order methods are exact, semi-implicit Euler
+// overshoots by a*dt²/2 per step and explicit Euler falls short by as much.
+func TestFreeFall(t *testing.T) {
+	const a, dt, steps = 9.8, 0.016, 100
+	fall := func(s *State, dt float64) { s.VY += a * dt }
+	T := dt * steps
+	exact := a * T * T / 2
+	for _, c := range []struct {
+		m    Method
+		want float64
+	}{
+		{SemiImplicitEuler, exact + a*dt*T/2},
+		{ExplicitEuler, exact - a*dt*T/2},
+		{VelocityVerlet, exact},
+		{MidpointRK2, exact},
+	} {
+		var s State
+		for i := 0; i < steps; i++ {
+			c.m.Step(&s, dt, fall)
+		}
+		if math.Abs(s.Y-c.want) > 1e-9 || math.Abs(s.VY-a*T) > 1e-9 {
+			t.Errorf("%v: fell to y %v at speed %v, want %v at %v", c.m, s.Y, s.VY, c.want, a*T)
+		}
+	}
+}
+
+// TestOrbitEnergyDrift puts a body on an eccentric orbit around a fixed
+// point mass and integrates ten orbits with every method, tracking each
+// one's worst relative drift in total energy. Explicit Euler gains energy
+// every step and spirals out; the symplectic methods only oscillate, and
+// velocity Verlet, being second order, stays well below semi-implicit Euler.
+func TestOrbitEnergyDrift(t *testing.T) {
+	const (
+		gm     = 1e7   // gravitational parameter of the fixed mass
+		r0     = 100.0 // starting distance
+		dt     = 0.016
+		steps  = 1250 // about ten orbits at dt
+		orbitV = 0.8  // starting speed as a fraction of circular speed
+	)
+	energy := func(s State) float64 { return (s.VX*s.VX+s.VY*s.VY)/2 - gm/math.Hypot(s.X, s.Y) }
+	well := func(s *State, dt float64) {
+		d := math.Hypot(s.X, s.Y)
+		k := -gm / (d * d * d) * dt
+		s.VX += s.X * k
+		s.VY += s.Y * k
+	}
+
+	var drift [NumMethods]float64
+	for m := Method(0); m < NumMethods; m++ {
+		s := State{X: r0, VY: orbitV * math.Sqrt(gm/r0)}
+		e0 := energy(s)
+		for i := 0; i < steps; i++ {
+			m.Step(&s, dt, well)
+			drift[m] = math.Max(drift[m], math.Abs((energy(s)-e0)/e0))
+		}
+		t.Logf("%-6s max energy drift %.2e", m, drift[m])
+	}
+	if drift[VelocityVerlet] >= drift[SemiImplicitEuler] {
+		t.Errorf("velocity Verlet drift %.2e is not below semi-implicit Euler's %.2e", drift[VelocityVerlet], drift[SemiImplicitEuler])
+	}
+	if drift[VelocityVerlet]*10 >= drift[ExplicitEuler] {
+		t.Errorf("velocity Verlet drift %.2e is not an order of magnitude below explicit Euler's %.2e", drift[VelocityVerlet], drift[ExplicitEuler])
+	}
+}
@@ -0,0 +1,665 @@
+// Copyright 2017 The Ebiten Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mandelbrot is the mandelbrot demo's viewer: an escape-time
+// renderer with smooth, distance-estimate and interior-period coloring,
+// drawn progressively in parallel tiles on the CPU or, for shallow views,
+// by a Kage shader. The demo only reads flags and runs a Game.
+package mandelbrot
+
+import (
+	"fmt"
+	imagecolor "image/color"
+	"math"
+	"math/cmplx"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// default window size (-width, -height); the window is resizable and
+	// the view keeps square pixels at any aspect ratio
+	ScreenWidth  = 640
+	ScreenHeight = 640
+	maxIt        = 256 // Increased iterations for better detail when zooming
+
+	// Progressive renderer: tiles are computed in parallel batches, and each
+	// Update spends at most renderBudget on them so input stays responsive.
+	tileSize     = 64
+	renderBudget = 12 * time.Millisecond
+
+	// escapeRadius is far larger than the minimal 2 so that |z| is deep in the
+	// asymptotic regime at bailout, which makes the smooth iteration count
+	// accurate and removes the residual banding. The test stays a single compare.
+	escapeRadius   = 1 << 16
+	escapeRadiusSq = float64(escapeRadius) * escapeRadius
+)
+
+// logEscapeRadius normalizes the smoothing term for the chosen radius.
+var logEscapeRadius = math.Log(escapeRadius)
+
+// --- Color Function: Smooth Julia Set-like Coloring ---
+
+// color calculates a smooth color based on the escape time 'it' and final complex value 'z'.
+func color(it int, z complex128) (r, g, b byte) {
+	if it == maxIt {
+		// Points in the set are black
+		return 0x00, 0x00, 0x00
+	}
+
+	// Calculate Normalized Iteration Count (smooth coloring)
+	// v = it + 1 - log2(log(|z|) / log(R)), continuous across iteration bands for escape radius R
+	magZ := real(z)*real(z) + imag(z)*imag(z) // Using |z|^2 as it avoids a sqrt, and log(sqrt(x)) = 0.5 * log(x)
+
+	// A small check to avoid log(0) which happens if magZ is very close to zero
+	if magZ == 0 {
+		return 0x00, 0x00, 0x00
+	}
+
+	// Dividing by log(R) keeps the fractional part in [0, 1) for the large
+	// escape radius: |z| lands between R and R^2 on the escaping iteration.
+
+	// We use the log of the magnitude squared.
+	// We'll use a simple, aesthetically pleasing sine wave color map.
+	logMagZ := math.Log(magZ)
+	v := float64(it) + 1.0 - math.Log2(logMagZ/2/logEscapeRadius)
+
+	// Map the fractional iteration count 'v' to an HSL or sine-based RGB color.
+	// Adjust these constants for a different palette.
+	r = byte(math.Sin(0.1*v+0.0)*127 + 128)
+	g = byte(math.Sin(0.1*v+2.0)*127 + 128)
+	b = byte(math.Sin(0.1*v+4.0)*127 + 128)
+
+	return r, g, b
+}
+
+// deColor maps the distance estimate to the set onto brightness, so the
+// boundary shows as a thin bright outline that stays sharp at any zoom.
+// dz is the derivative of z with respect to c and pixelSize the width of one
+// pixel in the complex plane.
+func deColor(it int, z, dz complex128, pixelSize float64) (r, g, b byte) {
+	if it == maxIt {
+		return 0x00, 0x00, 0x00
+	}
+	absZ := cmplx.Abs(z)
+	absDz := cmplx.Abs(dz)
+	if absDz == 0 {
+		return 0x00, 0x00, 0x00
+	}
+	// Distance estimate: d = 2|z|·ln|z| / |dz|
+	d := 2 * absZ * math.Log(absZ) / absDz
+
+	// Within a few pixels of the boundary is bright, falling off with distance
+	t := math.Min(math.Sqrt(d/(4*pixelSize)), 1)
+	v := byte((1 - t) * 255)
+	return v, v, v
+}
+
+// interiorColor shades a point inside the set by the period of the
+// attracting cycle its orbit settled into, with brightness from the final |z|,
+// so bulbs of different periods show as distinct subtle bands.
+func interiorColor(period int, z complex128) (r, g, b byte) {
+	if period == 0 {
+		// no cycle detected within maxIt
+		return 0x00, 0x00, 0x00
+	}
+	t := float64(period)
+	shade := 0.25 + 0.2*math.Min(cmplx.Abs(z)/2, 1)
+	r = byte((math.Sin(0.9*t+0.0)*0.5 + 0.5) * shade * 255)
+	g = byte((math.Sin(0.9*t+2.0)*0.5 + 0.5) * shade * 255)
+	b = byte((math.Sin(0.9*t+4.0)*0.5 + 0.5) * shade * 255)
+	return r, g, b
+}
+
+// mandelbrotShaderSrc is the Kage program for the -gpu path: the escape-time
+// iteration and smooth coloring of color, per pixel in float32. Kage loops
+// need a constant bound, so MaxIt may not exceed 1024.
+const mandelbrotShaderSrc = `//kage:unit pixels
+
+package main
+
+var Center vec2
+var Size float
+var MaxIt int
+var ScreenSize vec2
+
+const logEscapeRadius = 11.090354888959125 // ln(2^16)
+
+func Fragment(dstPos vec4, srcPos vec2, color vec4) vec4 {
+	p := dstPos.xy - 0.5 // pixel centers, as the CPU path samples them
+	c := vec2(
+		p.x*Size/ScreenSize.x-Size/2+Center.x,
+		(ScreenSize.y-p.y)*Size/ScreenSize.x-Size*ScreenSize.y/ScreenSize.x/2+Center.y,
+	)
+	z := vec2(0)
+	for i := 0; i < 1024; i++ {
+		if i >= MaxIt {
+			break
+		}
+		z = vec2(z.x*z.x-z.y*z.y, 2*z.x*z.y) + c
+		m := dot(z, z)
+		if m > 4294967296.0 { // escapeRadius^2
+			v := float(i) + 1 - log2(log(m)/2/logEscapeRadius)
+			rgb := sin(0.1*v+vec3(0, 2, 4))*127 + 128
+			return vec4(floor(rgb)/255, 1)
+		}
+	}
+	return vec4(0, 0, 0, 1)
+}
+`
+
+// GPUMinSize is the narrowest view width drawn with the shader. Below it a
+// pixel spans only a few float32 ulps of c, so the image turns blocky and
+// the CPU path (float64) takes over.
+const GPUMinSize = 1e-3
+
+// periodEpsilon is how close the orbit must return to a checkpoint to count as a cycle.
+const periodEpsilon = 1e-10
+
+// --- Game Structure and Methods ---
+
+type Game struct {
+	offscreen     *ebiten.Image
+	offscreenPix  []byte
+	centerX       float64
+	centerY       float64
+	size          float64 // Width of the view in the complex plane
+	width, height int     // frame size in pixels; the view is size*height/width tall
+
+	// window size from the last Layout call; Update resizes to it
+	layoutW, layoutH int
+	needsRedraw      bool
+	deMode           bool // distance-estimation coloring instead of smooth iteration count
+	interiorShade    bool // color points in the set by their cycle period instead of black
+
+	// Progressive tiled renderer state
+	renderCX, renderCY, renderSize float64 // view the pending tiles belong to
+	pendingTiles                   []tile
+	totalTiles                     int
+	renderTime                     time.Duration // compute time spent on the frame in progress
+	lastRenderTime                 time.Duration // compute time of the last completed frame
+	showStats                      bool
+	lastView                       viewKey // view the offscreen holds or is rendering
+	haveView                       bool
+
+	// "Go to coordinate" input box (G)
+	gotoActive bool
+	gotoText   []rune
+	gotoErr    string
+
+	// In-frame controls/coordinates overlay (H)
+	showHelp bool
+
+	// Goroutines rendering tiles in parallel (-workers)
+	workers int
+
+	// Window title last handed to SetWindowTitle, and how many times it was
+	// called, so title churn can be measured (TestTitleChurn)
+	title     string
+	titleSets int
+
+	// Compiled escape-time shader (-gpu); nil renders on the CPU only
+	gpu *ebiten.Shader
+}
+
+// WindowTitle is the static window title; controls and coordinates are
+// drawn in the frame instead.
+const WindowTitle = "Mandelbrot (Ebitengine Demo)"
+
+// helpText lists the controls for the H overlay.
+const helpText = "Pan: Arrows | Zoom: I/O or Mouse Clicks | DE: D | Interior: P\nStats: T | Go to: G | Reset: R | Help: H"
+
+// SetTitle updates the window title only when it actually changes.
+func (g *Game) SetTitle(title string) {
+	if title == g.title {
+		return
+	}
+	g.title = title
+	g.titleSets++
+	ebiten.SetWindowTitle(title)
+}
+
+// viewKey identifies everything that affects the rendered image.
+type viewKey struct {
+	centerX, centerY, size float64
+	maxIt                  int
+	deMode, interiorShade  bool
+}
+
+// tile is a rectangle of pixels [x0,x1) x [y0,y1) rendered as one unit of work.
+type tile struct {
+	x0, y0, x1, y1 int
+}
+
+// NewGame returns a viewer of the whole set that renders with workers
+// goroutines.
+func NewGame(workers int) *Game {
+	g := &Game{
+		offscreen:    ebiten.NewImage(ScreenWidth, ScreenHeight),
+		offscreenPix: make([]byte, ScreenWidth*ScreenHeight*4),
+		width:        ScreenWidth,
+		height:       ScreenHeight,
+		// Initial View: the whole Mandelbrot set
+		centerX:     -0.75,
+		centerY:     0.0,
+		size:        3.0,
+		needsRedraw: true,
+		showHelp:    true,
+		workers:     workers,
+	}
+	// Initial image will be drawn in the first Update call
+	return g
+}
+
+// resize reallocates the offscreen for a width x height frame and starts a
+// fresh render.
+func (g *Game) resize(width, height int) {
+	g.width, g.height = width, height
+	g.offscreen = ebiten.NewImage(width, height)
+	g.offscreenPix = make([]byte, width*height*4)
+	g.pendingTiles = g.pendingTiles[:0]
+	g.haveView = false
+	g.needsRedraw = true
+}
+
+// toPlane maps pixel (i, j) of a width x height frame to c = x + yi for the
+// view centered on (centerX, centerY) that is size wide. Pixels are square,
+// so the view is size*height/width tall.
+func toPlane(i, j, width, height int, centerX, centerY, size float64) (x, y float64) {
+	w, h := float64(width), float64(height)
+	x = float64(i)*size/w - size/2 + centerX
+	y = (h-float64(j))*size/w - size*h/w/2 + centerY
+	return x, y
+}
+
+// usingGPU reports whether the current view is drawn by the shader. Deep
+// zooms, distance estimation and interior shading stay on the CPU.
+func (g *Game) usingGPU() bool {
+	return g.gpu != nil && g.size >= GPUMinSize && !g.deMode && !g.interiorShade
+}
+
+// queueTiles splits a width x height frame into tiles for the progressive
+// renderer.
+func queueTiles(tiles []tile, width, height int) []tile {
+	tiles = tiles[:0]
+	for y := 0; y < height; y += tileSize {
+		for x := 0; x < width; x += tileSize {
+			tiles = append(tiles, tile{x, y, min(x+tileSize, width), min(y+tileSize, height)})
+		}
+	}
+	return tiles
+}
+
+// updateOffscreen starts a progressive render of the given view. The tiles are
+// computed by renderPending over the following frames.
+func (gm *Game) updateOffscreen(centerX, centerY, size float64) {
+	// Skip redundant redraws (e.g. holding R at the reset position): the
+	// offscreen already holds, or is being filled with, this exact view.
+	key := viewKey{centerX, centerY, size, maxIt, gm.deMode, gm.interiorShade}
+	if gm.haveView && key == gm.lastView {
+		return
+	}
+	gm.lastView, gm.haveView = key, true
+
+	gm.renderCX, gm.renderCY, gm.renderSize = centerX, centerY, size
+	gm.pendingTiles = queueTiles(gm.pendingTiles, gm.width, gm.height)
+	gm.totalTiles = len(gm.pendingTiles)
+	gm.renderTime = 0
+}
+
+// renderPending computes queued tiles in parallel batches of one tile per
+// worker until the queue is empty or the frame budget is spent.
+func (gm *Game) renderPending(budget time.Duration) {
+	start := time.Now()
+	for len(gm.pendingTiles) > 0 && time.Since(start) < budget {
+		n := min(gm.workers, len(gm.pendingTiles))
+		batch := gm.pendingTiles[len(gm.pendingTiles)-n:]
+		var wg sync.WaitGroup
+		for _, t := range batch {
+			wg.Add(1)
+			go func(t tile) {
+				defer wg.Done()
+				gm.renderTile(t)
+			}(t)
+		}
+		wg.Wait()
+		gm.pendingTiles = gm.pendingTiles[:len(gm.pendingTiles)-n]
+	}
+	gm.renderTime += time.Since(start)
+	if len(gm.pendingTiles) == 0 {
+		gm.lastRenderTime = gm.renderTime
+	}
+	// Update the Ebiten image from the pixel buffer
+	gm.offscreen.WritePixels(gm.offscreenPix)
+}
+
+// renderTile runs the escape-time algorithm for the pixels of one tile.
+func (gm *Game) renderTile(t tile) {
+	centerX, centerY, size := gm.renderCX, gm.renderCY, gm.renderSize
+	// The complex plane width/height is 'size'.
+	// This is the Mandelbrot Set calculation (escape time algorithm).
+	for j := t.y0; j < t.y1; j++ {
+		for i := t.x0; i < t.x1; i++ {
+			// Map pixel (i, j) to complex coordinate c = x + yi
+			x, y := toPlane(i, j, gm.width, gm.height, centerX, centerY, size)
+			it, period, z, dz := gm.escape(x, y)
+
+			// Get color using the smooth coloring or distance-estimation function
+			var r, g, b byte
+			if it == maxIt && gm.interiorShade {
+				r, g, b = interiorColor(period, z)
+			} else if gm.deMode {
+				r, g, b = deColor(it, z, dz, size/float64(gm.width))
+			} else {
+				r, g, b = color(it, z)
+			}
+
+			// Write the color to the pixel buffer
+			p := 4 * (i + j*gm.width)
+			gm.offscreenPix[p] = r
+			gm.offscreenPix[p+1] = g
+			gm.offscreenPix[p+2] = b
+			gm.offscreenPix[p+3] = 0xff // Alpha
+		}
+	}
+}
+
+// parseGoto parses "re,im,size" as typed into the go-to box.
+func parseGoto(s string) (re, im, size float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("expected re,im,size")
+	}
+	var v [3]float64
+	for i, part := range parts {
+		v[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("bad number %q", strings.TrimSpace(part))
+		}
+	}
+	if !(v[2] > 0) || math.IsInf(v[2], 0) {
+		return 0, 0, 0, fmt.Errorf("size must be positive")
+	}
+	return v[0], v[1], v[2], nil
+}
+
+// updateGoto collects typed characters while the go-to box is open and jumps
+// to the parsed view on Enter.
+func (g *Game) updateGoto() {
+	g.gotoText = ebiten.AppendInputChars(g.gotoText)
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.gotoText) > 0 {
+		g.gotoText = g.gotoText[:len(g.gotoText)-1]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.gotoActive = false
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter) {
+		re, im, size, err := parseGoto(string(g.gotoText))
+		if err != nil {
+			g.gotoErr = err.Error()
+			return
+		}
+		g.centerX, g.centerY, g.size = re, im, size
+		g.needsRedraw = true
+		g.gotoActive = false
+	}
+}
+
+// escape iterates z = z*z + c for c = x + yi and returns the iteration count,
+// the detected cycle period (interior shading) and the final z and dz/dc. It
+// works on explicit real/imaginary float64 parts so the bailout test reuses
+// the squares needed for the next step; the arithmetic matches escapeComplex
+// operation for operation, so the output is bit-identical.
+func (gm *Game) escape(x, y float64) (it, period int, z, dz complex128) {
+	zr, zi := 0.0, 0.0
+	zr2, zi2 := 0.0, 0.0
+	dzr, dzi := 0.0, 0.0 // dz/dc, only tracked for distance estimation
+
+	// Brent-style cycle detection: compare against a checkpoint that is
+	// moved forward at doubling intervals.
+	oldR, oldI := zr, zi
+	steps, checkEvery := 0, 8
+
+	for ; it < maxIt; it++ {
+		if gm.deMode {
+			// dz = 2*z*dz + 1
+			tr, ti := 2*zr, 2*zi
+			dzr, dzi = tr*dzr-ti*dzi+1, tr*dzi+ti*dzr
+		}
+		zi = zr*zi + zi*zr + y
+		zr = zr2 - zi2 + x
+		zr2, zi2 = zr*zr, zi*zi
+		// Check for bailout condition: |z|^2 > R^2
+		if zr2+zi2 > escapeRadiusSq {
+			break
+		}
+		if gm.interiorShade {
+			steps++
+			if math.Abs(zr-oldR) < periodEpsilon && math.Abs(zi-oldI) < periodEpsilon {
+				period = steps
+				it = maxIt // a cycle means the point never escapes
+				break
+			}
+			if steps == checkEvery {
+				oldR, oldI = zr, zi
+				steps = 0
+				checkEvery *= 2
+			}
+		}
+	}
+	return it, period, complex(zr, zi), complex(dzr, dzi)
+}
+
+// escapeComplex is the straightforward complex128 form of escape, kept as
+// the reference TestEscapeMatchesComplex and BenchmarkEscape compare against.
+func (gm *Game) escapeComplex(x, y float64) (it, period int, z, dz complex128) {
+	c := complex(x, y)
+	dz = complex(0, 0)
+
+	zOld := z
+	steps, checkEvery := 0, 8
+
+	for ; it < maxIt; it++ {
+		if gm.deMode {
+			dz = 2*z*dz + 1
+		}
+		z = z*z + c
+		if real(z)*real(z)+imag(z)*imag(z) > escapeRadiusSq {
+			break
+		}
+		if gm.interiorShade {
+			steps++
+			if math.Abs(real(z)-real(zOld)) < periodEpsilon && math.Abs(imag(z)-imag(zOld)) < periodEpsilon {
+				period = steps
+				it = maxIt
+				break
+			}
+			if steps == checkEvery {
+				zOld = z
+				steps = 0
+				checkEvery *= 2
+			}
+		}
+	}
+	return it, period, z, dz
+}
+
+func (g *Game) Update() error {
+	const (
+		panSpeed   = 0.05 // Pan distance relative to current view size
+		zoomFactor = 1.1  // Zoom step (10% change)
+	)
+
+	if g.layoutW > 0 && (g.layoutW != g.width || g.layoutH != g.height) {
+		g.resize(g.layoutW, g.layoutH)
+	}
+
+	// While the go-to box is open the keyboard belongs to it
+	if g.gotoActive {
+		g.updateGoto()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		g.gotoActive = true
+		g.gotoText = g.gotoText[:0]
+		g.gotoErr = ""
+	} else {
+		g.handleViewInput(panSpeed, zoomFactor)
+	}
+
+	// Only recalculate the fractal if the view has changed; the shader
+	// path needs no offscreen at all
+	if g.needsRedraw {
+		if !g.usingGPU() {
+			g.updateOffscreen(g.centerX, g.centerY, g.size)
+		}
+		g.needsRedraw = false
+	}
+	if len(g.pendingTiles) > 0 {
+		g.renderPending(renderBudget)
+	}
+	return nil
+}
+
+// handleViewInput applies the pan, zoom and display toggles.
+func (g *Game) handleViewInput(panSpeed, zoomFactor float64) {
+
+	// --- Input Handling for Pan and Zoom ---
+
+	// Panning (Navigation)
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		g.centerX -= g.size * panSpeed
+		g.needsRedraw = true
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		g.centerX += g.size * panSpeed
+		g.needsRedraw = true
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		g.centerY += g.size * panSpeed
+		g.needsRedraw = true
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		g.centerY -= g.size * panSpeed
+		g.needsRedraw = true
+	}
+
+	// Zooming
+	if ebiten.IsKeyPressed(ebiten.KeyI) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		g.size /= zoomFactor
+		g.needsRedraw = true
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyO) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		g.size *= zoomFactor
+		g.needsRedraw = true
+	}
+
+	// Toggle distance-estimation coloring
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		g.deMode = !g.deMode
+		g.needsRedraw = true
+	}
+
+	// Toggle interior (period) shading
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.interiorShade = !g.interiorShade
+		g.needsRedraw = true
+	}
+
+	// Reset to initial view (Optional feature)
+	if ebiten.IsKeyPressed(ebiten.KeyR) {
+		g.centerX = -0.75
+		g.centerY = 0.0
+		g.size = 3.0
+		g.needsRedraw = true
+	}
+
+	// Toggle the render statistics overlay
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		g.showStats = !g.showStats
+	}
+
+	// Toggle the controls/coordinates overlay
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
+		g.showHelp = !g.showHelp
+	}
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	if g.usingGPU() {
+		op := &ebiten.DrawRectShaderOptions{Uniforms: map[string]any{
+			"Center":     []float32{float32(g.centerX), float32(g.centerY)},
+			"Size":       float32(g.size),
+			"MaxIt":      maxIt,
+			"ScreenSize": []float32{float32(g.width), float32(g.height)},
+		}}
+		screen.DrawRectShader(g.width, g.height, g.gpu, op)
+	} else {
+		// Draw the pre-calculated offscreen image to the main screen
+		screen.DrawImage(g.offscreen, nil)
+	}
+
+	var overlay string
+	if g.showHelp {
+		overlay = fmt.Sprintf("%s\nCenter: %.10g, %.10g  Size: %.4g\n", helpText, g.centerX, g.centerY, g.size)
+	}
+	if g.showStats {
+		renderer := "CPU"
+		if g.usingGPU() {
+			renderer = "GPU shader"
+		} else if g.gpu != nil {
+			renderer = "CPU (view needs float64 or DE/interior)"
+		}
+		overlay += fmt.Sprintf("Renderer: %s\nCPUs: %d, workers: %d\nTiles remaining: %d/%d\nLast full frame: %v",
+			renderer, runtime.NumCPU(), g.workers, len(g.pendingTiles), g.totalTiles, g.lastRenderTime.Round(time.Microsecond))
+	}
+	if overlay != "" {
+		ebitenutil.DebugPrint(screen, overlay)
+	}
+
+	// Go-to input box along the bottom edge
+	if g.gotoActive {
+		ebitenutil.DrawRect(screen, 0, float64(g.height-40), float64(g.width), 40, imagecolor.RGBA{0, 0, 0, 0xc0})
+		ebitenutil.DebugPrintAt(screen, "Go to re,im,size: "+string(g.gotoText)+"_", 8, g.height-36)
+		if g.gotoErr != "" {
+			ebitenutil.DebugPrintAt(screen, "Error: "+g.gotoErr+" (Enter: go, Esc: cancel)", 8, g.height-20)
+		} else {
+			ebitenutil.DebugPrintAt(screen, "Enter: go, Esc: cancel", 8, g.height-20)
+		}
+	}
+}
+
+// EnableGPU compiles the escape-time shader, so views wider than GPUMinSize
+// are drawn on the GPU.
+func (g *Game) EnableGPU() error {
+	s, err := ebiten.NewShader([]byte(mandelbrotShaderSrc))
+	if err != nil {
+		return err
+	}
+	g.gpu = s
+	return nil
+}
+
+// Layout renders at the window's actual size; Update reallocates the
+// offscreen when it changes.
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	g.layoutW, g.layoutH = max(outsideWidth, 1), max(outsideHeight, 1)
+	return g.layoutW, g.layoutH
+}
@@ -0,0 +1,169 @@
+package mandelbrot
+
+import (
+	"math"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// escapeModes are the coloring modes that change what escape computes.
+var escapeModes = []struct {
+	name         string
+	de, interior bool
+}{{"smooth", false, false}, {"distance", true, false}, {"interior", false, true}}
+
+// eachPixel maps every pixel of the default view to the plane.
+func eachPixel(fn func(i, j int, x, y float64)) {
+	const size, cx, cy = 3.0, -0.75, 0.0
+	for j := 0; j < ScreenHeight; j++ {
+		for i := 0; i < ScreenWidth; i++ {
+			x, y := toPlane(i, j, ScreenWidth, ScreenHeight, cx, cy, size)
+			fn(i, j, x, y)
+		}
+	}
+}
+
+// TestEscapeMatchesComplex checks that the float64 escape produces exactly
+// the results of the complex128 reference over the default view in every
+// coloring mode.
+func TestEscapeMatchesComplex(t *testing.T) {
+	gm := &Game{}
+	for _, mode := range escapeModes {
+		gm.deMode, gm.interiorShade = mode.de, mode.interior
+		bad := 0
+		eachPixel(func(i, j int, x, y float64) {
+			it1, p1, z1, dz1 := gm.escape(x, y)
+			it2, p2, z2, dz2 := gm.escapeComplex(x, y)
+			if it1 != it2 || p1 != p2 || z1 != z2 || dz1 != dz2 {
+				if bad++; bad <= 3 {
+					t.Errorf("%s: pixel (%d,%d) differs: it %d/%d period %d/%d z %v/%v dz %v/%v",
+						mode.name, i, j, it1, it2, p1, p2, z1, z2, dz1, dz2)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEscape times escape against escapeComplex over one frame of the
+// default view in every coloring mode.
+func BenchmarkEscape(b *testing.B) {
+	gm := &Game{}
+	for _, mode := range escapeModes {
+		for _, impl := range []struct {
+			name string
+			fn   func(x, y float64) (int, int, complex128, complex128)
+		}{{"float64", gm.escape}, {"complex128", gm.escapeComplex}} {
+			b.Run(mode.name+"/"+impl.name, func(b *testing.B) {
+				gm.deMode, gm.interiorShade = mode.de, mode.interior
+				for n := 0; n < b.N; n++ {
+					eachPixel(func(_, _ int, x, y float64) { impl.fn(x, y) })
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkRender times complete renders of the default view with one
+// worker and with one per CPU, so scaling can be compared.
+func BenchmarkRender(b *testing.B) {
+	counts := []int{1}
+	if n := runtime.NumCPU(); n > 1 {
+		counts = append(counts, n)
+	}
+	for _, workers := range counts {
+		b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+			gm := NewGame(workers)
+			for n := 0; n < b.N; n++ {
+				gm.haveView = false // force a fresh render of the same view
+				gm.updateOffscreen(gm.centerX, gm.centerY, gm.size)
+				gm.renderPending(time.Duration(math.MaxInt64))
+			}
+		})
+	}
+}
+
+// TestTitleChurn runs update/draw cycles into an offscreen image and checks
+// the window title was set only once (Draw used to set it every frame).
+func TestTitleChurn(t *testing.T) {
+	const frames = 120
+	g := NewGame(runtime.NumCPU())
+	g.SetTitle(WindowTitle)
+	screen := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	for i := 0; i < frames; i++ {
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+		g.Draw(screen)
+	}
+	if g.titleSets != 1 {
+		t.Errorf("window title set %d times in %d frames, want 1", g.titleSets, frames)
+	}
+}
+
+// TestAspect maps the corner and center pixels of square, wide and tall
+// frames and checks pixels stay square and the view stays centered.
+func TestAspect(t *testing.T) {
+	const cx, cy, size = -0.75, 0.0, 3.0
+	for _, d := range [][2]int{{640, 640}, {1280, 720}, {540, 960}} {
+		w, h := d[0], d[1]
+		left, top := toPlane(0, 0, w, h, cx, cy, size)
+		right, bottom := toPlane(w, h, w, h, cx, cy, size)
+		if got, want := (right-left)/(top-bottom), float64(w)/float64(h); math.Abs(got-want) > 1e-12 {
+			t.Errorf("%dx%d: view is %g wide and %g tall, aspect %g, want %g", w, h, right-left, top-bottom, got, want)
+		}
+		if x, y := toPlane(w/2, h/2, w, h, cx, cy, size); math.Abs(x-cx) > 1e-12 || math.Abs(y-cy) > 1e-12 {
+			t.Errorf("%dx%d: center pixel maps to %g%+gi, want %g%+gi", w, h, x, y, cx, cy)
+		}
+		x1, _ := toPlane(1, 0, w, h, cx, cy, size)
+		_, y1 := toPlane(0, 1, w, h, cx, cy, size)
+		if dx, dy := x1-left, top-y1; math.Abs(dx-dy) > 1e-15 {
+			t.Errorf("%dx%d: pixels are %g wide and %g tall", w, h, dx, dy)
+		}
+	}
+}
+
+// setBox renders the whole set into a w x h frame showing size of the
+// plane and returns the bounding box of its black (interior) pixels.
+func setBox(t *testing.T, w, h int, size float64) (bw, bh int) {
+	t.Helper()
+	g := NewGame(runtime.NumCPU())
+	g.resize(w, h)
+	g.size = size
+	g.updateOffscreen(g.centerX, g.centerY, g.size)
+	g.renderPending(time.Duration(math.MaxInt64))
+	x0, y0, x1, y1 := w, h, -1, -1
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			p := 4 * (i + j*w)
+			if g.offscreenPix[p] == 0 && g.offscreenPix[p+1] == 0 && g.offscreenPix[p+2] == 0 {
+				x0, y0, x1, y1 = min(x0, i), min(y0, j), max(x1, i), max(y1, j)
+			}
+		}
+	}
+	if x1 < 0 || x0 == 0 || y0 == 0 || x1 == w-1 || y1 == h-1 {
+		t.Fatalf("%dx%d: set not fully inside the frame (%d,%d)-(%d,%d)", w, h, x0, y0, x1, y1)
+	}
+	return x1 - x0 + 1, y1 - y0 + 1
+}
+
+// TestAspectRender renders the whole set into a square and a wide frame at
+// the same pixel size and checks it comes out with the same proportions.
+func TestAspectRender(t *testing.T) {
+	sw, sh := setBox(t, 640, 640, 3)
+	ww, wh := setBox(t, 1280, 720, 6)
+	if abs(ww-sw) > 1 || abs(wh-sh) > 1 {
+		t.Errorf("set is stretched: %dx%d pixels in a square frame, %dx%d in a wide one", sw, sh, ww, wh)
+	}
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,677 @@
+// Mandelbrot Interactive Viewer in Go using Ebiten
+// Author: Juan Arce & ChatGPT (Senior Software Engineer & Physicist)
+// Features: Mouse wheel zoom (to cursor), click & drag panning, smooth coloring, efficient rendering.
+
+// Package mandelbrotyes is the mandelbrotyes demo's explorer: smooth and
+// distance-estimate coloring with an iteration limit that follows the
+// zoom, an auto-dive toward bookmarks, and a deep zoom that renders by
+// perturbation around arbitrary-precision reference orbits. The demo only
+// reads flags and runs a Game.
+package mandelbrotyes
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// default window size (-width, -height); the window is resizable and
+	// the view keeps square pixels at any aspect ratio
+	ScreenWidth  = 800
+	ScreenHeight = 800
+	maxIt        = 256 // fixed iteration limit, and the floor in auto mode
+
+	// Auto mode adds iterPerOctave iterations for every halving of the view
+	// size below the default, up to maxItCap.
+	iterPerOctave = 48
+	maxItCap      = 4096
+)
+
+// Smooth color mapping based on normalized iteration count. Interior points
+// (it == limit) are colored by the caller.
+func color(it int, z complex128) (r, g, b byte) {
+	magZ := real(z)*real(z) + imag(z)*imag(z)
+	if magZ == 0 {
+		return 0, 0, 0
+	}
+	logMagZ := math.Log(magZ)
+	v := float64(it) + 1.0 - math.Log(logMagZ/2)/math.Log(2.0)
+	r = byte(math.Sin(0.1*v+0.0)*127 + 128)
+	g = byte(math.Sin(0.1*v+2.0)*127 + 128)
+	b = byte(math.Sin(0.1*v+4.0)*127 + 128)
+	return
+}
+
+// Distance-estimation coloring: brightness from the estimated distance to the
+// set, giving thin boundary outlines that stay sharp at any zoom.
+func deColor(it int, z, dz complex128, pixelSize float64) (r, g, b byte) {
+	absZ := cmplx.Abs(z)
+	absDz := cmplx.Abs(dz)
+	if absDz == 0 {
+		return 0, 0, 0
+	}
+	d := 2 * absZ * math.Log(absZ) / absDz
+	t := math.Min(math.Sqrt(d/(4*pixelSize)), 1)
+	v := byte((1 - t) * 255)
+	return v, v, v
+}
+
+type Game struct {
+	offscreen    *ebiten.Image
+	offscreenPix []byte
+	centerX      float64
+	centerY      float64
+	size         float64
+	width        int // frame size in pixels; the view is size*height/width tall
+	height       int
+	needsRedraw  bool
+	deMode       bool // distance-estimation coloring
+	skipInterior bool // cardioid/bulb test before iterating
+	autoIter     bool // scale the iteration limit with zoom depth
+	limit        int  // effective iteration limit for the current view
+
+	// Mouse interaction
+	prevMouseX float64
+	prevMouseY float64
+	dragging   bool
+
+	// In-frame controls/status overlay (H)
+	showHelp bool
+
+	// Auto-dive (V): zoom steadily toward diveTargets[diveIdx]; with DiveLoop
+	// (-diveloop) move on to the next bookmark at the precision floor
+	diving   bool
+	diveIdx  int
+	DiveLoop bool
+
+	// Past the float64 precision limit, render in blocks that are at least
+	// one representable step apart (L)
+	pixelDouble bool
+
+	// Deep zoom (-deep): the view center in arbitrary precision, rendered by
+	// perturbation around reference orbits; deepRefs, deepSkip and
+	// deepGlitches describe the last render
+	deep         bool
+	deepX, deepY *big.Float
+	deepRefs     int
+	deepSkip     int
+	deepGlitches int
+
+	// Window size from the last Layout call; Update resizes to it
+	layoutW, layoutH int
+
+	// Window title last handed to SetWindowTitle, and how many times it was
+	// called (TestTitleChurn)
+	title     string
+	titleSets int
+}
+
+// WindowTitle is the static window title; controls and status are drawn in
+// the frame instead.
+const WindowTitle = "Mandelbrot Explorer (Go + Ebiten)"
+
+// SetTitle updates the window title only when it actually changes.
+func (gm *Game) SetTitle(title string) {
+	if title == gm.title {
+		return
+	}
+	gm.title = title
+	gm.titleSets++
+	ebiten.SetWindowTitle(title)
+}
+
+// NewGame returns an explorer of the whole set; deep renders by
+// perturbation (-deep).
+func NewGame(deep bool) *Game {
+	g := &Game{
+		offscreen:    ebiten.NewImage(ScreenWidth, ScreenHeight),
+		offscreenPix: make([]byte, ScreenWidth*ScreenHeight*4),
+		width:        ScreenWidth,
+		height:       ScreenHeight,
+		size:         3.0,
+		needsRedraw:  true,
+		skipInterior: true,
+		limit:        maxIt,
+		showHelp:     true,
+		deep:         deep,
+	}
+	g.setCenter(-0.75, 0)
+	return g
+}
+
+// deepPrec is the big.Float precision for a deep-zoom center at the given
+// view size: enough mantissa bits to place a pixel, plus headroom.
+func deepPrec(size float64) uint {
+	return 64 + uint(math.Max(0, math.Log2(3.0/size)))
+}
+
+// setCenter moves the view center to x+yi.
+func (gm *Game) setCenter(x, y float64) {
+	gm.centerX, gm.centerY = x, y
+	if gm.deep {
+		gm.deepX = new(big.Float).SetPrec(deepPrec(gm.size)).SetFloat64(x)
+		gm.deepY = new(big.Float).SetPrec(deepPrec(gm.size)).SetFloat64(y)
+	}
+}
+
+// moveCenter shifts the view center by dx+dyi. In deep mode the shift is
+// added to the big.Float center, so it isn't lost below float64 precision;
+// centerX and centerY then follow as its nearest doubles.
+func (gm *Game) moveCenter(dx, dy float64) {
+	if !gm.deep {
+		gm.centerX += dx
+		gm.centerY += dy
+		return
+	}
+	if p := deepPrec(gm.size); p > gm.deepX.Prec() {
+		gm.deepX.SetPrec(p)
+		gm.deepY.SetPrec(p)
+	}
+	gm.deepX.Add(gm.deepX, big.NewFloat(dx))
+	gm.deepY.Add(gm.deepY, big.NewFloat(dy))
+	gm.centerX, _ = gm.deepX.Float64()
+	gm.centerY, _ = gm.deepY.Float64()
+}
+
+// diveTargets are the bookmarks auto-dive heads for, in order: Seahorse
+// Valley, a Misiurewicz point on the main antenna's side branch, c = i and
+// the tip of the antenna at c = -2. They are kept as decimal text so deep
+// mode can dive on them past float64 precision.
+var diveTargets = [][2]string{
+	{"-0.743643887037158704752191506114774", "0.131825904205311970493132056385139"},
+	{"-0.77568377", "0.13646737"},
+	{"0", "1"},
+	{"-2", "0"},
+}
+
+// diveTarget returns bookmark i rounded to prec bits.
+func diveTarget(i int, prec uint) (x, y *big.Float) {
+	x, _, _ = big.ParseFloat(diveTargets[i][0], 10, prec, big.ToNearestEven)
+	y, _, _ = big.ParseFloat(diveTargets[i][1], 10, prec, big.ToNearestEven)
+	return x, y
+}
+
+const (
+	diveRate    = 0.985 // size multiplier per frame: half the width every ~0.75s
+	diveSteer   = 0.08  // fraction of the way the center moves to the target per frame
+	diveMinUlps = 4     // stop diving when neighboring pixels are this few float64 steps apart
+)
+
+// pixelUlps is how many float64 steps (ulps) apart neighboring pixels are
+// around c = x+yi when each pixel is pixel wide in the plane. Below 1,
+// adjacent pixels map to the same double and the image turns blocky.
+func pixelUlps(pixel, x, y float64) float64 {
+	ulp := func(v float64) float64 {
+		v = math.Abs(v)
+		return math.Nextafter(v, math.Inf(1)) - v
+	}
+	return pixel / math.Max(ulp(x), ulp(y))
+}
+
+// maxPixelBlock caps the block size pixel-doubling grows to.
+const maxPixelBlock = 16
+
+// pixelBlock is the side of the square pixel blocks renderPixels computes
+// once each: 1 normally, or with pixel-doubling past the precision limit the
+// smallest power of two whose blocks sit at least one float64 step apart.
+func (gm *Game) pixelBlock() int {
+	if !gm.pixelDouble {
+		return 1
+	}
+	u := pixelUlps(gm.pixelSize(), gm.centerX, gm.centerY)
+	b := 1
+	for float64(b)*u < 1 && b < maxPixelBlock {
+		b *= 2
+	}
+	return b
+}
+
+// dive advances auto-dive by one frame: the view shrinks by diveRate while
+// the center eases toward the current bookmark. At the precision floor (or
+// minDeepSize in deep mode) it stops, or with DiveLoop starts over on the
+// next bookmark.
+func (gm *Game) dive() {
+	var dx, dy float64
+	if gm.deep {
+		tx, ty := diveTarget(gm.diveIdx, gm.deepX.Prec())
+		dx, _ = tx.Sub(tx, gm.deepX).Float64()
+		dy, _ = ty.Sub(ty, gm.deepY).Float64()
+	} else {
+		tx, ty := diveTarget(gm.diveIdx, 53)
+		x, _ := tx.Float64()
+		y, _ := ty.Float64()
+		dx, dy = x-gm.centerX, y-gm.centerY
+	}
+	gm.moveCenter(dx*diveSteer, dy*diveSteer)
+	gm.size *= diveRate
+	gm.needsRedraw = true
+	if gm.deep {
+		if gm.size > minDeepSize {
+			return
+		}
+	} else if pixelUlps(gm.pixelSize(), gm.centerX, gm.centerY) >= diveMinUlps {
+		return
+	}
+	if !gm.DiveLoop {
+		gm.diving = false
+		return
+	}
+	gm.diveIdx = (gm.diveIdx + 1) % len(diveTargets)
+	gm.size = 3.0
+}
+
+// iterations returns the iteration limit for the current view: maxIt, or in
+// auto mode a limit growing with log2 of the zoom (3.0/size), clamped to
+// [maxIt, maxItCap].
+func (gm *Game) iterations() int {
+	if !gm.autoIter {
+		return maxIt
+	}
+	n := maxIt + int(iterPerOctave*math.Log2(3.0/gm.size))
+	if n < maxIt {
+		return maxIt
+	}
+	if n > maxItCap {
+		return maxItCap
+	}
+	return n
+}
+
+// inCardioidOrBulb reports whether c = x+yi lies inside the main cardioid or
+// the period-2 bulb. Those points never escape, so they can be colored as
+// interior without iterating.
+func inCardioidOrBulb(x, y float64) bool {
+	xq := x - 0.25
+	q := xq*xq + y*y
+	if q*(q+xq) < 0.25*y*y {
+		return true
+	}
+	return (x+1)*(x+1)+y*y < 1.0/16
+}
+
+func (gm *Game) updateOffscreen() {
+	if gm.deep {
+		gm.renderDeep()
+	} else {
+		gm.renderPixels()
+	}
+	gm.offscreen.WritePixels(gm.offscreenPix)
+}
+
+// shade colors a pixel that escaped after it iterations; interior points
+// (it == limit) stay black.
+func (gm *Game) shade(it int, z, dz complex128) (r, g, b byte) {
+	if it >= gm.limit {
+		return 0, 0, 0
+	}
+	if gm.deMode {
+		return deColor(it, z, dz, gm.pixelSize())
+	}
+	return color(it, z)
+}
+
+// setPixel writes one opaque pixel into offscreenPix.
+func (gm *Game) setPixel(i, j int, r, g, b byte) {
+	p := 4 * (i + j*gm.width)
+	gm.offscreenPix[p+0] = r
+	gm.offscreenPix[p+1] = g
+	gm.offscreenPix[p+2] = b
+	gm.offscreenPix[p+3] = 0xFF
+}
+
+// pixelSize is the width (and height) of one pixel in the complex plane.
+func (gm *Game) pixelSize() float64 {
+	return gm.size / float64(gm.width)
+}
+
+// pixelOffset is pixel (i, j)'s offset from the view center in the complex
+// plane. Pixels are square, so the view is size*height/width tall.
+func (gm *Game) pixelOffset(i, j int) (dx, dy float64) {
+	w, h := float64(gm.width), float64(gm.height)
+	return (float64(i)/w - 0.5) * gm.size, (h/2 - float64(j)) * gm.size / w
+}
+
+// resize reallocates the offscreen for a width x height frame and queues a
+// redraw.
+func (gm *Game) resize(width, height int) {
+	gm.width, gm.height = width, height
+	gm.offscreen = ebiten.NewImage(width, height)
+	gm.offscreenPix = make([]byte, width*height*4)
+	gm.needsRedraw = true
+}
+
+// renderPixels fills offscreenPix for the current view, computing one pixel
+// per pixelBlock square and copying it over the block.
+func (gm *Game) renderPixels() {
+	block := gm.pixelBlock()
+	for j := 0; j < gm.height; j += block {
+		for i := 0; i < gm.width; i += block {
+			dx, dy := gm.pixelOffset(i, j)
+			x, y := dx+gm.centerX, dy+gm.centerY
+			c := complex(x, y)
+
+			z := complex(0, 0)
+			dz := complex(0, 0)
+			it := 0
+			if gm.skipInterior && inCardioidOrBulb(x, y) {
+				it = gm.limit
+			}
+			for ; it < gm.limit; it++ {
+				if gm.deMode {
+					dz = 2*z*dz + 1
+				}
+				z = z*z + c
+				if real(z)*real(z)+imag(z)*imag(z) > 4 {
+					break
+				}
+			}
+			r, g, b := gm.shade(it, z, dz)
+			for bj := j; bj < min(j+block, gm.height); bj++ {
+				for bi := i; bi < min(i+block, gm.width); bi++ {
+					gm.setPixel(bi, bj, r, g, b)
+				}
+			}
+		}
+	}
+}
+
+const (
+	// minDeepSize is as far as deep mode zooms: pixel offsets are still
+	// plain float64, which run out of exponent around 1e-308.
+	minDeepSize = 1e-290
+
+	// seriesTol bounds how large the series approximation's cubic term may
+	// grow against its lower-order terms before pixels iterate on their own.
+	seriesTol = 1e-6
+
+	// glitchTol flags a pixel as glitched once |z|^2 drops below this
+	// fraction of the reference's |Z|^2: its delta then carries more
+	// rounding error than signal.
+	glitchTol = 1e-6
+
+	// maxGlitchPasses caps how many extra reference orbits a deep render
+	// computes to re-render glitched pixels.
+	maxGlitchPasses = 8
+)
+
+// referenceOrbit iterates z = z^2 + c at c = cx+cyi in the centers'
+// precision and returns the orbit Z_0 = 0, Z_1, ... rounded to complex128,
+// ending at the first escaped value or after limit steps.
+func referenceOrbit(cx, cy *big.Float, limit int) []complex128 {
+	prec := cx.Prec()
+	zr := new(big.Float).SetPrec(prec)
+	zi := new(big.Float).SetPrec(prec)
+	zr2 := new(big.Float).SetPrec(prec)
+	zi2 := new(big.Float).SetPrec(prec)
+	t := new(big.Float).SetPrec(prec)
+	orbit := make([]complex128, 1, limit+1)
+	for n := 0; n < limit; n++ {
+		t.Mul(zr, zi)
+		t.Add(t, t)
+		zr2.Mul(zr, zr)
+		zi2.Mul(zi, zi)
+		zr.Sub(zr2, zi2)
+		zr.Add(zr, cx)
+		zi.Add(t, cy)
+		x, _ := zr.Float64()
+		y, _ := zi.Float64()
+		orbit = append(orbit, complex(x, y))
+		if x*x+y*y > 4 {
+			break
+		}
+	}
+	return orbit
+}
+
+// seriesSkip approximates every pixel's delta as a*dc + b*dc^2 + c*dc^3
+// for |dc| up to dmax, and returns how many iterations that skips along
+// with the coefficients there. It stops once the cubic term is no longer
+// negligible next to the linear and quadratic ones.
+func seriesSkip(orbit []complex128, dmax float64) (n int, a, b, c complex128) {
+	for ; n+2 < len(orbit); n++ {
+		z2 := 2 * orbit[n]
+		na := z2*a + 1
+		nb := z2*b + a*a
+		nc := z2*c + 2*a*b
+		cubic := cmplx.Abs(nc) * dmax * dmax
+		if cubic > seriesTol*cmplx.Abs(nb)/dmax || cubic > seriesTol*cmplx.Abs(na) {
+			break
+		}
+		a, b, c = na, nb, nc
+	}
+	return n, a, b, c
+}
+
+// perturb iterates one pixel as the delta d from the reference orbit,
+// starting at iteration n: d = 2*Z*d + d^2 + dc. It returns like the plain
+// loop (the escape iteration, the escaped z and its derivative) and whether
+// the pixel glitched, either by outliving the reference or by losing its
+// precision near a reference point close to zero.
+func perturb(orbit []complex128, dc, d complex128, n, limit int, de bool) (it int, z, dz complex128, glitch bool) {
+	for ; n < limit; n++ {
+		if n+1 >= len(orbit) {
+			return n, z, dz, true
+		}
+		if de {
+			dz = 2*(orbit[n]+d)*dz + 1
+		}
+		d = 2*orbit[n]*d + d*d + dc
+		ref := orbit[n+1]
+		z = ref + d
+		mag := real(z)*real(z) + imag(z)*imag(z)
+		if mag > 4 {
+			return n, z, dz, false
+		}
+		if mag < glitchTol*(real(ref)*real(ref)+imag(ref)*imag(ref)) {
+			return n, z, dz, true
+		}
+	}
+	return limit, z, dz, false
+}
+
+// renderDeep fills offscreenPix by perturbation: one reference orbit at the
+// big.Float center, float64 deltas for every pixel, and the series
+// approximation to skip the iterations they share. Glitched pixels are
+// re-rendered against a new reference taken at one of them, up to
+// maxGlitchPasses times; any left after that keep their glitched value.
+func (gm *Game) renderDeep() {
+	delta := func(k int) complex128 {
+		return complex(gm.pixelOffset(k%gm.width, k/gm.width))
+	}
+	orbit := referenceOrbit(gm.deepX, gm.deepY, gm.limit)
+	gm.deepRefs = 1
+
+	// The series has no derivative term, so DE coloring iterates from 0.
+	var a, b, c complex128
+	gm.deepSkip = 0
+	if !gm.deMode {
+		gm.deepSkip, a, b, c = seriesSkip(orbit, math.Hypot(gm.size, gm.size*float64(gm.height)/float64(gm.width))/2)
+	}
+
+	var glitched []int
+	for k := 0; k < gm.width*gm.height; k++ {
+		dc := delta(k)
+		it, z, dz, bad := perturb(orbit, dc, ((c*dc+b)*dc+a)*dc, gm.deepSkip, gm.limit, gm.deMode)
+		if bad {
+			glitched = append(glitched, k)
+			continue
+		}
+		r, g, b := gm.shade(it, z, dz)
+		gm.setPixel(k%gm.width, k/gm.width, r, g, b)
+	}
+
+	for pass := 0; len(glitched) > 0; pass++ {
+		// The new reference is a glitched pixel itself, which therefore
+		// can't glitch again: every pass makes progress.
+		rc := delta(glitched[len(glitched)/2])
+		rx := new(big.Float).SetPrec(gm.deepX.Prec()).Add(gm.deepX, big.NewFloat(real(rc)))
+		ry := new(big.Float).SetPrec(gm.deepY.Prec()).Add(gm.deepY, big.NewFloat(imag(rc)))
+		orbit = referenceOrbit(rx, ry, gm.limit)
+		gm.deepRefs++
+		last := pass == maxGlitchPasses-1
+		remaining := glitched[:0]
+		for _, k := range glitched {
+			it, z, dz, bad := perturb(orbit, delta(k)-rc, 0, 0, gm.limit, gm.deMode)
+			if bad {
+				remaining = append(remaining, k)
+				if !last {
+					continue
+				}
+			}
+			r, g, b := gm.shade(it, z, dz)
+			gm.setPixel(k%gm.width, k/gm.width, r, g, b)
+		}
+		glitched = remaining
+		if last {
+			break
+		}
+	}
+	gm.deepGlitches = len(glitched)
+}
+
+func (g *Game) Update() error {
+	if g.layoutW > 0 && (g.layoutW != g.width || g.layoutH != g.height) {
+		g.resize(g.layoutW, g.layoutH)
+	}
+
+	// Handle zoom (mouse wheel)
+	_, scrollY := ebiten.Wheel()
+	if scrollY != 0 {
+		mx, my := ebiten.CursorPosition()
+
+		// Mouse position relative to the center, in complex plane units
+		mouseX, mouseY := g.pixelOffset(mx, my)
+
+		zoomFactor := math.Pow(1.1, -scrollY) // smooth zoom
+		g.size *= zoomFactor
+		if g.deep && g.size < minDeepSize {
+			zoomFactor *= minDeepSize / g.size
+			g.size = minDeepSize
+		}
+		g.diving = false // manual input cancels auto-dive
+
+		// Zoom towards cursor (keep mouse position fixed in view)
+		g.moveCenter(mouseX*(1-zoomFactor), mouseY*(1-zoomFactor))
+
+		g.needsRedraw = true
+	}
+
+	// Handle panning (left mouse drag)
+	mx, my := ebiten.CursorPosition()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		if !g.dragging {
+			g.dragging = true
+			g.prevMouseX, g.prevMouseY = float64(mx), float64(my)
+		} else {
+			dx := float64(mx) - g.prevMouseX
+			dy := float64(my) - g.prevMouseY
+			g.prevMouseX, g.prevMouseY = float64(mx), float64(my)
+
+			// Translate movement into Mandelbrot coordinates
+			g.moveCenter(-dx*g.pixelSize(), dy*g.pixelSize())
+			g.needsRedraw = true
+			if dx != 0 || dy != 0 {
+				g.diving = false
+			}
+		}
+	} else {
+		g.dragging = false
+	}
+
+	// Toggle distance-estimation coloring
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		g.deMode = !g.deMode
+		g.needsRedraw = true
+	}
+
+	// Toggle zoom-dependent iteration limit
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		g.autoIter = !g.autoIter
+		g.needsRedraw = true
+	}
+
+	// Toggle the controls/status overlay
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
+		g.showHelp = !g.showHelp
+	}
+
+	// Reset view
+	if ebiten.IsKeyPressed(ebiten.KeyR) {
+		g.size = 3.0
+		g.setCenter(-0.75, 0)
+		g.needsRedraw = true
+		g.diving = false
+	}
+
+	// Toggle pixel-doubling past the precision limit
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		g.pixelDouble = !g.pixelDouble
+		g.needsRedraw = true
+	}
+
+	// Toggle auto-dive
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		g.diving = !g.diving
+	}
+	if g.diving {
+		g.dive()
+	}
+
+	if g.needsRedraw {
+		g.limit = g.iterations()
+		g.updateOffscreen()
+		g.needsRedraw = false
+	}
+	return nil
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	screen.DrawImage(g.offscreen, nil)
+
+	// Warn once neighboring pixels map to the same double: the blocks are
+	// float64 running out, not part of the set
+	if u := pixelUlps(g.pixelSize(), g.centerX, g.centerY); u < 1 && !g.deep {
+		msg := fmt.Sprintf("float64 precision exhausted: %.2f steps per pixel, image is blocky", u)
+		if g.pixelDouble {
+			msg += fmt.Sprintf(" (pixel-doubled x%d, L: off)", g.pixelBlock())
+		} else {
+			msg += " (L: pixel-double)"
+		}
+		ebitenutil.DebugPrintAt(screen, msg, 8, g.height-20)
+	}
+
+	if !g.showHelp {
+		return
+	}
+	auto := "off"
+	if g.autoIter {
+		auto = "on"
+	}
+	dive := "off"
+	if g.diving {
+		dive = fmt.Sprintf("bookmark %d/%d", g.diveIdx+1, len(diveTargets))
+	}
+	center := fmt.Sprintf("%.17g, %.17g", g.centerX, g.centerY)
+	if g.deep {
+		digits := int(float64(g.deepX.Prec())*math.Log10(2)) + 1
+		center = g.deepX.Text('g', digits) + ", " + g.deepY.Text('g', digits)
+		dive += fmt.Sprintf("\nDeep zoom: %d reference orbits, %d iterations skipped, %d glitched pixels", g.deepRefs, g.deepSkip, g.deepGlitches)
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf(
+		"Zoom: Mouse Wheel | Pan: Drag Left Mouse | DE: D | Reset: R | Help: H\nAuto maxIt (A): %s, maxIt %d\nDive (V): %s\nCenter: %s  Size: %.4g",
+		auto, g.limit, dive, center, g.size,
+	))
+}
+
+// Layout renders at the window's actual size; Update reallocates the
+// offscreen when it changes.
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	g.layoutW, g.layoutH = max(outsideWidth, 1), max(outsideHeight, 1)
+	return g.layoutW, g.layoutH
+}
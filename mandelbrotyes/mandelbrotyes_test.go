@@ -0,0 +1,371 @@
+package mandelbrotyes
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/hashes.txt instead of comparing against it")
+
+// homeGame is the default view at the default window size, rendered
+// without an offscreen image.
+func homeGame(skip bool) *Game {
+	return &Game{
+		offscreenPix: make([]byte, ScreenWidth*ScreenHeight*4),
+		width:        ScreenWidth,
+		height:       ScreenHeight,
+		centerX:      -0.75,
+		size:         3.0,
+		skipInterior: skip,
+		limit:        maxIt,
+	}
+}
+
+// TestInteriorSkip renders the default view with and without the
+// cardioid/bulb test and checks that the pixels match.
+func TestInteriorSkip(t *testing.T) {
+	plain, skipped := homeGame(false), homeGame(true)
+	plain.renderPixels()
+	skipped.renderPixels()
+	if !bytes.Equal(plain.offscreenPix, skipped.offscreenPix) {
+		t.Error("interior skip changed the rendered pixels")
+	}
+}
+
+// BenchmarkInteriorSkip times renders of the default view with and without
+// the cardioid/bulb test.
+func BenchmarkInteriorSkip(b *testing.B) {
+	for _, skip := range []bool{false, true} {
+		name := "full"
+		if skip {
+			name = "skip"
+		}
+		b.Run(name, func(b *testing.B) {
+			gm := homeGame(skip)
+			for i := 0; i < b.N; i++ {
+				gm.renderPixels()
+			}
+		})
+	}
+}
+
+// TestTitleChurn runs update/draw cycles into an offscreen image and checks
+// the window title was set only once (Draw used to set it every frame).
+func TestTitleChurn(t *testing.T) {
+	const frames = 120
+	g := NewGame(false)
+	g.SetTitle(WindowTitle)
+	screen := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	for i := 0; i < frames; i++ {
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+		g.Draw(screen)
+	}
+	if g.titleSets != 1 {
+		t.Errorf("window title set %d times in %d frames, want 1", g.titleSets, frames)
+	}
+}
+
+// hashView is one reference view for TestHashes: a center (decimal text,
+// so deep views can sit past float64 precision), a view width, an
+// iteration limit and the rendering options that change the math.
+type hashView struct {
+	name        string
+	x, y        string
+	size        float64
+	limit       int
+	de          bool // distance-estimation coloring
+	noSkip      bool // iterate the cardioid and bulb instead of skipping them
+	pixelDouble bool
+	deep        bool
+}
+
+// hashViews are rendered at hashWidth x hashHeight, which is not square so
+// the aspect handling is covered too. home-noskip must hash like home: the
+// interior skip may not change a pixel.
+var hashViews = []hashView{
+	{name: "home", x: "-0.75", y: "0", size: 3, limit: maxIt},
+	{name: "home-noskip", x: "-0.75", y: "0", size: 3, limit: maxIt, noSkip: true},
+	{name: "home-de", x: "-0.75", y: "0", size: 3, limit: maxIt, de: true},
+	{name: "seahorse", x: diveTargets[0][0], y: diveTargets[0][1], size: 1e-4, limit: 1024},
+	{name: "seahorse-de", x: diveTargets[0][0], y: diveTargets[0][1], size: 1e-4, limit: 1024, de: true},
+	{name: "antenna", x: diveTargets[3][0], y: diveTargets[3][1], size: 1e-3, limit: maxIt},
+	{name: "seahorse-blocky", x: diveTargets[0][0], y: diveTargets[0][1], size: 1e-14, limit: 4096, pixelDouble: true},
+	{name: "seahorse-deep", x: diveTargets[0][0], y: diveTargets[0][1], size: 1e-13, limit: 4096, deep: true},
+}
+
+const (
+	hashWidth  = 320
+	hashHeight = 240
+)
+
+// render draws the view offscreen and returns its pixels.
+func (v hashView) render() []byte {
+	gm := &Game{
+		offscreenPix: make([]byte, hashWidth*hashHeight*4),
+		width:        hashWidth,
+		height:       hashHeight,
+		size:         v.size,
+		limit:        v.limit,
+		deMode:       v.de,
+		skipInterior: !v.noSkip,
+		pixelDouble:  v.pixelDouble,
+		deep:         v.deep,
+	}
+	prec := deepPrec(v.size)
+	gm.deepX, _, _ = big.ParseFloat(v.x, 10, prec, big.ToNearestEven)
+	gm.deepY, _, _ = big.ParseFloat(v.y, 10, prec, big.ToNearestEven)
+	gm.centerX, _ = gm.deepX.Float64()
+	gm.centerY, _ = gm.deepY.Float64()
+	if v.deep {
+		gm.renderDeep()
+	} else {
+		gm.renderPixels()
+	}
+	return gm.offscreenPix
+}
+
+// pixelHash is an FNV-1a hash of a rendered frame.
+func pixelHash(pix []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(pix)
+	return h.Sum64()
+}
+
+// hashArch is GOARCH plus its instruction set level when the build
+// recorded one (GOAMD64, GOARM64, ...), e.g. "amd64/v1".
+func hashArch() string {
+	arch := runtime.GOARCH
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "GO"+strings.ToUpper(arch) {
+				arch += "/" + s.Value
+			}
+		}
+	}
+	return arch
+}
+
+// TestHashes renders every reference view and compares its pixel hash
+// against the "name hash" lines in testdata/hashes.txt, or rewrites the
+// file with -update. The deep view renders by perturbation and is skipped
+// with -short.
+//
+// The hashes are only portable within an architecture: Go may fuse x*y+z
+// into one multiply-add on arm64, ppc64, s390x or amd64 at GOAMD64=v3,
+// which changes the low bits of escape times and so the pixels. The file's
+// "arch" line records where it was generated, and the test skips
+// elsewhere.
+func TestHashes(t *testing.T) {
+	const path = "testdata/hashes.txt"
+	if *update {
+		var b strings.Builder
+		fmt.Fprintf(&b, "arch %s\n", hashArch())
+		for _, v := range hashViews {
+			fmt.Fprintf(&b, "%s %016x\n", v.name, pixelHash(v.render()))
+		}
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string]uint64)
+	arch := ""
+	for n, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		f := strings.Fields(line)
+		if len(f) != 2 {
+			t.Fatalf("%s:%d: want \"name hash\"", path, n+1)
+		}
+		if f[0] == "arch" {
+			arch = f[1]
+			continue
+		}
+		h, err := strconv.ParseUint(f[1], 16, 64)
+		if err != nil {
+			t.Fatalf("%s:%d: %v", path, n+1, err)
+		}
+		want[f[0]] = h
+	}
+	if arch == "" {
+		t.Fatalf("%s has no \"arch\" line", path)
+	} else if here := hashArch(); arch != here {
+		t.Skipf("%s was recorded on %s, running on %s; regenerate it here with -update to compare", path, arch, here)
+	}
+	if len(want) != len(hashViews) {
+		t.Fatalf("%s has %d views, want %d", path, len(want), len(hashViews))
+	}
+	for _, v := range hashViews {
+		t.Run(v.name, func(t *testing.T) {
+			w, ok := want[v.name]
+			if !ok {
+				t.Fatalf("%s has no hash for view %s", path, v.name)
+			}
+			if v.deep && testing.Short() {
+				t.Skip("deep render skipped in short mode")
+			}
+			if h := pixelHash(v.render()); h != w {
+				t.Errorf("hash %016x, want %016x", h, w)
+			}
+		})
+	}
+}
+
+// The deep zoom checks compare perturbation renders against the plain
+// float64 loop and against iterating single pixels directly in big.Float.
+//
+// Two pixels count as differing when a channel is more than deepColorTol
+// apart, more than float64 rounding in the smooth coloring explains. The
+// float64 comparison allows deepMaxDiffer such pixels. Of deepGrid x
+// deepGrid spot checks, deepMaxOff (1%) may be off: a pixel that stays
+// near the set's boundary for thousands of iterations amplifies the
+// rounding in its float64 delta until it escapes at a different iteration
+// than the big.Float orbit. At 1e-13 in Seahorse Valley one of the 400
+// does, after 3583 iterations instead of 3648. A render may also leave
+// deepMaxGlitched pixels glitched once its extra reference orbits run out.
+const (
+	deepColorTol    = 8
+	deepMaxDiffer   = ScreenWidth * ScreenHeight / 200
+	deepGrid        = 20
+	deepMaxOff      = deepGrid * deepGrid / 100
+	deepMaxGlitched = ScreenWidth * ScreenHeight / 1000
+)
+
+// deepRender renders bookmark at the given view size and iteration limit,
+// by perturbation when deep is set.
+func deepRender(bookmark int, size float64, limit int, deep bool) *Game {
+	gm := &Game{
+		offscreenPix: make([]byte, ScreenWidth*ScreenHeight*4),
+		width:        ScreenWidth,
+		height:       ScreenHeight,
+		size:         size,
+		limit:        limit,
+		deep:         deep,
+	}
+	x, y := diveTarget(bookmark, deepPrec(size))
+	gm.centerX, _ = x.Float64()
+	gm.centerY, _ = y.Float64()
+	gm.deepX, gm.deepY = x, y
+	if deep {
+		gm.renderDeep()
+	} else {
+		gm.renderPixels()
+	}
+	return gm
+}
+
+// colorDiffers reports whether two pixels are more than deepColorTol apart
+// in any channel.
+func colorDiffers(a, b []byte) bool {
+	for k := 0; k < 3; k++ {
+		if d := int(a[k]) - int(b[k]); d > deepColorTol || d < -deepColorTol {
+			return true
+		}
+	}
+	return false
+}
+
+// spotCheck returns how many of a deepGrid x deepGrid grid of gm's pixels
+// differ from iterating them directly in big.Float.
+func spotCheck(gm *Game) int {
+	bad := 0
+	for gj := 0; gj < deepGrid; gj++ {
+		for gi := 0; gi < deepGrid; gi++ {
+			i, j := (2*gi+1)*gm.width/(2*deepGrid), (2*gj+1)*gm.height/(2*deepGrid)
+			dx, dy := gm.pixelOffset(i, j)
+			x := new(big.Float).SetPrec(gm.deepX.Prec()).Add(gm.deepX, big.NewFloat(dx))
+			y := new(big.Float).SetPrec(gm.deepY.Prec()).Add(gm.deepY, big.NewFloat(dy))
+			orbit := referenceOrbit(x, y, gm.limit)
+			z := orbit[len(orbit)-1]
+			it := len(orbit) - 2
+			if real(z)*real(z)+imag(z)*imag(z) <= 4 {
+				it = gm.limit
+			}
+			r, g, b := gm.shade(it, z, 0)
+			p := 4 * (i + j*gm.width)
+			if colorDiffers([]byte{r, g, b}, gm.offscreenPix[p:p+3]) {
+				bad++
+			}
+		}
+	}
+	return bad
+}
+
+// colors counts the distinct colors in a frame.
+func colors(pix []byte) int {
+	seen := make(map[[3]byte]bool)
+	for p := 0; p < len(pix); p += 4 {
+		seen[[3]byte{pix[p], pix[p+1], pix[p+2]}] = true
+	}
+	return len(seen)
+}
+
+// TestDeepMatchesPlain renders Seahorse Valley at a zoom float64 still
+// resolves both by the plain loop and by perturbation and compares them.
+func TestDeepMatchesPlain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("deep render skipped in short mode")
+	}
+	plain := deepRender(0, 1e-9, 1024, false)
+	pert := deepRender(0, 1e-9, 1024, true)
+	differ := 0
+	for p := 0; p < len(plain.offscreenPix); p += 4 {
+		if colorDiffers(plain.offscreenPix[p:p+3], pert.offscreenPix[p:p+3]) {
+			differ++
+		}
+	}
+	if differ > deepMaxDiffer {
+		t.Errorf("%d pixels differ from the float64 render, want at most %d", differ, deepMaxDiffer)
+	}
+}
+
+// TestDeepSpotCheck renders bookmarks by perturbation and spot-checks a
+// grid of pixels against iterating them directly in big.Float: Seahorse
+// Valley deep enough that plain float64 goes wrong and pixels get flagged
+// as glitched and re-rendered against new reference orbits, and far past
+// float64 the Misiurewicz point c = i, whose spirals need few iterations
+// at any depth. There the plain loop collapses to one color, so the render
+// must also show at least minColors colors.
+func TestDeepSpotCheck(t *testing.T) {
+	for _, v := range []struct {
+		bookmark  int
+		size      float64
+		limit     int
+		minColors int
+		slow      bool // skipped with -short
+	}{
+		{0, 1e-13, 4096, 0, true},
+		{2, 1e-100, 1024, 500, false},
+	} {
+		t.Run(fmt.Sprintf("bookmark%d/%g", v.bookmark+1, v.size), func(t *testing.T) {
+			if v.slow && testing.Short() {
+				t.Skip("slow deep render skipped in short mode")
+			}
+			pert := deepRender(v.bookmark, v.size, v.limit, true)
+			if bad := spotCheck(pert); bad > deepMaxOff {
+				t.Errorf("%d of %d spot checks differ from direct big.Float iteration, want at most %d", bad, deepGrid*deepGrid, deepMaxOff)
+			}
+			if pert.deepGlitches > deepMaxGlitched {
+				t.Errorf("%d pixels left glitched, want at most %d", pert.deepGlitches, deepMaxGlitched)
+			}
+			if n := colors(pert.offscreenPix); n < v.minColors {
+				t.Errorf("render has only %d colors, want at least %d", n, v.minColors)
+			}
+		})
+	}
+}
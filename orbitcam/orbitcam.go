@@ -0,0 +1,60 @@
+// Package orbitcam is the slow orbiting camera shared by the 3D particle
+// demos: a steady yaw around the cloud and a gentle pitch swing, advanced by
+// elapsed seconds so the motion looks the same at any tick rate.
+package orbitcam
+
+import (
+	"flag"
+	"fmt"
+	"math"
+)
+
+// Defaults, matching the demos' original per-tick motion at 60 TPS (yaw
+// 0.004 per tick, pitch sin(tick*0.002)*0.15).
+const (
+	DefaultYawSpeed  = 0.004 * 60 // radians per second
+	DefaultPitchAmp  = 0.15       // radians
+	DefaultPitchFreq = 0.002 * 60 // pitch oscillation, radians per second
+)
+
+// Camera is the orbit state and its speeds. Yaw and Pitch are what the demos
+// rotate the world by before projecting.
+type Camera struct {
+	Yaw, Pitch float64
+
+	YawSpeed  float64 // radians per second (-orbit-speed)
+	PitchAmp  float64 // peak pitch in radians (-pitch-amp)
+	PitchFreq float64 // radians per second
+
+	elapsed float64 // seconds advanced so far
+}
+
+// New returns a camera with the default speeds.
+func New() *Camera {
+	return &Camera{YawSpeed: DefaultYawSpeed, PitchAmp: DefaultPitchAmp, PitchFreq: DefaultPitchFreq}
+}
+
+// RegisterFlags returns a default camera whose orbit speed and pitch
+// amplitude are set by -orbit-speed and -pitch-amp in the default flag set.
+// Call it before flag.Parse.
+func RegisterFlags() *Camera {
+	c := New()
+	flag.Float64Var(&c.YawSpeed, "orbit-speed", c.YawSpeed, "camera orbit speed in radians per second (0 = still, negative = reverse)")
+	flag.Float64Var(&c.PitchAmp, "pitch-amp", c.PitchAmp, "camera pitch swing amplitude in radians")
+	return c
+}
+
+// Validate rejects a pitch amplitude that would tip the camera over the pole.
+func (c *Camera) Validate() error {
+	if c.PitchAmp < 0 || c.PitchAmp >= math.Pi/2 {
+		return fmt.Errorf("-pitch-amp must be in [0, %.4f)", math.Pi/2)
+	}
+	return nil
+}
+
+// Update advances the camera by dt seconds.
+func (c *Camera) Update(dt float64) {
+	c.elapsed += dt
+	c.Yaw += c.YawSpeed * dt
+	c.Pitch = math.Sin(c.elapsed*c.PitchFreq) * c.PitchAmp
+}
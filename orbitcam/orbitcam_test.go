@@ -0,0 +1,53 @@
+package orbitcam
+
+import (
+	"math"
+	"testing"
+)
+
+// TestPerTickMotion steps the camera at 60 TPS and checks it follows the
+// demos' original per-tick motion: yaw 0.004 per tick and pitch
+// sin(tick*0.002)*0.15.
+func TestPerTickMotion(t *testing.T) {
+	c := New()
+	for tick := 1; tick <= 3600; tick++ {
+		c.Update(1.0 / 60)
+		yaw, pitch := 0.004*float64(tick), math.Sin(float64(tick)*0.002)*0.15
+		if math.Abs(c.Yaw-yaw) > 1e-9 || math.Abs(c.Pitch-pitch) > 1e-9 {
+			t.Fatalf("tick %d: camera at yaw %v pitch %v, want %v %v", tick, c.Yaw, c.Pitch, yaw, pitch)
+		}
+	}
+}
+
+// TestTickRate checks that stepping at 120 TPS for the same time lands
+// where 60 TPS does.
+func TestTickRate(t *testing.T) {
+	slow, fast := New(), New()
+	for tick := 0; tick < 600; tick++ {
+		slow.Update(1.0 / 60)
+		fast.Update(1.0 / 120)
+		fast.Update(1.0 / 120)
+	}
+	if math.Abs(fast.Yaw-slow.Yaw) > 1e-9 || math.Abs(fast.Pitch-slow.Pitch) > 1e-9 {
+		t.Errorf("120 TPS camera at yaw %v pitch %v, 60 TPS at %v %v", fast.Yaw, fast.Pitch, slow.Yaw, slow.Pitch)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		amp float64
+		ok  bool
+	}{
+		{0, true},
+		{DefaultPitchAmp, true},
+		{math.Pi/2 - 1e-3, true},
+		{math.Pi / 2, false},
+		{-0.1, false},
+	} {
+		cam := New()
+		cam.PitchAmp = c.amp
+		if err := cam.Validate(); (err == nil) != c.ok {
+			t.Errorf("Validate with pitch amplitude %v: %v", c.amp, err)
+		}
+	}
+}
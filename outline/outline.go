@@ -0,0 +1,119 @@
+// Package outline reads polylines from text files and walks them by arc
+// length, for demos that spawn particles along a shape. It has no ebiten
+// dependency.
+package outline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Point is a polyline vertex.
+type Point struct{ X, Y float64 }
+
+// Parse reads a polyline: one "x,y" (or "x y") vertex per line, with #
+// starting a comment. A walk runs from the first vertex to the last and
+// jumps back to the start, so an outline should repeat its first vertex at
+// the end to close.
+func Parse(r io.Reader) ([]Point, error) {
+	var pts []Point
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text, _, _ := strings.Cut(sc.Text(), "#")
+		f := strings.FieldsFunc(text, func(c rune) bool { return c == ',' || unicode.IsSpace(c) })
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 2 {
+			return nil, fmt.Errorf("line %d: want \"x,y\"", line)
+		}
+		x, errX := strconv.ParseFloat(f[0], 64)
+		y, errY := strconv.ParseFloat(f[1], 64)
+		if errX != nil || errY != nil || math.IsInf(x, 0) || math.IsInf(y, 0) || math.IsNaN(x) || math.IsNaN(y) {
+			return nil, fmt.Errorf("line %d: bad point %q", line, strings.TrimSpace(text))
+		}
+		pts = append(pts, Point{x, y})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return pts, nil
+}
+
+// Load reads a polyline file (see Parse).
+func Load(file string) ([]Point, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pts, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return pts, nil
+}
+
+// Path is a polyline prepared for walking by arc length.
+type Path struct {
+	Points []Point   // vertices, as fitted
+	cum    []float64 // arc length from Points[0] to each vertex
+}
+
+// Fit scales pts, keeping their aspect, to fill the fraction fill of a
+// w by h area around its center, so a shape can be drawn in any units.
+func Fit(pts []Point, w, h, fill float64) (*Path, error) {
+	if len(pts) < 2 {
+		return nil, fmt.Errorf("path needs at least 2 points, got %d", len(pts))
+	}
+	lo, hi := pts[0], pts[0]
+	for _, p := range pts {
+		lo = Point{math.Min(lo.X, p.X), math.Min(lo.Y, p.Y)}
+		hi = Point{math.Max(hi.X, p.X), math.Max(hi.Y, p.Y)}
+	}
+	scale := math.Min(fill*w/(hi.X-lo.X), fill*h/(hi.Y-lo.Y))
+	if math.IsInf(scale, 1) || math.IsNaN(scale) {
+		return nil, fmt.Errorf("path has no length")
+	}
+	p := &Path{}
+	for i, v := range pts {
+		q := Point{
+			w/2 + (v.X-(lo.X+hi.X)/2)*scale,
+			h/2 + (v.Y-(lo.Y+hi.Y)/2)*scale,
+		}
+		length := 0.0
+		if i > 0 {
+			prev := p.Points[i-1]
+			length = p.cum[i-1] + math.Hypot(q.X-prev.X, q.Y-prev.Y)
+		}
+		p.Points = append(p.Points, q)
+		p.cum = append(p.cum, length)
+	}
+	return p, nil
+}
+
+// Length is the path's total arc length.
+func (p *Path) Length() float64 { return p.cum[len(p.cum)-1] }
+
+// At returns the point at arc length s along the path, wrapped to its
+// length.
+func (p *Path) At(s float64) Point {
+	s = math.Mod(s, p.Length())
+	if s < 0 {
+		s += p.Length()
+	}
+	i := sort.SearchFloat64s(p.cum, s)
+	if i == 0 {
+		return p.Points[0]
+	}
+	a, b := p.Points[i-1], p.Points[i]
+	f := (s - p.cum[i-1]) / (p.cum[i] - p.cum[i-1])
+	return Point{a.X + (b.X-a.X)*f, a.Y + (b.Y-a.Y)*f}
+}
@@ -0,0 +1,88 @@
+package outline
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	pts, err := Parse(strings.NewReader("# unit square\n0,0\n1 0\n1, 1  # corner\n\n0,1\n0,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	if len(pts) != len(want) {
+		t.Fatalf("parsed %v, want %v", pts, want)
+	}
+	for i := range want {
+		if pts[i] != want[i] {
+			t.Errorf("point %d = %v, want %v", i, pts[i], want[i])
+		}
+	}
+	for _, bad := range []string{"0,0\n1\n", "0,0\n1,2,3\n", "x,1\n", "0,nan\n", "inf,0\n"} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func near(a, b Point) bool { return math.Hypot(a.X-b.X, a.Y-b.Y) < 1e-9 }
+
+// square is the unit square fitted to a 1280x720 area at fill 0.7.
+func square(t *testing.T) (p *Path, corner Point, side float64) {
+	t.Helper()
+	p, err := Fit([]Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}, 1280, 720, 0.7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	side = 0.7 * 720
+	return p, Point{640 - side/2, 360 - side/2}, side
+}
+
+// TestFit checks that a shape is scaled by its tighter axis and centered.
+func TestFit(t *testing.T) {
+	p, corner, side := square(t)
+	if math.Abs(p.Length()-4*side) > 1e-9 {
+		t.Errorf("square path length %.3f, want %.3f", p.Length(), 4*side)
+	}
+	if far := (Point{corner.X + side, corner.Y + side}); !near(p.Points[0], corner) || !near(p.Points[2], far) {
+		t.Errorf("square fitted to %v, want corners %v and %v", p.Points, corner, far)
+	}
+
+	wide, err := Fit([]Point{{-2, 5}, {2, 5}, {2, 6}}, 1280, 720, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wide.Points[1].X - wide.Points[0].X; math.Abs(got-640) > 1e-9 {
+		t.Errorf("4x1 shape spans %v px, want 640", got)
+	}
+
+	for _, pts := range [][]Point{nil, {{1, 1}}, {{1, 1}, {1, 1}}} {
+		if _, err := Fit(pts, 1280, 720, 0.7); err == nil {
+			t.Errorf("Fit(%v) succeeded", pts)
+		}
+	}
+}
+
+// TestAt checks interpolation along the square and wrapping around it in
+// both directions.
+func TestAt(t *testing.T) {
+	p, corner, side := square(t)
+	for _, c := range []struct {
+		s    float64
+		want Point
+	}{
+		{0, corner},
+		{side / 2, Point{corner.X + side/2, corner.Y}},
+		{side, Point{corner.X + side, corner.Y}},
+		{side * 2.5, Point{corner.X + side/2, corner.Y + side}},
+		{side * 4, corner},
+		{side * 4.25, Point{corner.X + side/4, corner.Y}},
+		{-side / 2, Point{corner.X, corner.Y + side/2}},
+	} {
+		if got := p.At(c.s); !near(got, c.want) {
+			t.Errorf("At(%.1f) = %v, want %v", c.s, got, c.want)
+		}
+	}
+}
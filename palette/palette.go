@@ -0,0 +1,194 @@
+// Package palette is the color-ramp code shared by the demos: gradients of
+// color stops sampled by a parameter in [0, 1], interpolated in RGB or HSV,
+// plus a few named presets and a parser for ramps given on the command line.
+package palette
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Space is the color space a Gradient interpolates in.
+type Space int
+
+const (
+	RGB Space = iota // straight per-channel blend; hues in between go gray
+	HSV              // hue, saturation and value; hue takes the shorter way round
+)
+
+// Stop is one color of a Gradient and its position.
+type Stop struct {
+	At    float64
+	Color color.RGBA
+}
+
+// Gradient is a color ramp: stops at increasing positions, sampled with
+// linear interpolation in Space and clamped at the ends.
+type Gradient struct {
+	Stops []Stop
+	Space Space
+}
+
+// Even returns a gradient with colors spaced evenly over [0, 1].
+func Even(space Space, colors ...color.RGBA) Gradient {
+	g := Gradient{Stops: make([]Stop, len(colors)), Space: space}
+	for i, c := range colors {
+		g.Stops[i] = Stop{At: float64(i) / float64(max(len(colors)-1, 1)), Color: c}
+	}
+	return g
+}
+
+// Sample returns the gradient's color at t. Below the first stop it is the
+// first color, above the last the last one. A gradient without stops is
+// transparent black everywhere.
+func (g Gradient) Sample(t float64) color.RGBA {
+	s := g.Stops
+	if len(s) == 0 {
+		return color.RGBA{}
+	}
+	if t <= s[0].At || math.IsNaN(t) {
+		return s[0].Color
+	}
+	for i := 1; i < len(s); i++ {
+		if t > s[i].At {
+			continue
+		}
+		a, b := s[i-1], s[i]
+		f := (t - a.At) / (b.At - a.At)
+		if g.Space == HSV {
+			return lerpHSV(a.Color, b.Color, f)
+		}
+		lerp := func(x, y uint8) uint8 {
+			return uint8(float64(x) + (float64(y)-float64(x))*f)
+		}
+		return color.RGBA{lerp(a.Color.R, b.Color.R), lerp(a.Color.G, b.Color.G), lerp(a.Color.B, b.Color.B), 255}
+	}
+	return s[len(s)-1].Color
+}
+
+// lerpHSV blends a toward b by f in HSV. A gray end (no saturation) has no
+// hue of its own and takes the other end's.
+func lerpHSV(a, b color.RGBA, f float64) color.RGBA {
+	h1, s1, v1 := ToHSV(a)
+	h2, s2, v2 := ToHSV(b)
+	if s1 == 0 {
+		h1 = h2
+	} else if s2 == 0 {
+		h2 = h1
+	}
+	dh := h2 - h1
+	if dh > 180 {
+		dh -= 360
+	} else if dh < -180 {
+		dh += 360
+	}
+	return FromHSV(h1+dh*f, s1+(s2-s1)*f, v1+(v2-v1)*f)
+}
+
+// ToHSV converts c to hue in degrees [0, 360) and saturation and value in
+// [0, 1].
+func ToHSV(c color.RGBA) (h, s, v float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	v = hi
+	d := hi - lo
+	if hi == 0 || d == 0 {
+		return 0, 0, v
+	}
+	s = d / hi
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
+
+// FromHSV converts hue in degrees (any value, wrapped) and saturation and
+// value in [0, 1] to an opaque color.
+func FromHSV(h, s, v float64) color.RGBA {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g = c, x
+	case h < 120:
+		r, g = x, c
+	case h < 180:
+		g, b = c, x
+	case h < 240:
+		g, b = x, c
+	case h < 300:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := v - c
+	to8 := func(f float64) uint8 { return uint8(math.Round((f + m) * 255)) }
+	return color.RGBA{to8(r), to8(g), to8(b), 255}
+}
+
+// Presets are the named gradients Parse also accepts.
+var Presets = map[string]Gradient{
+	"fire": Even(RGB,
+		color.RGBA{0, 0, 0, 255}, color.RGBA{160, 20, 0, 255}, color.RGBA{255, 120, 0, 255},
+		color.RGBA{255, 220, 60, 255}, color.RGBA{255, 255, 255, 255}),
+	"ice": Even(RGB,
+		color.RGBA{10, 20, 80, 255}, color.RGBA{40, 120, 220, 255}, color.RGBA{140, 230, 255, 255},
+		color.RGBA{255, 255, 255, 255}),
+	"rainbow": Even(HSV,
+		color.RGBA{255, 0, 0, 255}, color.RGBA{255, 255, 0, 255}, color.RGBA{0, 255, 0, 255},
+		color.RGBA{0, 255, 255, 255}, color.RGBA{0, 0, 255, 255}, color.RGBA{255, 0, 255, 255}),
+	// matplotlib's viridis at five points
+	"viridis": Even(RGB,
+		color.RGBA{0x44, 0x01, 0x54, 255}, color.RGBA{0x3b, 0x52, 0x8b, 255}, color.RGBA{0x21, 0x91, 0x8c, 255},
+		color.RGBA{0x5e, 0xc9, 0x62, 255}, color.RGBA{0xfd, 0xe7, 0x25, 255}),
+}
+
+// Names lists the presets in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(Presets))
+	for n := range Presets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads a preset name, or a comma-separated list of hex colors
+// ("0000ff,00ff00,ff0000") spaced evenly and blended in RGB.
+func Parse(s string) (Gradient, error) {
+	if g, ok := Presets[strings.TrimSpace(s)]; ok {
+		return g, nil
+	}
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 {
+		return Gradient{}, fmt.Errorf("gradient %q: need a preset (%s) or at least two colors", s, strings.Join(Names(), ", "))
+	}
+	colors := make([]color.RGBA, len(fields))
+	for i, f := range fields {
+		f = strings.TrimPrefix(strings.TrimSpace(f), "#")
+		v, err := strconv.ParseUint(f, 16, 32)
+		if len(f) != 6 || err != nil {
+			return Gradient{}, fmt.Errorf("gradient %q: bad color %q, want rrggbb", s, f)
+		}
+		colors[i] = color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+	}
+	return Even(RGB, colors...), nil
+}
@@ -0,0 +1,100 @@
+package palette
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+var (
+	black   = color.RGBA{0, 0, 0, 255}
+	white   = color.RGBA{255, 255, 255, 255}
+	red     = color.RGBA{255, 0, 0, 255}
+	green   = color.RGBA{0, 255, 0, 255}
+	blue    = color.RGBA{0, 0, 255, 255}
+	magenta = color.RGBA{255, 0, 255, 255}
+)
+
+// TestSample checks colors between uneven stops and clamping past the ends.
+func TestSample(t *testing.T) {
+	three := Gradient{Stops: []Stop{{At: 0.2, Color: black}, {At: 0.4, Color: white}, {At: 1, Color: red}}}
+	for _, c := range []struct {
+		t    float64
+		want color.RGBA
+	}{
+		{0.2, black}, {0.3, color.RGBA{127, 127, 127, 255}}, {0.4, white}, {0.7, color.RGBA{255, 127, 127, 255}}, {1, red},
+		{-5, black}, {0, black}, {1.5, red}, {math.NaN(), black},
+	} {
+		if got := three.Sample(c.t); got != c.want {
+			t.Errorf("three-stop gradient at %v: got %v, want %v", c.t, got, c.want)
+		}
+	}
+}
+
+func TestSampleDegenerate(t *testing.T) {
+	if got := (Gradient{}).Sample(0.5); got != (color.RGBA{}) {
+		t.Errorf("zero gradient sampled %v, want transparent black", got)
+	}
+	if got := Even(HSV).Sample(0.5); got != (color.RGBA{}) {
+		t.Errorf("gradient of no colors sampled %v, want transparent black", got)
+	}
+	for _, x := range []float64{0, 0.5, 1} {
+		if got := Even(RGB, green).Sample(x); got != green {
+			t.Errorf("one-color gradient at %v: got %v, want %v", x, got, green)
+		}
+	}
+}
+
+// TestHSV checks that HSV blending keeps hues saturated where RGB goes
+// through gray, takes the short way round the hue circle, and lets a gray
+// end take the other end's hue.
+func TestHSV(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		g    Gradient
+		want color.RGBA
+	}{
+		{"red-green RGB", Even(RGB, red, green), color.RGBA{127, 127, 0, 255}},
+		{"red-green HSV", Even(HSV, red, green), color.RGBA{255, 255, 0, 255}},
+		// the short way from red (0°) to magenta (300°) is backwards through 330°
+		{"red-magenta HSV", Even(HSV, red, magenta), color.RGBA{255, 0, 128, 255}},
+		// a gray end takes the other end's hue instead of swinging from red
+		{"white-blue HSV", Even(HSV, white, blue), color.RGBA{128, 128, 255, 255}},
+	} {
+		if got := c.g.Sample(0.5); got != c.want {
+			t.Errorf("%s midpoint %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, name := range Names() {
+		g := Presets[name]
+		for _, st := range g.Stops {
+			if h, s, v := ToHSV(st.Color); FromHSV(h, s, v) != st.Color {
+				t.Errorf("%s: %v does not survive an HSV round trip", name, st.Color)
+			}
+			if got := g.Sample(st.At); got != st.Color {
+				t.Errorf("%s at stop %v: got %v, want %v", name, st.At, got, st.Color)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("viridis"); err != nil {
+		t.Error(err)
+	}
+	g, err := Parse("00ffff, #ffffff,ffff00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.Sample(0.25), (color.RGBA{127, 255, 255, 255}); got != want {
+		t.Errorf("parsed ramp at 0.25: got %v, want %v", got, want)
+	}
+	for _, s := range []string{"00ff00", "", "00ff00,nothex", "00ff00,fff"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
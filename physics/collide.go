@@ -0,0 +1,310 @@
+package physics
+
+import "math"
+
+// Collision flash: a ball-ball hit flashes both balls toward white by the
+// velocity change it gave each (full at flashDeltaV), fading out over
+// flashFrames steps.
+const (
+	flashDeltaV = 30.0
+	flashFrames = 10
+)
+
+// flashBall starts or strengthens b's hit flash for a velocity change dv.
+func flashBall(b *Ball, dv float64) {
+	b.Flash = math.Max(b.Flash, math.Min(dv/flashDeltaV, 1))
+}
+
+// recordContact notes a resolved contact point for the overlay.
+func (w *World) recordContact(p Vector) {
+	if w.TrackContacts {
+		w.Contacts = append(w.Contacts, p)
+	}
+}
+
+func updatePosition(b *Ball, dt float64) {
+	b.Pos.Add(Vector{b.Vel.X * dt, b.Vel.Y * dt})
+}
+
+// Circle-circle collision detection
+func circlesCollided(b1, b2 *Ball) bool {
+	dx := b2.Pos.X - b1.Pos.X
+	dy := b2.Pos.Y - b1.Pos.Y
+	dist := math.Sqrt(dx*dx + dy*dy)
+	return dist < (b1.Radius + b2.Radius)
+}
+
+// Circle-circle collision response
+func (w *World) bounceBalls(b1, b2 *Ball) {
+	normal := Vector{b2.Pos.X - b1.Pos.X, b2.Pos.Y - b1.Pos.Y}
+	dist := normal.Length()
+	if dist == 0 {
+		return
+	}
+	n := normal.Normalized()
+
+	// relative velocity
+	rv := Vector{b2.Vel.X - b1.Vel.X, b2.Vel.Y - b1.Vel.Y}
+	velAlongNormal := rv.X*n.X + rv.Y*n.Y
+
+	if velAlongNormal > 0 {
+		return
+	}
+	w.recordContact(Vector{b1.Pos.X + n.X*b1.Radius, b1.Pos.Y + n.Y*b1.Radius})
+
+	k := 1/b1.Mass + 1/b2.Mass
+	impulse := -(1 + w.Restitution) * velAlongNormal
+	impulse /= k
+	// The impulse changes the pair's kinetic energy by j*vn + j^2*k/2; keep
+	// that at or below what restitution is meant to dissipate.
+	impulse *= impulseScale(impulse*velAlongNormal, impulse*impulse*k/2, w.restitutionLoss(velAlongNormal, k))
+
+	flashBall(b1, impulse/b1.Mass)
+	flashBall(b2, impulse/b2.Mass)
+
+	impulseVec := Vector{n.X * impulse, n.Y * impulse}
+	b1.Vel.X -= (impulseVec.X / b1.Mass)
+	b1.Vel.Y -= (impulseVec.Y / b1.Mass)
+	b2.Vel.X += (impulseVec.X / b2.Mass)
+	b2.Vel.Y += (impulseVec.Y / b2.Mass)
+
+	// positional correction (prevent sinking)
+	penetration := (b1.Radius + b2.Radius) - dist
+	correction := Vector{n.X * penetration / 2, n.Y * penetration / 2}
+	b1.Pos.X -= correction.X
+	b1.Pos.Y -= correction.Y
+	b2.Pos.X += correction.X
+	b2.Pos.Y += correction.Y
+}
+
+// energySlack is the relative rounding allowance in impulseScale, so an
+// impulse that exactly meets its energy budget is not trimmed.
+const energySlack = 1e-9
+
+// restitutionLoss is the kinetic energy change (zero or negative) a
+// collision with approach speed vn and inverse-mass sum k is allowed: with
+// restitution e the normal part of the relative motion keeps e^2 of its
+// energy. A restitution above 1 is not allowed to add energy.
+func (w *World) restitutionLoss(vn, k float64) float64 {
+	e := w.Restitution
+	return -math.Max(1-e*e, 0) * vn * vn / (2 * k)
+}
+
+// impulseScale returns the largest s in [0, 1] such that scaling a
+// collision's impulses by s changes kinetic energy by at most allowed, where
+// the unscaled change is s*lin + s*s*quad. Scaling keeps momentum. If no such
+// s exists it returns the s that removes the most energy.
+func impulseScale(lin, quad, allowed float64) float64 {
+	if quad <= 0 || lin+quad <= allowed+energySlack*quad {
+		return 1
+	}
+	disc := lin*lin + 4*quad*allowed
+	if disc < 0 {
+		return math.Max(0, math.Min(-lin/(2*quad), 1))
+	}
+	return math.Max(0, math.Min((-lin+math.Sqrt(disc))/(2*quad), 1))
+}
+
+// pegCollided reports whether b overlaps p.
+func pegCollided(b *Ball, p Peg) bool {
+	dx := p.Pos.X - b.Pos.X
+	dy := p.Pos.Y - b.Pos.Y
+	return dx*dx+dy*dy < (b.Radius+p.Radius)*(b.Radius+p.Radius)
+}
+
+// bouncePeg resolves a ball-peg collision with the bounceBalls math, taking
+// the peg's inverse mass as zero so only the ball moves.
+func (w *World) bouncePeg(b *Ball, p Peg) {
+	normal := Vector{p.Pos.X - b.Pos.X, p.Pos.Y - b.Pos.Y}
+	dist := normal.Length()
+	if dist == 0 {
+		return
+	}
+	n := normal.Normalized()
+
+	// the peg is at rest, so the relative velocity is just the ball's, reversed
+	velAlongNormal := -(b.Vel.X*n.X + b.Vel.Y*n.Y)
+	if velAlongNormal <= 0 {
+		k := 1 / b.Mass
+		impulse := -(1 + w.Restitution) * velAlongNormal / k
+		impulse *= impulseScale(impulse*velAlongNormal, impulse*impulse*k/2, w.restitutionLoss(velAlongNormal, k))
+		w.recordContact(Vector{b.Pos.X + n.X*b.Radius, b.Pos.Y + n.Y*b.Radius})
+		flashBall(b, impulse/b.Mass)
+		b.Vel.X -= n.X * impulse / b.Mass
+		b.Vel.Y -= n.Y * impulse / b.Mass
+	}
+
+	// positional correction: the peg doesn't move, so the ball takes it all
+	penetration := (b.Radius + p.Radius) - dist
+	b.Pos.X -= n.X * penetration
+	b.Pos.Y -= n.Y * penetration
+}
+
+// closestPointOnAABB clamps p to the wall's rectangle.
+func closestPointOnAABB(p Vector, w Wall) (x, y float64) {
+	x = math.Max(w.X, math.Min(p.X, w.X+w.W))
+	y = math.Max(w.Y, math.Min(p.Y, w.Y+w.H))
+	return x, y
+}
+
+// Wall collision. This needs to be slightly more robust to handle
+// the boundary *and* the internal structure.
+func (w *World) bounceWall(b *Ball, box Wall) {
+	e := w.Restitution
+
+	// Corner contact: the closest point on the box is one of its corners, so
+	// the contact normal is the diagonal from that corner to the ball center.
+	cx, cy := closestPointOnAABB(b.Pos, box)
+	outsideX := b.Pos.X < box.X || b.Pos.X > box.X+box.W
+	outsideY := b.Pos.Y < box.Y || b.Pos.Y > box.Y+box.H
+	if outsideX && outsideY {
+		d := Vector{b.Pos.X - cx, b.Pos.Y - cy}
+		dist := d.Length()
+		if dist == 0 || dist >= b.Radius {
+			return
+		}
+		n := d.Normalized()
+		if vn := b.Vel.X*n.X + b.Vel.Y*n.Y; vn < 0 {
+			b.Vel.X -= (1 + e) * vn * n.X
+			b.Vel.Y -= (1 + e) * vn * n.Y
+		}
+		w.recordContact(Vector{cx, cy})
+		b.Pos = Vector{cx + n.X*b.Radius, cy + n.Y*b.Radius}
+		return
+	}
+
+	// AABB (Axis-Aligned Bounding Box) collision check
+
+	// Check top edge of the wall (e.g., floor)
+	if b.Pos.Y+b.Radius > box.Y && b.Pos.Y+b.Radius < box.Y+box.H &&
+		b.Pos.X > box.X && b.Pos.X < box.X+box.W && b.Vel.Y > 0 {
+		w.recordContact(Vector{b.Pos.X, box.Y})
+		b.Pos.Y = box.Y - b.Radius
+		b.Vel.Y *= -e
+		return
+	}
+	// Check bottom edge of the wall (e.g., ceiling)
+	if b.Pos.Y-b.Radius < box.Y+box.H && b.Pos.Y-b.Radius > box.Y &&
+		b.Pos.X > box.X && b.Pos.X < box.X+box.W && b.Vel.Y < 0 {
+		w.recordContact(Vector{b.Pos.X, box.Y + box.H})
+		b.Pos.Y = box.Y + box.H + b.Radius
+		b.Vel.Y *= -e
+		return
+	}
+	// Check left edge of the wall
+	if b.Pos.X+b.Radius > box.X && b.Pos.X+b.Radius < box.X+box.W &&
+		b.Pos.Y > box.Y && b.Pos.Y < box.Y+box.H && b.Vel.X > 0 {
+		w.recordContact(Vector{box.X, b.Pos.Y})
+		b.Pos.X = box.X - b.Radius
+		b.Vel.X *= -e
+		return
+	}
+	// Check right edge of the wall
+	if b.Pos.X-b.Radius < box.X+box.W && b.Pos.X-b.Radius > box.X &&
+		b.Pos.Y > box.Y && b.Pos.Y < box.Y+box.H && b.Vel.X < 0 {
+		w.recordContact(Vector{box.X + box.W, b.Pos.Y})
+		b.Pos.X = box.X + box.W + b.Radius
+		b.Vel.X *= -e
+		return
+	}
+}
+
+// contact is an overlapping ball pair gathered for simultaneous resolution.
+type contact struct {
+	a, b        *Ball
+	n           Vector  // unit normal from a to b
+	penetration float64 // overlap depth
+	target      float64 // separating speed required along n (restitution)
+	impulse     float64 // accumulated impulse, never negative
+}
+
+// contactIterations bounds the sequential-impulse passes in resolveContacts.
+const contactIterations = 16
+
+// resolveContacts gathers every overlapping pair first and then solves them
+// together with accumulated, clamped impulses iterated to convergence, so a
+// chain of touching balls (Newton's cradle) passes momentum through the
+// middle regardless of the order the pairs were found in.
+//
+// Sequential impulses can hand a contact more separating speed than its own
+// approach speed paid for, so afterwards the impulses are scaled back if the
+// touched balls together gained more kinetic energy than restitution allows.
+func (w *World) resolveContacts(balls []*Ball) {
+	var contacts []contact
+	before := map[*Ball]Vector{} // velocities of the touched balls
+	allowed := 0.0
+	for i := 0; i < len(balls); i++ {
+		for j := i + 1; j < len(balls); j++ {
+			a, b := balls[i], balls[j]
+			if !ballsInteract(a, b) || !circlesCollided(a, b) {
+				continue
+			}
+			d := Vector{b.Pos.X - a.Pos.X, b.Pos.Y - a.Pos.Y}
+			dist := d.Length()
+			if dist == 0 {
+				continue
+			}
+			n := d.Normalized()
+			vn := (b.Vel.X-a.Vel.X)*n.X + (b.Vel.Y-a.Vel.Y)*n.Y
+			before[a], before[b] = a.Vel, b.Vel
+			allowed += w.restitutionLoss(math.Min(vn, 0), 1/a.Mass+1/b.Mass)
+			contacts = append(contacts, contact{
+				a: a, b: b, n: n,
+				penetration: (a.Radius + b.Radius) - dist,
+				target:      -w.Restitution * math.Min(vn, 0),
+			})
+		}
+	}
+
+	for it := 0; it < contactIterations; it++ {
+		converged := true
+		for k := range contacts {
+			c := &contacts[k]
+			vn := (c.b.Vel.X-c.a.Vel.X)*c.n.X + (c.b.Vel.Y-c.a.Vel.Y)*c.n.Y
+			d := (c.target - vn) / (1/c.a.Mass + 1/c.b.Mass)
+			next := math.Max(c.impulse+d, 0)
+			d = next - c.impulse
+			c.impulse = next
+			if math.Abs(d) > 1e-9 {
+				converged = false
+			}
+			c.a.Vel.X -= d * c.n.X / c.a.Mass
+			c.a.Vel.Y -= d * c.n.Y / c.a.Mass
+			c.b.Vel.X += d * c.n.X / c.b.Mass
+			c.b.Vel.Y += d * c.n.Y / c.b.Mass
+		}
+		if converged {
+			break
+		}
+	}
+
+	lin, quad := 0.0, 0.0
+	for _, b := range balls { // in slice order, so the sums replay exactly
+		v0, ok := before[b]
+		if !ok {
+			continue
+		}
+		dv := Vector{b.Vel.X - v0.X, b.Vel.Y - v0.Y}
+		lin += b.Mass * (v0.X*dv.X + v0.Y*dv.Y)
+		quad += b.Mass * dv.LengthSq() / 2
+	}
+	if s := impulseScale(lin, quad, allowed); s < 1 {
+		for b, v0 := range before {
+			b.Vel = Vector{v0.X + s*(b.Vel.X-v0.X), v0.Y + s*(b.Vel.Y-v0.Y)}
+		}
+		for k := range contacts {
+			contacts[k].impulse *= s
+		}
+	}
+
+	// positional correction (prevent sinking)
+	for _, c := range contacts {
+		w.recordContact(Vector{c.a.Pos.X + c.n.X*c.a.Radius, c.a.Pos.Y + c.n.Y*c.a.Radius})
+		flashBall(c.a, c.impulse/c.a.Mass)
+		flashBall(c.b, c.impulse/c.b.Mass)
+		c.a.Pos.X -= c.n.X * c.penetration / 2
+		c.a.Pos.Y -= c.n.Y * c.penetration / 2
+		c.b.Pos.X += c.n.X * c.penetration / 2
+		c.b.Pos.Y += c.n.Y * c.penetration / 2
+	}
+}
@@ -0,0 +1,130 @@
+package physics
+
+import "log"
+
+// setup places n balls by layout, drawing the random and stress layouts
+// from the world's generator, so the same seed always builds the same
+// scene.
+func (w *World) setup(n int, layout string) {
+	if layout == "grid" {
+		w.placeGrid(n)
+	} else if layout == "stress" {
+		w.placeStress(n)
+	} else {
+		// Create initial balls, redrawing positions that land in an obstacle
+		for i := 0; i < n; i++ {
+			var pos Vector
+			placed := false
+			for try := 0; try < maxPlacementTries && !placed; try++ {
+				pos = Vector{float64(w.rng.IntN(int(w.width)-40) + 20), float64(w.rng.IntN(int(w.height)/4) + 20)}
+				placed = !w.obstructed(pos, BallRadius)
+			}
+			if !placed {
+				log.Printf("random layout: only %d of %d balls fit", i, n)
+				return
+			}
+			b := &Ball{
+				Pos:    pos,
+				Vel:    Vector{float64(w.rng.IntN(10) - 5), float64(w.rng.IntN(10) - 5)},
+				Radius: BallRadius,
+				Mass:   1.0,
+				ID:     w.newBallID(),
+			}
+			w.Balls = append(w.Balls, b)
+		}
+	}
+}
+
+// maxPlacementTries bounds how often the random layout redraws a ball's
+// position before giving up on the rest.
+const maxPlacementTries = 100
+
+// obstructed reports whether a ball of radius r at p would overlap a wall
+// or a peg of the loaded level.
+func (w *World) obstructed(p Vector, r float64) bool {
+	for _, wall := range w.Walls {
+		x, y := closestPointOnAABB(p, wall)
+		if dx, dy := p.X-x, p.Y-y; dx*dx+dy*dy < r*r {
+			return true
+		}
+	}
+	for _, pg := range w.Pegs {
+		if dx, dy := p.X-pg.Pos.X, p.Y-pg.Pos.Y; dx*dx+dy*dy < (r+pg.Radius)*(r+pg.Radius) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeGrid lays n resting balls out in rows from the top-left, inside the
+// boundary walls and above y=500, skipping grid cells that overlap one of
+// the level's obstacles.
+func (w *World) placeGrid(n int) {
+	const (
+		wall    = 20.0
+		spacing = 3 * BallRadius
+		bottom  = 500.0
+	)
+	x0, y0 := wall+spacing/2, wall+spacing/2
+	cols := int((w.width - 2*wall) / spacing)
+	for i, cell := 0, 0; i < n; cell++ {
+		x := x0 + float64(cell%cols)*spacing
+		y := y0 + float64(cell/cols)*spacing
+		if y+BallRadius > bottom {
+			log.Printf("grid layout: only %d of %d balls fit", i, n)
+			return
+		}
+		if w.obstructed(Vector{X: x, Y: y}, BallRadius) {
+			continue
+		}
+		i++
+		w.Balls = append(w.Balls, &Ball{
+			Pos:    Vector{X: x, Y: y},
+			Radius: BallRadius,
+			Mass:   1.0,
+			ID:     w.newBallID(),
+		})
+	}
+}
+
+// Stress layout: balls packed stressGap apart (relative to their diameter)
+// with velocities up to stressSpeed in each axis, shrunk below BallRadius
+// when needed so n of them fit in the same area as placeGrid.
+const (
+	stressGap   = 1.1
+	stressSpeed = 20.0
+)
+
+// placeStress packs n balls into a tight grid above the internal obstacles,
+// with small random velocities, so the same n and seed always build the
+// same scene.
+func (w *World) placeStress(n int) {
+	const (
+		wall   = 20.0
+		bottom = 500.0
+	)
+	if n == 0 {
+		return
+	}
+	width, height := w.width-2*wall, bottom-wall
+	radius := BallRadius
+	cols := 0
+	for {
+		spacing := 2 * stressGap * radius
+		cols = int(width / spacing)
+		if cols*int(height/spacing) >= n {
+			break
+		}
+		radius *= 0.95
+	}
+	spacing := 2 * stressGap * radius
+	for i := 0; i < n; i++ {
+		w.Balls = append(w.Balls, &Ball{
+			Pos:    Vector{X: wall + spacing*(float64(i%cols)+0.5), Y: wall + spacing*(float64(i/cols)+0.5)},
+			Vel:    Vector{X: (w.rng.Float64()*2 - 1) * stressSpeed, Y: (w.rng.Float64()*2 - 1) * stressSpeed},
+			Radius: radius,
+			Mass:   1.0,
+			ID:     w.newBallID(),
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package physics
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// A level is a named obstacle layout placed inside the boundary walls of a
+// world of width w and height h.
+type level struct {
+	name      string
+	obstacles func(w, h float64) []Wall
+	pegs      func(w, h float64) []Peg // nil for levels without pegs
+}
+
+// levels lists the layouts in the order NextLevel cycles through them.
+var levels = []level{
+	{"classic", classicLevel, nil},
+	{"empty", func(w, h float64) []Wall { return nil }, nil},
+	{"funnel", funnelLevel, nil},
+	{"pachinko", pachinkoBins, pachinkoPegs},
+	{"maze", mazeLevel, nil},
+}
+
+// LevelNames returns the level names in cycling order.
+func LevelNames() []string {
+	names := make([]string, len(levels))
+	for i, l := range levels {
+		names[i] = l.name
+	}
+	return names
+}
+
+// NextLevel returns the level after name, wrapping around.
+func NextLevel(name string) string {
+	for i, l := range levels {
+		if l.name == name {
+			return levels[(i+1)%len(levels)].name
+		}
+	}
+	return levels[0].name
+}
+
+// LoadLevel replaces the walls and pegs with the boundary plus the named
+// level's obstacles. Balls caught inside a new obstacle are pushed out by the
+// next wall bounce.
+func (w *World) LoadLevel(name string) error {
+	for _, l := range levels {
+		if l.name != name {
+			continue
+		}
+		wallColor := color.RGBA{100, 100, 100, 255}
+		wallThickness := 20.0
+		w.Walls = []Wall{
+			// top
+			{X: 0, Y: 0, W: w.width, H: wallThickness, Color: wallColor},
+			// bottom
+			{X: 0, Y: w.height - wallThickness, W: w.width, H: wallThickness, Color: wallColor},
+			// left
+			{X: 0, Y: 0, W: wallThickness, H: w.height, Color: wallColor},
+			// right
+			{X: w.width - wallThickness, Y: 0, W: wallThickness, H: w.height, Color: wallColor},
+		}
+		w.Walls = append(w.Walls, l.obstacles(w.width, w.height)...)
+		w.Pegs = nil
+		if l.pegs != nil {
+			w.Pegs = l.pegs(w.width, w.height)
+		}
+		w.level = name
+		return nil
+	}
+	return fmt.Errorf("level %q: want one of %s", name, strings.Join(LevelNames(), ", "))
+}
+
+// classicLevel is the original layout: a gold shelf and a pillar.
+func classicLevel(w, h float64) []Wall {
+	gold := color.RGBA{200, 150, 0, 255}
+	return []Wall{
+		{X: 100, Y: 650, W: 350, H: 30, Color: gold}, // shelf
+		{X: 450, Y: 500, W: 50, H: 180, Color: gold}, // pillar
+	}
+}
+
+// funnelLevel stairs two slopes down toward an 80px gap in the middle,
+// with a catch tray underneath.
+func funnelLevel(w, h float64) []Wall {
+	const steps, stepW, stepH = 10, 34.0, 24.0
+	slope := color.RGBA{70, 130, 180, 255}
+	var ws []Wall
+	for i := 0; i < steps; i++ {
+		y := 280 + float64(i)*stepH
+		sw := 20 + float64(i+1)*stepW
+		ws = append(ws,
+			Wall{X: 20, Y: y, W: sw - 20, H: stepH, Color: slope},
+			Wall{X: w - sw, Y: y, W: sw - 20, H: stepH, Color: slope})
+	}
+	tray := color.RGBA{200, 150, 0, 255}
+	ws = append(ws,
+		Wall{X: 300, Y: 680, W: 200, H: 16, Color: tray},
+		Wall{X: 300, Y: 620, W: 16, H: 60, Color: tray},
+		Wall{X: 484, Y: 620, W: 16, H: 60, Color: tray})
+	return ws
+}
+
+// pachinkoPegs fills the middle of the world with rows of staggered pegs.
+func pachinkoPegs(w, h float64) []Peg {
+	const rows, spacing, radius = 7, 70.0, 6.0
+	pegColor := color.RGBA{190, 190, 210, 255}
+	var ps []Peg
+	for r := 0; r < rows; r++ {
+		y := 280 + float64(r)*50
+		offset := 55.0
+		if r%2 == 1 {
+			offset += spacing / 2
+		}
+		for x := offset; x < w-40; x += spacing {
+			ps = append(ps, Peg{Pos: Vector{x, y}, Radius: radius, Color: pegColor})
+		}
+	}
+	return ps
+}
+
+// pachinkoBins is the row of collection bins under the pachinko pegs.
+func pachinkoBins(w, h float64) []Wall {
+	bin := color.RGBA{200, 150, 0, 255}
+	var ws []Wall
+	for x := 100.0; x < w-40; x += 80 {
+		ws = append(ws, Wall{X: x - 3, Y: 680, W: 6, H: 100, Color: bin})
+	}
+	return ws
+}
+
+// mazeLevel zig-zags balls down through shelves with alternating gaps.
+func mazeLevel(w, h float64) []Wall {
+	const thickness, gap = 16.0, 120.0
+	shelf := color.RGBA{120, 170, 90, 255}
+	var ws []Wall
+	for i := 0; i < 4; i++ {
+		y := 260 + float64(i)*130
+		if i%2 == 0 {
+			ws = append(ws, Wall{X: 20, Y: y, W: w - 40 - gap, H: thickness, Color: shelf})
+		} else {
+			ws = append(ws, Wall{X: 20 + gap, Y: y, W: w - 40 - gap, H: thickness, Color: shelf})
+		}
+	}
+	// baffles between the shelves, alternating sides
+	ws = append(ws,
+		Wall{X: 250, Y: 276, W: thickness, H: 60, Color: shelf},
+		Wall{X: 530, Y: 406, W: thickness, H: 60, Color: shelf},
+		Wall{X: 250, Y: 536, W: thickness, H: 60, Color: shelf})
+	return ws
+}
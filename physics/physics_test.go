@@ -0,0 +1,550 @@
+package physics
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arcesoftware/GO_Examples/integrator"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/golden.txt instead of comparing against it")
+
+const (
+	dt           = 0.016
+	goldenFrames = 3000
+)
+
+// goldenScene is a fixed arrangement (no RNG) that exercises gravity, the
+// boundary walls, the shelf and ball-ball contacts.
+func goldenScene() ([]*Ball, []Wall) {
+	var bs []*Ball
+	for i := 0; i < 8; i++ {
+		bs = append(bs, &Ball{
+			Pos:    Vector{X: 120 + float64(i)*70, Y: 100 + float64(i%3)*40},
+			Vel:    Vector{X: float64(i%4-2) * 1.5, Y: float64(i%2) * 2},
+			Radius: BallRadius,
+			Mass:   1.0 + float64(i%3)*0.5,
+			ID:     uint64(i + 1),
+		})
+	}
+	ws := []Wall{
+		{X: 0, Y: 0, W: 800, H: 20},
+		{X: 0, Y: 780, W: 800, H: 20},
+		{X: 0, Y: 0, W: 20, H: 800},
+		{X: 780, Y: 0, W: 20, H: 800},
+		{X: 100, Y: 650, W: 350, H: 30},
+		{X: 450, Y: 500, W: 50, H: 180},
+	}
+	return bs, ws
+}
+
+// sceneWorld returns a world with the default settings simulating exactly
+// the given balls and walls.
+func sceneWorld(t testing.TB, bs []*Ball, ws []Wall) *World {
+	t.Helper()
+	opts := DefaultOptions()
+	opts.Balls = 0
+	w, err := NewWorld(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Balls, w.Walls, w.Pegs = bs, ws, nil
+	return w
+}
+
+// goldenArch is GOARCH plus its instruction set level when the build
+// recorded one (GOAMD64, GOARM64, ...), e.g. "amd64/v1".
+func goldenArch() string {
+	arch := runtime.GOARCH
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "GO"+strings.ToUpper(arch) {
+				arch += "/" + s.Value
+			}
+		}
+	}
+	return arch
+}
+
+// TestGolden runs the golden scene and compares every ball's final state
+// against testdata/golden.txt, or rewrites the file with -update.
+//
+// The values are written with exactly enough digits to round-trip, so on
+// the architecture that recorded them they must match bit for bit. They
+// are not portable beyond it: Go may fuse x*y+z into one multiply-add on
+// arm64, ppc64, s390x or amd64 at GOAMD64=v3, and 3000 steps of bouncing
+// grow those last-bit differences far past any fixed tolerance. The
+// file's "arch" line records where it was generated, and the test skips
+// elsewhere rather than fail on rounding.
+func TestGolden(t *testing.T) {
+	bs, ws := goldenScene()
+	w := sceneWorld(t, bs, ws)
+	for i := 0; i < goldenFrames; i++ {
+		w.Step(dt)
+	}
+	const path = "testdata/golden.txt"
+	if *update {
+		var b strings.Builder
+		fmt.Fprintf(&b, "arch %s\n", goldenArch())
+		for _, ball := range bs {
+			for k, v := range []float64{ball.Pos.X, ball.Pos.Y, ball.Vel.X, ball.Vel.Y} {
+				if k > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+			}
+			b.WriteByte('\n')
+		}
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arch, rest, ok := strings.Cut(string(data), "\n")
+	if f := strings.Fields(arch); !ok || len(f) != 2 || f[0] != "arch" {
+		t.Fatalf("%s: first line %q, want \"arch <GOARCH>\"", path, arch)
+	} else if here := goldenArch(); f[1] != here {
+		t.Skipf("%s was recorded on %s, running on %s; regenerate it here with -update to compare", path, f[1], here)
+	}
+	want := strings.Fields(rest)
+	if len(want) != 4*len(bs) {
+		t.Fatalf("%s has %d values, want %d", path, len(want), 4*len(bs))
+	}
+	for i, ball := range bs {
+		for k, got := range []float64{ball.Pos.X, ball.Pos.Y, ball.Vel.X, ball.Vel.Y} {
+			w, err := strconv.ParseFloat(want[4*i+k], 64)
+			if err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+			if got != w {
+				t.Errorf("ball %d %s: got %v, want %v", i, [4]string{"x", "y", "vx", "vy"}[k], got, w)
+			}
+		}
+	}
+}
+
+// TestOrder runs the golden scene plus a block of overlapping balls, which
+// pairwise resolution is sensitive to, with the balls in slice order and
+// again reversed and shuffled, under both contact solvers. Every ball must
+// end in exactly the same state.
+func TestOrder(t *testing.T) {
+	run := func(perm []int, simultaneous bool) map[uint64]Ball {
+		bs, ws := goldenScene()
+		for i := 0; i < 12; i++ {
+			bs = append(bs, &Ball{
+				Pos:    Vector{X: 300 + float64(i%4)*(2*BallRadius-2), Y: 300 + float64(i/4)*(2*BallRadius-2)},
+				Radius: BallRadius,
+				Mass:   1,
+				ID:     uint64(len(bs) + 1),
+			})
+		}
+		order := make([]*Ball, len(bs))
+		for i, k := range perm {
+			order[i] = bs[k]
+		}
+		w := sceneWorld(t, order, ws)
+		w.Simultaneous = simultaneous
+		for i := 0; i < goldenFrames; i++ {
+			w.Step(dt)
+		}
+		end := make(map[uint64]Ball, len(bs))
+		for _, b := range bs {
+			end[b.ID] = *b
+		}
+		return end
+	}
+	const n = 20
+	identity, reversed := make([]int, n), make([]int, n)
+	for i := range identity {
+		identity[i], reversed[i] = i, n-1-i
+	}
+	perms := [][]int{reversed, rand.New(rand.NewPCG(3, 4)).Perm(n)}
+	for _, simultaneous := range []bool{false, true} {
+		want := run(identity, simultaneous)
+		for _, perm := range perms {
+			for id, got := range run(perm, simultaneous) {
+				if w := want[id]; got.Pos != w.Pos || got.Vel != w.Vel {
+					t.Errorf("simultaneous=%v, order %v: ball %d ended at %v moving %v, want %v moving %v", simultaneous, perm, id, got.Pos, got.Vel, w.Pos, w.Vel)
+				}
+			}
+		}
+	}
+}
+
+// TestIsolation builds three worlds from the same seeded random layout,
+// runs one with different settings (no gravity, a flow, restitution 0, the
+// simultaneous solver, another integrator, extra spawned balls, shaking)
+// and verifies another matches the third, which ran alone, bit for bit,
+// with ball IDs counted per world.
+func TestIsolation(t *testing.T) {
+	newWorld := func() *World {
+		w, err := NewWorld(DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	a, b, alone := newWorld(), newWorld(), newWorld()
+	b.Gravity, b.Flow, b.Restitution = Vector{}, Vector{X: 30}, 0
+	b.Simultaneous, b.Integrator = true, integrator.VelocityVerlet
+	for i := 0; i < 5; i++ {
+		b.SpawnAt(Vector{X: 400, Y: 300})
+	}
+	for i := 0; i < 600; i++ {
+		if i%100 == 0 {
+			b.Shake(150)
+		}
+		a.Step(dt)
+		b.Step(dt)
+		alone.Step(dt)
+	}
+	if len(a.Balls) != len(alone.Balls) || a.lastBallID != alone.lastBallID {
+		t.Fatalf("world has %d balls (last ID %d), want %d (last ID %d)", len(a.Balls), a.lastBallID, len(alone.Balls), alone.lastBallID)
+	}
+	for i, ball := range a.Balls {
+		if w := alone.Balls[i]; ball.Pos != w.Pos || ball.Vel != w.Vel || ball.ID != w.ID {
+			t.Errorf("ball %d is at %v moving %v (ID %d), want %v moving %v (ID %d)", i, ball.Pos, ball.Vel, ball.ID, w.Pos, w.Vel, w.ID)
+		}
+	}
+	if b.lastBallID != a.lastBallID+5 {
+		t.Errorf("second world's last ball ID is %d, want %d", b.lastBallID, a.lastBallID+5)
+	}
+
+	// The same seed replays spawning and shaking exactly.
+	c := newWorld()
+	c.Gravity, c.Flow, c.Restitution = Vector{}, Vector{X: 30}, 0
+	c.Simultaneous, c.Integrator = true, integrator.VelocityVerlet
+	for i := 0; i < 5; i++ {
+		c.SpawnAt(Vector{X: 400, Y: 300})
+	}
+	for i := 0; i < 600; i++ {
+		if i%100 == 0 {
+			c.Shake(150)
+		}
+		c.Step(dt)
+	}
+	for i, ball := range c.Balls {
+		if w := b.Balls[i]; ball.Pos != w.Pos || ball.Vel != w.Vel {
+			t.Errorf("replayed ball %d is at %v moving %v, want %v moving %v", i, ball.Pos, ball.Vel, w.Pos, w.Vel)
+		}
+	}
+}
+
+// TestEnergy collides deeply overlapping balls, a pair and a pile, with
+// both solvers and restitutions up to above 1, and verifies kinetic energy
+// never increases. It then lets a pile packed into one spot push itself
+// apart without gravity and checks the energy step by step.
+func TestEnergy(t *testing.T) {
+	w := sceneWorld(t, nil, nil)
+	rng := rand.New(rand.NewPCG(1, 2))
+	pile := func(n int) []*Ball {
+		bs := make([]*Ball, n)
+		for i := range bs {
+			bs[i] = &Ball{
+				Pos:    Vector{400 + rng.Float64()*3, 400 + rng.Float64()*3},
+				Vel:    Vector{rng.Float64()*100 - 50, rng.Float64()*100 - 50},
+				Radius: BallRadius,
+				Mass:   0.5 + rng.Float64()*2,
+			}
+		}
+		return bs
+	}
+	solvers := []struct {
+		name  string
+		solve func([]*Ball)
+	}{
+		{"pairwise", func(bs []*Ball) {
+			for i := 0; i < len(bs); i++ {
+				for j := i + 1; j < len(bs); j++ {
+					if circlesCollided(bs[i], bs[j]) {
+						w.bounceBalls(bs[i], bs[j])
+					}
+				}
+			}
+		}},
+		{"simultaneous", w.resolveContacts},
+	}
+	for _, e := range []float64{0, 0.8, 1, 1.5} {
+		w.Restitution = e
+		for _, sv := range solvers {
+			for _, n := range []int{2, 8} {
+				for trial := 0; trial < 200; trial++ {
+					bs := pile(n)
+					before := KineticEnergy(bs)
+					sv.solve(bs)
+					if after := KineticEnergy(bs); after > before*(1+1e-9) {
+						t.Fatalf("%s, e=%g, %d balls: kinetic energy rose from %v to %v", sv.name, e, n, before, after)
+					}
+				}
+			}
+		}
+	}
+
+	box := []Wall{
+		{X: 0, Y: 0, W: 800, H: 20},
+		{X: 0, Y: 780, W: 800, H: 20},
+		{X: 0, Y: 0, W: 20, H: 800},
+		{X: 780, Y: 0, W: 20, H: 800},
+	}
+	for _, simultaneous := range []bool{false, true} {
+		bs := pile(30)
+		w := sceneWorld(t, bs, box)
+		w.Gravity, w.Simultaneous = Vector{}, simultaneous
+		ke := KineticEnergy(bs)
+		for i := 0; i < 300; i++ {
+			w.Step(dt)
+			next := KineticEnergy(bs)
+			if next > ke*(1+1e-9) {
+				t.Fatalf("simultaneous=%v, step %d: kinetic energy rose from %v to %v", simultaneous, i, ke, next)
+			}
+			ke = next
+		}
+	}
+}
+
+// TestLayers sends two balls head-on through each other, once on layers
+// that don't collide and once on the default layer, with both solvers, and
+// verifies the first pair never bounces while the second does.
+func TestLayers(t *testing.T) {
+	for _, simultaneous := range []bool{false, true} {
+		for _, ghost := range []bool{true, false} {
+			a := &Ball{Pos: Vector{X: 300, Y: 400}, Vel: Vector{X: 100}, Radius: BallRadius, Mass: 1}
+			b := &Ball{Pos: Vector{X: 500, Y: 400}, Vel: Vector{X: -100}, Radius: BallRadius, Mass: 1}
+			if ghost {
+				b.Layer, b.Mask = LayerGhost, LayerGhost|LayerWalls
+			}
+			w := sceneWorld(t, []*Ball{a, b}, nil)
+			w.Gravity, w.Simultaneous = Vector{}, simultaneous
+			bounced := false
+			for i := 0; i < 120; i++ {
+				w.Step(dt)
+				if a.Vel.X != 100 || b.Vel.X != -100 {
+					bounced = true
+					if ghost {
+						t.Fatalf("simultaneous=%v, step %d: balls on separate layers bounced (vx %v, %v)", simultaneous, i, a.Vel.X, b.Vel.X)
+					}
+				}
+			}
+			if ghost && a.Pos.X < b.Pos.X {
+				t.Errorf("simultaneous=%v: ghost balls did not pass each other", simultaneous)
+			}
+			if !ghost && !bounced {
+				t.Errorf("simultaneous=%v: balls on the same layer never bounced", simultaneous)
+			}
+		}
+	}
+}
+
+// TestCorner fires a ball at 45 degrees into the top-left corner of the
+// pillar and verifies it bounces back out instead of slipping past.
+func TestCorner(t *testing.T) {
+	pillar := Wall{X: 450, Y: 500, W: 50, H: 180}
+	b := &Ball{Pos: Vector{X: 400, Y: 450}, Vel: Vector{X: 100, Y: 100}, Radius: BallRadius, Mass: 1}
+	w := sceneWorld(t, []*Ball{b}, []Wall{pillar})
+	for i := 0; i < 120; i++ {
+		updatePosition(b, dt)
+		w.bounceWall(b, pillar)
+		cx, cy := closestPointOnAABB(b.Pos, pillar)
+		if d := (Vector{b.Pos.X - cx, b.Pos.Y - cy}); d.Length() < b.Radius-1e-9 {
+			t.Fatalf("step %d: ball at %v overlaps the pillar", i, b.Pos)
+		}
+	}
+	if b.Vel.X >= 0 || b.Vel.Y >= 0 {
+		t.Errorf("ball did not bounce back from the corner: velocity %v", b.Vel)
+	}
+}
+
+// TestPeg fires balls at a peg above and below its center and verifies
+// the first contact reflects the velocity about the contact normal, with
+// restitution e, and the ball is deflected away from the peg's side it hit.
+func TestPeg(t *testing.T) {
+	peg := Peg{Pos: Vector{X: 400, Y: 400}, Radius: 12}
+	for _, offset := range []float64{-8, 8} {
+		b := &Ball{Pos: Vector{X: 300, Y: 400 + offset}, Vel: Vector{X: 200}, Radius: BallRadius, Mass: 1}
+		w := sceneWorld(t, []*Ball{b}, nil)
+		w.Pegs = []Peg{peg}
+		bounced := false
+		for i := 0; i < 120; i++ {
+			updatePosition(b, dt)
+			if !pegCollided(b, peg) {
+				continue
+			}
+			if !bounced {
+				n := Vector{b.Pos.X - peg.Pos.X, b.Pos.Y - peg.Pos.Y}.Normalized()
+				vn := b.Vel.X*n.X + b.Vel.Y*n.Y
+				want := Vector{b.Vel.X - (1+w.Restitution)*vn*n.X, b.Vel.Y - (1+w.Restitution)*vn*n.Y}
+				w.bouncePeg(b, peg)
+				if math.Abs(b.Vel.X-want.X) > 1e-9 || math.Abs(b.Vel.Y-want.Y) > 1e-9 {
+					t.Errorf("offset %v: velocity after contact %v, want %v", offset, b.Vel, want)
+				}
+				bounced = true
+				continue
+			}
+			w.bouncePeg(b, peg)
+		}
+		if !bounced {
+			t.Errorf("offset %v: ball never reached the peg", offset)
+			continue
+		}
+		if b.Vel.X >= 0 || math.Signbit(b.Vel.Y) != math.Signbit(offset) || b.Vel.Y == 0 {
+			t.Errorf("offset %v: ball not deflected back and to its side: velocity %v", offset, b.Vel)
+		}
+		d := Vector{b.Pos.X - peg.Pos.X, b.Pos.Y - peg.Pos.Y}
+		if d.Length() < b.Radius+peg.Radius-1e-9 {
+			t.Errorf("offset %v: ball at %v overlaps the peg", offset, b.Pos)
+		}
+	}
+}
+
+// TestSettle drops a seeded random layout into the empty box and verifies
+// the scene pauses once, and only once, the balls have stayed calm for
+// SettleFrames steps; that a settled scene no longer moves; and that
+// waking it resumes stepping.
+//
+// Under the demo's gentle gravity, balls at the default restitution keep
+// visibly bouncing for close to two minutes whatever the threshold, so the
+// drop uses deader balls, which settle in about 2000 steps. The limit is
+// a minute at 60 steps a second.
+func TestSettle(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Balls = 10
+	opts.Level = "empty"
+	opts.Seed = 1
+	opts.Restitution = 0.4
+	w, err := NewWorld(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calm := 0
+	const limit = 60 * 60
+	step := 0
+	for ; step < limit && !w.Settled(); step++ {
+		w.Advance()
+		if KineticEnergy(w.Balls)/float64(len(w.Balls)) < opts.SettleEnergy {
+			calm++
+		} else {
+			calm = 0
+		}
+		if w.Settled() != (calm >= opts.SettleFrames) {
+			t.Fatalf("step %d: settled %v after %d calm steps", step, w.Settled(), calm)
+		}
+	}
+	if !w.Settled() {
+		t.Fatalf("scene still moving after %d steps (mean kinetic energy %.3g)", limit, KineticEnergy(w.Balls)/float64(len(w.Balls)))
+	}
+	t.Logf("settled after %d steps (%.0fs)", step, float64(step)*dt)
+
+	before := make([]Vector, len(w.Balls))
+	for i, b := range w.Balls {
+		before[i] = b.Pos
+	}
+	for i := 0; i < 60; i++ {
+		w.Advance()
+	}
+	for i, b := range w.Balls {
+		if b.Pos != before[i] {
+			t.Fatalf("ball %d moved from %v to %v while settled", i, before[i], b.Pos)
+		}
+	}
+
+	w.Wake()
+	w.Shake(150)
+	w.Advance()
+	if w.Settled() || w.CalmFrames() != 0 {
+		t.Fatalf("woken and shaken scene still settled (calm steps %d)", w.CalmFrames())
+	}
+	for i, b := range w.Balls {
+		if b.Pos != before[i] {
+			return
+		}
+	}
+	t.Error("no ball moved after waking")
+}
+
+// TestLayout builds the random and grid layouts on every level and
+// verifies all the balls were placed and none starts inside a wall or peg.
+func TestLayout(t *testing.T) {
+	for _, l := range levels {
+		for _, layout := range []string{"random", "grid"} {
+			opts := DefaultOptions()
+			opts.Balls, opts.MaxBalls = 250, 250
+			opts.Level, opts.Layout = l.name, layout
+			w, err := NewWorld(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(w.Balls) != opts.Balls {
+				t.Errorf("%s layout on %s: placed %d of %d balls", layout, l.name, len(w.Balls), opts.Balls)
+			}
+			for _, b := range w.Balls {
+				if w.obstructed(b.Pos, b.Radius) {
+					t.Errorf("%s layout on %s: ball %d at %v overlaps an obstacle", layout, l.name, b.ID, b.Pos)
+				}
+			}
+		}
+	}
+}
+
+// TestCradle lines up three touching balls with the first one moving into
+// the others and verifies the far ball picks up the momentum.
+func TestCradle(t *testing.T) {
+	const gap = 2*BallRadius - 0.01 // just overlapping so every pair is in contact
+	bs := []*Ball{
+		{Pos: Vector{X: 300, Y: 400}, Vel: Vector{X: 50}, Radius: BallRadius, Mass: 1},
+		{Pos: Vector{X: 300 + gap, Y: 400}, Radius: BallRadius, Mass: 1},
+		{Pos: Vector{X: 300 + 2*gap, Y: 400}, Radius: BallRadius, Mass: 1},
+	}
+	// Reverse the slice too: the result must not depend on pair order.
+	for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}} {
+		run := make([]*Ball, len(order))
+		for i, k := range order {
+			b := *bs[k]
+			run[i] = &b
+		}
+		sceneWorld(t, run, nil).resolveContacts(run)
+		first, far := run[slices.Index(order, 0)], run[slices.Index(order, 2)]
+		if far.Vel.X <= 0 {
+			t.Errorf("order %v: far ball did not move (vx=%v)", order, far.Vel.X)
+		}
+		if first.Vel.X >= far.Vel.X {
+			t.Errorf("order %v: moving ball kept its speed (vx=%v, far vx=%v)", order, first.Vel.X, far.Vel.X)
+		}
+		p := 0.0
+		for _, b := range run {
+			p += b.Mass * b.Vel.X
+		}
+		if math.Abs(p-50) > 1e-9 {
+			t.Errorf("order %v: momentum %v, want 50", order, p)
+		}
+	}
+}
+
+// TestLoadLevel checks every level loads and an unknown one is refused,
+// naming the choices.
+func TestLoadLevel(t *testing.T) {
+	w := sceneWorld(t, nil, nil)
+	for _, name := range LevelNames() {
+		if err := w.LoadLevel(name); err != nil || w.Level() != name {
+			t.Errorf("LoadLevel(%q) = %v, level %q", name, err, w.Level())
+		}
+	}
+	if err := w.LoadLevel("moon"); err == nil || !strings.Contains(err.Error(), "pachinko") {
+		t.Errorf("LoadLevel(moon) = %v, want an error listing the levels", err)
+	}
+	if got := NextLevel(LevelNames()[len(levels)-1]); got != levels[0].name {
+		t.Errorf("NextLevel wraps to %q, want %q", got, levels[0].name)
+	}
+}
@@ -0,0 +1,318 @@
+package physics
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/arcesoftware/GO_Examples/integrator"
+)
+
+// Options configures a world built by NewWorld.
+type Options struct {
+	Width, Height float64           // world size; the boundary walls line its edges
+	TimeStep      float64           // seconds of simulation per Advance
+	Balls         int               // initial balls, at most MaxBalls
+	Layout        string            // initial placement: "random", "grid" or "stress"
+	Seed          uint64            // seeds the layouts, shaking and spawned balls
+	MaxBalls      int               // spawning past this recycles the oldest ball
+	Restitution   float64           // coefficient of restitution
+	Gravity       Vector            // constant force on every ball
+	Simultaneous  bool              // resolve ball-ball contacts together instead of pair by pair
+	Integrator    integrator.Method // integration method
+	Density       float64           // relative density for mass-derived radii; 0 keeps fixed-size balls
+	Level         string            // obstacle layout, one of Levels
+	SettleEnergy  float64           // pause once the mean kinetic energy per ball stays below this; 0 never pauses
+	SettleFrames  int               // for this many steps
+}
+
+// DefaultOptions returns physicsgame's starting settings.
+//
+// SettleEnergy 5 pauses once the average ball moves at about 3 px/s, a
+// pixel every twenty steps, with bounces under half a pixel high. At 1 a
+// dropped random layout spent another minute or more sliding at speeds
+// nobody could see before it paused.
+func DefaultOptions() Options {
+	return Options{
+		Width:        800,
+		Height:       800,
+		TimeStep:     0.016,
+		Balls:        20,
+		Layout:       "random",
+		Seed:         1,
+		MaxBalls:     200,
+		Restitution:  0.8,
+		Gravity:      Vector{0, 9.8},
+		Integrator:   integrator.SemiImplicitEuler,
+		Level:        "classic",
+		SettleEnergy: 5,
+		SettleFrames: 120,
+	}
+}
+
+// World is one independent simulation. Everything a step reads or writes
+// lives here, including the random generator shaking and spawning draw
+// from, so worlds don't affect each other and a world built from the same
+// Options replays exactly.
+type World struct {
+	Balls []*Ball
+	Walls []Wall
+	Pegs  []Peg
+
+	Restitution  float64           // coefficient of restitution
+	Gravity      Vector            // constant force on every ball
+	Flow         Vector            // uniform flow (wind tunnel) pushing every ball
+	Integrator   integrator.Method // integration method
+	Simultaneous bool              // resolve ball-ball contacts together instead of pair by pair
+
+	// TrackContacts records the points of the contacts each step resolves
+	// in Contacts, for an overlay.
+	TrackContacts bool
+	Contacts      []Vector
+
+	// Advance stops stepping once the mean kinetic energy per ball has
+	// stayed below SettleEnergy for SettleFrames steps, until Wake; a
+	// SettleEnergy of 0 never settles.
+	SettleEnergy float64
+	SettleFrames int
+
+	width, height float64
+	timeStep      float64
+	level         string // name of the loaded obstacle layout
+	density       float64
+	rng           *rand.Rand
+
+	maxBalls    int    // spawning past this recycles the oldest ball
+	recycleNext int    // ring index of the oldest ball
+	lastBallID  uint64 // most recently assigned Ball.ID
+	byID        []*Ball
+
+	calmFrames int
+	settled    bool
+}
+
+// NewWorld builds a simulation from opts: the boundary walls and the
+// obstacles of opts.Level plus opts.Balls balls placed by opts.Layout.
+func NewWorld(opts Options) (*World, error) {
+	if !(opts.Width > 0 && opts.Height > 0) {
+		return nil, fmt.Errorf("world size %gx%g: want a positive width and height", opts.Width, opts.Height)
+	}
+	if !(opts.TimeStep > 0) {
+		return nil, fmt.Errorf("time step %g: want a positive step", opts.TimeStep)
+	}
+	if opts.Layout != "random" && opts.Layout != "grid" && opts.Layout != "stress" {
+		return nil, fmt.Errorf("layout %q: want random, grid or stress", opts.Layout)
+	}
+	if opts.MaxBalls < 1 {
+		return nil, fmt.Errorf("max balls %d: want at least 1", opts.MaxBalls)
+	}
+	if opts.SettleFrames < 1 {
+		return nil, fmt.Errorf("settle frames %d: want at least 1", opts.SettleFrames)
+	}
+	w := &World{
+		Balls:        make([]*Ball, 0, opts.MaxBalls),
+		Restitution:  opts.Restitution,
+		Gravity:      opts.Gravity,
+		Integrator:   opts.Integrator,
+		Simultaneous: opts.Simultaneous,
+		SettleEnergy: opts.SettleEnergy,
+		SettleFrames: opts.SettleFrames,
+		width:        opts.Width,
+		height:       opts.Height,
+		timeStep:     opts.TimeStep,
+		density:      opts.Density,
+		rng:          rand.New(rand.NewPCG(opts.Seed, opts.Seed)),
+		maxBalls:     opts.MaxBalls,
+	}
+	if err := w.LoadLevel(opts.Level); err != nil {
+		return nil, err
+	}
+	w.setup(min(opts.Balls, opts.MaxBalls), opts.Layout)
+	return w, nil
+}
+
+// TimeStep is the seconds of simulation one Advance covers.
+func (w *World) TimeStep() float64 { return w.timeStep }
+
+// MaxBalls is how many balls the world holds before spawning recycles.
+func (w *World) MaxBalls() int { return w.maxBalls }
+
+// Level is the name of the loaded obstacle layout.
+func (w *World) Level() string { return w.level }
+
+// newBallID returns a fresh ball ID for this world.
+func (w *World) newBallID() uint64 {
+	w.lastBallID++
+	return w.lastBallID
+}
+
+// applyForce applies f to a body of the given mass in state s over dt.
+func applyForce(s *integrator.State, f Vector, mass, dt float64) {
+	a := Vector{f.X / mass, f.Y / mass}
+	s.VX += a.X * dt
+	s.VY += a.Y * dt
+}
+
+// Step advances the simulation by dt: integrate, then resolve ball-wall and
+// ball-ball collisions.
+func (w *World) Step(dt float64) {
+	w.Contacts = w.Contacts[:0]
+	for _, b := range w.Balls {
+		b.PrevPos = b.Pos
+	}
+
+	// Integrate
+	for _, b := range w.Balls {
+		w.integrate(b, dt)
+		b.Flash = math.Max(b.Flash-1.0/flashFrames, 0)
+	}
+
+	// Handle ball-wall collisions (boundaries and internal structures)
+	for _, b := range w.Balls {
+		for _, wall := range w.Walls {
+			if hitsWall(b, &wall) {
+				w.bounceWall(b, wall)
+			}
+		}
+		for _, p := range w.Pegs {
+			if pegCollided(b, p) {
+				w.bouncePeg(b, p)
+			}
+		}
+	}
+
+	// Handle ball-ball collisions, pairs in (lower ID, higher ID) order so
+	// the result doesn't depend on where the balls sit in the slice
+	ordered := w.sortByID()
+	if w.Simultaneous {
+		w.resolveContacts(ordered)
+		return
+	}
+	for i := 0; i < len(ordered); i++ {
+		for j := i + 1; j < len(ordered); j++ {
+			if ballsInteract(ordered[i], ordered[j]) && circlesCollided(ordered[i], ordered[j]) {
+				w.bounceBalls(ordered[i], ordered[j])
+			}
+		}
+	}
+}
+
+// integrate advances b by dt with the world's integrator under gravity and
+// the flow.
+func (w *World) integrate(b *Ball, dt float64) {
+	s := integrator.State{X: b.Pos.X, Y: b.Pos.Y, VX: b.Vel.X, VY: b.Vel.Y}
+	w.Integrator.Step(&s, dt, func(s *integrator.State, dt float64) {
+		applyForce(s, w.Gravity, b.Mass, dt)
+		applyForce(s, w.Flow, b.Mass, dt)
+	})
+	b.Pos, b.Vel = Vector{s.X, s.Y}, Vector{s.VX, s.VY}
+}
+
+// sortByID returns the balls ordered by ID, in a buffer reused across steps.
+// Balls without an ID get one first, in slice order.
+func (w *World) sortByID() []*Ball {
+	w.byID = append(w.byID[:0], w.Balls...)
+	for _, b := range w.byID {
+		if b.ID == 0 {
+			b.ID = w.newBallID()
+		}
+	}
+	slices.SortFunc(w.byID, func(a, b *Ball) int { return cmp.Compare(a.ID, b.ID) })
+	return w.byID
+}
+
+// Advance steps the world by its time step unless the scene has settled,
+// then checks whether it just did.
+func (w *World) Advance() {
+	if w.settled {
+		return
+	}
+	w.Step(w.timeStep)
+	w.updateSettled()
+}
+
+// updateSettled counts consecutive steps with the mean kinetic energy per
+// ball below SettleEnergy and marks the scene settled after SettleFrames.
+func (w *World) updateSettled() {
+	if w.SettleEnergy <= 0 || len(w.Balls) == 0 || KineticEnergy(w.Balls)/float64(len(w.Balls)) >= w.SettleEnergy {
+		w.calmFrames = 0
+		return
+	}
+	w.calmFrames++
+	w.settled = w.calmFrames >= w.SettleFrames
+}
+
+// Settled reports whether Advance has stopped stepping a settled scene.
+func (w *World) Settled() bool { return w.settled }
+
+// CalmFrames is how many steps in a row the scene has been below
+// SettleEnergy.
+func (w *World) CalmFrames() int { return w.calmFrames }
+
+// Wake resumes a settled scene.
+func (w *World) Wake() {
+	w.settled = false
+	w.calmFrames = 0
+}
+
+// Shake kicks every ball with a random velocity of up to strength in a
+// random direction, like shaking the container.
+func (w *World) Shake(strength float64) {
+	for _, b := range w.Balls {
+		a := w.rng.Float64() * 2 * math.Pi
+		m := w.rng.Float64() * strength
+		b.Vel.Add(Vector{math.Cos(a) * m, math.Sin(a) * m})
+	}
+}
+
+// radiusForMass returns the radius of a disc of the given mass at the
+// configured density, with density 1 giving a mass-1 ball the default
+// BallRadius. Area scales with mass, so r grows as sqrt(mass).
+func (w *World) radiusForMass(mass float64) float64 {
+	if w.density <= 0 {
+		return BallRadius
+	}
+	return BallRadius * math.Sqrt(mass/w.density)
+}
+
+// SpawnAt adds a ball at p, moved inside the world if it would stick out,
+// with a small random velocity and, when the world has a density, a random
+// mass and matching size. It returns the ball.
+func (w *World) SpawnAt(p Vector) *Ball {
+	mass := 1.0
+	if w.density > 0 {
+		mass = 0.25 + w.rng.Float64()*3.75
+	}
+	r := w.radiusForMass(mass)
+	b := &Ball{
+		Pos: Vector{
+			X: math.Max(r, math.Min(p.X, w.width-r)),
+			Y: math.Max(r, math.Min(p.Y, w.height-r)),
+		},
+		Vel:    Vector{X: float64(w.rng.IntN(500)-250) / 100.0, Y: float64(w.rng.IntN(500)-250) / 100.0},
+		Radius: r,
+		Mass:   mass,
+	}
+	w.SpawnBall(b)
+	return b
+}
+
+// SpawnBall adds b, or once MaxBalls is reached replaces the oldest ball so
+// the simulation cost stays bounded.
+func (w *World) SpawnBall(b *Ball) {
+	if b.ID == 0 {
+		b.ID = w.newBallID()
+	}
+	if len(w.Balls) < w.maxBalls {
+		w.Balls = append(w.Balls, b)
+		return
+	}
+	if len(w.Balls) == 0 {
+		return
+	}
+	w.recycleNext %= len(w.Balls)
+	w.Balls[w.recycleNext] = b
+	w.recycleNext = (w.recycleNext + 1) % len(w.Balls)
+}
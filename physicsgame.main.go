@@ -1,242 +1,324 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"image/color"
 	"log"
 	"math"
-	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
-)
 
-// ============================
-// Basic Physics Structures
-// ============================
-
-type Vector struct {
-	X, Y float64
-}
-
-func (v *Vector) Add(o Vector) {
-	v.X += o.X
-	v.Y += o.Y
-}
-
-func (v *Vector) Scale(s float64) {
-	v.X *= s
-	v.Y *= s
-}
+	"github.com/arcesoftware/GO_Examples/integrator"
+	"github.com/arcesoftware/GO_Examples/palette"
+	"github.com/arcesoftware/GO_Examples/physics"
+)
 
-func (v Vector) LengthSq() float64 {
-	return v.X*v.X + v.Y*v.Y
-}
+const (
+	screenW = 800
+	screenH = 800
+)
 
-func (v Vector) Length() float64 {
-	return math.Sqrt(v.LengthSq())
+// ghostColor draws c at half opacity, marking balls on the ghost layer.
+func ghostColor(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{uint16(r / 2), uint16(g / 2), uint16(b / 2), uint16(a / 2)}
 }
 
-func (v Vector) Normalized() Vector {
-	l := v.Length()
-	if l == 0 {
-		return Vector{}
+// flashColor blends c toward white by the flash amount. Draw applies it on
+// top of whatever color the ball currently has.
+func flashColor(c color.Color, flash float64) color.Color {
+	if flash <= 0 {
+		return c
 	}
-	return Vector{v.X / l, v.Y / l}
-}
-
-// ============================
-// Ball and Wall Definitions
-// ============================
-
-type Ball struct {
-	Pos, Vel Vector
-	Radius   float64
-	Mass     float64
-	Color    color.Color
+	r, g, b, a := c.RGBA()
+	mix := func(v uint32) uint8 {
+		return uint8(float64(v>>8)*(1-flash) + 255*flash)
+	}
+	return color.RGBA{R: mix(r), G: mix(g), B: mix(b), A: uint8(a >> 8)}
 }
 
-type Wall struct {
-	X, Y, W, H float64
-	Color      color.Color
-}
+const (
+	flowStep = 0.2  // flow change per tick while an arrow key is held
+	maxFlow  = 40.0 // flow magnitude cap
+)
 
 // ============================
-// Simulation Parameters
+// Speed Color Ramp
 // ============================
 
-var (
-	balls   []*Ball
-	walls   []Wall
-	dt      = 0.016
-	e       = 0.8 // coefficient of restitution
-	gravity = Vector{0, 9.8}
-	screenW = 800
-	screenH = 800
+// defaultSpeedRamp reproduces the original ball coloring by normalized
+// kinetic energy: cyan at rest, white, then yellow at full speed.
+var defaultSpeedRamp = palette.Even(palette.RGB,
+	color.RGBA{0, 255, 255, 255},
+	color.RGBA{255, 255, 255, 255},
+	color.RGBA{255, 255, 0, 255},
 )
 
-// ============================
-// Physics Functions
-// ============================
+const (
+	minColorMaxSpeed = 1.0
+	colorMaxStep     = 1.25 // factor per - or = press
+	colorMaxFollow   = 0.05 // fraction of the gap closed per frame when calibrating
+)
 
-func applyForce(b *Ball, f Vector) {
-	a := Vector{f.X / b.Mass, f.Y / b.Mass}
-	b.Vel.Add(Vector{a.X * dt, a.Y * dt})
+// speedColor samples the speed ramp by the ball's kinetic energy relative
+// to a ball at colorMaxSpeed.
+func (g *Game) speedColor(b *physics.Ball) color.RGBA {
+	maxSpeedSq := g.colorMaxSpeed * g.colorMaxSpeed
+	return g.speedRamp.Sample(math.Min(b.Vel.LengthSq(), maxSpeedSq) / maxSpeedSq)
 }
 
-func updatePosition(b *Ball) {
-	b.Pos.Add(Vector{b.Vel.X * dt, b.Vel.Y * dt})
-}
+// Restitution overlay (E): every ball gets an outline colored by the
+// restitution its collisions use, and a legend strip maps the colors to
+// values with the current e marked. There is one e per world, so all
+// outlines share a color; , and . change it to compare bounciness live.
+var restitutionRamp = palette.Even(palette.RGB,
+	color.RGBA{220, 60, 60, 255}, // dead: no bounce
+	color.RGBA{240, 190, 40, 255},
+	color.RGBA{80, 220, 120, 255}, // perfectly elastic
+)
 
-// Circle-circle collision detection
-func circlesCollided(b1, b2 *Ball) bool {
-	dx := b2.Pos.X - b1.Pos.X
-	dy := b2.Pos.Y - b1.Pos.Y
-	dist := math.Sqrt(dx*dx + dy*dy)
-	return dist < (b1.Radius + b2.Radius)
+const (
+	restitutionStep = 0.05
+	outlineWidth    = 2.0
+)
+
+// restitutionColor is the outline color for a restitution coefficient.
+func restitutionColor(r float64) color.RGBA {
+	return restitutionRamp.Sample(r)
 }
 
-// Circle-circle collision response
-func bounceBalls(b1, b2 *Ball) {
-	normal := Vector{b2.Pos.X - b1.Pos.X, b2.Pos.Y - b1.Pos.Y}
-	dist := normal.Length()
-	if dist == 0 {
-		return
+// drawRestitutionLegend draws the ramp as a strip in the bottom-right
+// corner with labeled ends and a marker at the current e.
+func (g *Game) drawRestitutionLegend(screen *ebiten.Image) {
+	const w, h, steps = 200.0, 12.0, 50
+	x0, y0 := float64(screenW)-w-30, float64(screenH)-50
+	for i := 0; i < steps; i++ {
+		t := (float64(i) + 0.5) / steps
+		ebitenutil.DrawRect(screen, x0+w*float64(i)/steps, y0, w/steps+1, h, restitutionColor(t))
 	}
-	n := normal.Normalized()
-
-	// relative velocity
-	rv := Vector{b2.Vel.X - b1.Vel.X, b2.Vel.Y - b1.Vel.Y}
-	velAlongNormal := rv.X*n.X + rv.Y*n.Y
+	e := g.world.Restitution
+	mx := x0 + w*math.Min(math.Max(e, 0), 1)
+	ebitenutil.DrawRect(screen, mx-1, y0-4, 3, h+8, color.White)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("restitution e = %.2f (, .)", e), int(x0), int(y0)-20)
+	ebitenutil.DebugPrintAt(screen, "0", int(x0)-2, int(y0+h)+2)
+	ebitenutil.DebugPrintAt(screen, "1", int(x0+w)-4, int(y0+h)+2)
+}
 
-	if velAlongNormal > 0 {
-		return
+// calibrateColorMax eases colorMaxSpeed toward the fastest ball's speed, so
+// the ramp spans the speeds the current scene actually reaches.
+func (g *Game) calibrateColorMax() {
+	fastest := 0.0
+	for _, b := range g.world.Balls {
+		fastest = math.Max(fastest, b.Vel.LengthSq())
 	}
+	target := math.Max(math.Sqrt(fastest), minColorMaxSpeed)
+	g.colorMaxSpeed += (target - g.colorMaxSpeed) * colorMaxFollow
+}
 
-	impulse := -(1 + e) * velAlongNormal
-	impulse /= (1/b1.Mass + 1/b2.Mass)
-
-	impulseVec := Vector{n.X * impulse, n.Y * impulse}
-	b1.Vel.X -= (impulseVec.X / b1.Mass)
-	b1.Vel.Y -= (impulseVec.Y / b1.Mass)
-	b2.Vel.X += (impulseVec.X / b2.Mass)
-	b2.Vel.Y += (impulseVec.Y / b2.Mass)
+// ============================
+// Ebiten Game Loop
+// ============================
 
-	// positional correction (prevent sinking)
-	penetration := (b1.Radius + b2.Radius) - dist
-	correction := Vector{n.X * penetration / 2, n.Y * penetration / 2}
-	b1.Pos.X -= correction.X
-	b1.Pos.Y -= correction.Y
-	b2.Pos.X += correction.X
-	b2.Pos.Y += correction.Y
+// Game is one simulation and its window: the physics lives in world, and
+// everything else here only affects how it is drawn and driven.
+type Game struct {
+	world *physics.World
+
+	// speedRamp colors balls by normalized kinetic energy (-ramp);
+	// colorMaxSpeed is the speed mapped to its top (-colormax, - and =),
+	// which with autoColorMax (A) follows the fastest ball instead.
+	speedRamp     palette.Gradient
+	colorMaxSpeed float64
+	autoColorMax  bool
+
+	showRestitution bool // restitution overlay (E)
+
+	// interpolate draws balls between their previous and current physics
+	// positions (-interpolate, I), so frames that land between ticks don't
+	// stutter on a display faster than the tick rate.
+	interpolate bool
+
+	spawnGhosts   bool    // spawn new balls on the ghost layer (-ghosts, G)
+	shakeStrength float64 // largest velocity kick a shake gives a ball (-shake, [ and ])
+
+	// The physics runs at the world's fixed time step whatever the tick
+	// rate: Update adds the real time since the last one to acc and steps
+	// once per time step it holds, leaving the remainder for next time.
+	acc        float64
+	lastUpdate time.Time
 }
 
-// Wall collision. This needs to be slightly more robust to handle
-// the boundary *and* the internal structure.
-func bounceWall(b *Ball, w Wall) {
-	// AABB (Axis-Aligned Bounding Box) collision check
+// maxFrameTime caps the real time one Update may feed the accumulator, so a
+// stall (a dragged window, a debugger) doesn't replay seconds of steps.
+const maxFrameTime = 0.25
 
-	// Check top edge of the wall (e.g., floor)
-	if b.Pos.Y+b.Radius > w.Y && b.Pos.Y+b.Radius < w.Y+w.H &&
-		b.Pos.X > w.X && b.Pos.X < w.X+w.W && b.Vel.Y > 0 {
-		b.Pos.Y = w.Y - b.Radius
-		b.Vel.Y *= -e
-		return
-	}
-	// Check bottom edge of the wall (e.g., ceiling)
-	if b.Pos.Y-b.Radius < w.Y+w.H && b.Pos.Y-b.Radius > w.Y &&
-		b.Pos.X > w.X && b.Pos.X < w.X+w.W && b.Vel.Y < 0 {
-		b.Pos.Y = w.Y + w.H + b.Radius
-		b.Vel.Y *= -e
-		return
-	}
-	// Check left edge of the wall
-	if b.Pos.X+b.Radius > w.X && b.Pos.X+b.Radius < w.X+w.W &&
-		b.Pos.Y > w.Y && b.Pos.Y < w.Y+w.H && b.Vel.X > 0 {
-		b.Pos.X = w.X - b.Radius
-		b.Vel.X *= -e
-		return
-	}
-	// Check right edge of the wall
-	if b.Pos.X-b.Radius < w.X+w.W && b.Pos.X-b.Radius > w.X &&
-		b.Pos.Y > w.Y && b.Pos.Y < w.Y+w.H && b.Vel.X < 0 {
-		b.Pos.X = w.X + w.W + b.Radius
-		b.Vel.X *= -e
-		return
+// NewGame builds a simulation from opts with the default display settings.
+func NewGame(opts physics.Options) (*Game, error) {
+	w, err := physics.NewWorld(opts)
+	if err != nil {
+		return nil, err
 	}
+	return &Game{
+		world:         w,
+		speedRamp:     defaultSpeedRamp,
+		colorMaxSpeed: math.Sqrt(500),
+		interpolate:   true,
+		shakeStrength: 150,
+	}, nil
 }
 
-// getColorBySpeed generates a color based on the ball's speed.
-// Fast balls are Red (high kinetic energy), slow balls are Blue/Purple.
-func getColorBySpeed(b *Ball) color.RGBA {
-	maxSpeedSq := 500.0 // Max speed squared for mapping (adjustable)
-	speedSq := math.Min(b.Vel.LengthSq(), maxSpeedSq)
-
-	// Normalize speed (0.0 to 1.0)
-	ratio := speedSq / maxSpeedSq
-
-	// Map ratio to colors: Blue (0) -> Green/Yellow (0.5) -> Red (1)
-	r := uint8(math.Min(ratio*2*255, 255))
-	g := uint8(math.Min((1-math.Abs(ratio-0.5))*2*255, 255))
-	bVal := uint8(math.Min((1-ratio)*2*255, 255))
-
-	return color.RGBA{R: r, G: g, B: bVal, A: 255}
+// renderAlpha is how far the current frame is between the last physics step
+// and the next one, 0 to 1: the fraction of a time step left in the
+// accumulator.
+func (g *Game) renderAlpha() float64 {
+	if !g.interpolate {
+		return 1
+	}
+	return math.Min(g.acc/g.world.TimeStep(), 1)
 }
 
-// ============================
-// Ebiten Game Loop
-// ============================
-
-type Game struct{}
-
 func (g *Game) Update() error {
-	// 1. Handle user input
+	// 1. Handle user input; any of it wakes a settled scene
+	if anyInput() {
+		g.world.Wake()
+	}
 	g.handleInput()
 
-	// 2. Physics simulation step
-	for _, b := range balls {
-		applyForce(b, gravity)
-		updatePosition(b)
-		b.Color = getColorBySpeed(b) // Update color based on velocity
+	// 2. Physics simulation steps for the time since the last Update
+	now := time.Now()
+	if !g.lastUpdate.IsZero() {
+		g.acc += math.Min(now.Sub(g.lastUpdate).Seconds(), maxFrameTime)
 	}
-
-	// 3. Handle ball-wall collisions (boundaries and internal structures)
-	for _, b := range balls {
-		for _, w := range walls {
-			bounceWall(b, w)
+	g.lastUpdate = now
+	for dt := g.world.TimeStep(); g.acc >= dt; g.acc -= dt {
+		if g.world.Settled() {
+			g.acc = 0
+			break
 		}
-	}
-
-	// 4. Handle ball-ball collisions
-	for i := 0; i < len(balls); i++ {
-		for j := i + 1; j < len(balls); j++ {
-			if circlesCollided(balls[i], balls[j]) {
-				bounceBalls(balls[i], balls[j])
-			}
+		g.world.Advance()
+		if g.autoColorMax {
+			g.calibrateColorMax()
 		}
 	}
 
 	return nil
 }
 
+// anyInput reports whether a key is held or a mouse button or touch was
+// just pressed.
+func anyInput() bool {
+	return len(inpututil.AppendPressedKeys(nil)) > 0 ||
+		inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) ||
+		inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) ||
+		len(inpututil.AppendJustPressedTouchIDs(nil)) > 0
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
+	w := g.world
+
 	// Draw the background
 	screen.Fill(color.RGBA{20, 20, 40, 255}) // Dark blue background
 
+	// Draw the flow field as a grid of arrows behind everything else
+	if w.Flow.LengthSq() > 0 {
+		g.drawFlowArrows(screen)
+	}
+
 	// Draw the walls (boundaries and internal)
-	for _, w := range walls {
+	for _, wall := range w.Walls {
 		// Use ebitenutil.DrawRect for simple drawing of walls
-		ebitenutil.DrawRect(screen, w.X, w.Y, w.W, w.H, w.Color)
+		ebitenutil.DrawRect(screen, wall.X, wall.Y, wall.W, wall.H, wall.Color)
+	}
+	for _, p := range w.Pegs {
+		ebitenutil.DrawCircle(screen, p.Pos.X, p.Pos.Y, p.Radius, p.Color)
 	}
 
-	// Draw the balls
-	for _, b := range balls {
+	// Draw the balls, interpolated from the previous step toward the current
+	// one (a tick behind the physics, but evenly spaced)
+	alpha := g.renderAlpha()
+	for _, b := range w.Balls {
+		x := b.PrevPos.X + (b.Pos.X-b.PrevPos.X)*alpha
+		y := b.PrevPos.Y + (b.Pos.Y-b.PrevPos.Y)*alpha
+		c := flashColor(g.speedColor(b), b.Flash)
+		if layer, _ := b.CollisionBits(); layer&physics.LayerGhost != 0 {
+			c = ghostColor(c)
+		}
+		if g.showRestitution {
+			ebitenutil.DrawCircle(screen, x, y, b.Radius+outlineWidth, restitutionColor(w.Restitution))
+		}
 		// Use ebitenutil.DrawCircle for the balls (easy to use)
-		ebitenutil.DrawCircle(screen, b.Pos.X, b.Pos.Y, b.Radius, b.Color)
+		ebitenutil.DrawCircle(screen, x, y, b.Radius, c)
+	}
+
+	// Contact overlay: a small cross at every contact resolved this step
+	if w.TrackContacts {
+		markerColor := color.RGBA{255, 60, 60, 255}
+		for _, p := range w.Contacts {
+			ebitenutil.DrawLine(screen, p.X-4, p.Y-4, p.X+4, p.Y+4, markerColor)
+			ebitenutil.DrawLine(screen, p.X-4, p.Y+4, p.X+4, p.Y-4, markerColor)
+		}
+	}
+
+	if g.showRestitution {
+		g.drawRestitutionLegend(screen)
+	}
+
+	if w.Settled() {
+		msg := "SETTLED - any input resumes"
+		ebitenutil.DebugPrintAt(screen, msg, (screenW-len(msg)*6)/2, screenH/2-8)
 	}
 
 	// Draw info text
-	ebitenutil.DebugPrint(screen, "Balls: %d | Click/Tap to add ball")
+	contactInfo := "\nContacts overlay: C"
+	if w.TrackContacts {
+		contactInfo = fmt.Sprintf("\nCollisions this step: %d (C)", len(w.Contacts))
+	}
+	mode := "pairwise"
+	if w.Simultaneous {
+		mode = "simultaneous"
+	}
+	spawnLayer := "main"
+	if g.spawnGhosts {
+		spawnLayer = "ghost (passes through main)"
+	}
+	meanKE := 0.0
+	if len(w.Balls) > 0 {
+		meanKE = physics.KineticEnergy(w.Balls) / float64(len(w.Balls))
+	}
+	calibration := "manual"
+	if g.autoColorMax {
+		calibration = "auto"
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("Balls: %d/%d | Click/Tap to add ball | Contacts: %s (S)\nFlow (arrows, 0 = off): (%.1f, %.1f) |%.1f|%s\nShake (Space, [ ]): %.0f\nColor max speed (- =): %.1f, %s (A) | Interpolate (I): %v\nSpawn layer (G): %s\nRestitution (, .): %.2f | overlay (E): %v\nIntegrator (M): %s | Level (L): %s\nKE/ball: %.3g | settle pause below %.2g for %d frames (%d calm)",
+		len(w.Balls), w.MaxBalls(), mode, w.Flow.X, w.Flow.Y, w.Flow.Length(), contactInfo, g.shakeStrength, g.colorMaxSpeed, calibration, g.interpolate, spawnLayer, w.Restitution, g.showRestitution, w.Integrator, w.Level(), meanKE, w.SettleEnergy, w.SettleFrames, w.CalmFrames()))
+}
+
+// drawFlowArrows draws arrows along the flow direction, scaled by its strength.
+func (g *Game) drawFlowArrows(screen *ebiten.Image) {
+	const spacing = 80.0
+	arrowColor := color.RGBA{60, 60, 100, 255}
+	flow := g.world.Flow
+	n := flow.Normalized()
+	length := 10 + 40*math.Min(flow.Length()/maxFlow, 1)
+	for y := spacing / 2; y < float64(screenH); y += spacing {
+		for x := spacing / 2; x < float64(screenW); x += spacing {
+			tx, ty := x+n.X*length/2, y+n.Y*length/2
+			ebitenutil.DrawLine(screen, x-n.X*length/2, y-n.Y*length/2, tx, ty, arrowColor)
+			// arrow head: two short strokes back from the tip at ±30°
+			for _, a := range []float64{math.Pi / 6, -math.Pi / 6} {
+				c, s := math.Cos(a), math.Sin(a)
+				hx := -(n.X*c - n.Y*s) * 8
+				hy := -(n.X*s + n.Y*c) * 8
+				ebitenutil.DrawLine(screen, tx, ty, tx+hx, ty+hy, arrowColor)
+			}
+		}
+	}
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -245,6 +327,88 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 
 // handleInput spawns a new ball at the mouse/touch position.
 func (g *Game) handleInput() {
+	w := g.world
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		if err := w.LoadLevel(physics.NextLevel(w.Level())); err != nil {
+			log.Print(err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		w.Simultaneous = !w.Simultaneous
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		w.TrackContacts = !w.TrackContacts
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		g.interpolate = !g.interpolate
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		g.spawnGhosts = !g.spawnGhosts
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		w.Integrator = (w.Integrator + 1) % integrator.NumMethods
+	}
+
+	// Restitution: E shows the overlay, , and . adjust e within [0, 1]
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		g.showRestitution = !g.showRestitution
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyComma) {
+		w.Restitution = math.Max(w.Restitution-restitutionStep, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPeriod) {
+		w.Restitution = math.Min(w.Restitution+restitutionStep, 1)
+	}
+
+	// Color ramp range: - and = set the top speed by hand, A follows the
+	// fastest ball
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		g.autoColorMax = !g.autoColorMax
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyMinus) {
+		g.autoColorMax = false
+		g.colorMaxSpeed = math.Max(g.colorMaxSpeed/colorMaxStep, minColorMaxSpeed)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) {
+		g.autoColorMax = false
+		g.colorMaxSpeed *= colorMaxStep
+	}
+
+	// Shake the box; [ and ] adjust how hard
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		w.Shake(g.shakeStrength)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+		g.shakeStrength = math.Max(g.shakeStrength-25, 25)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+		g.shakeStrength += 25
+	}
+
+	// Steer the wind-tunnel flow
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		w.Flow.X -= flowStep
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		w.Flow.X += flowStep
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		w.Flow.Y -= flowStep
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		w.Flow.Y += flowStep
+	}
+	if l := w.Flow.Length(); l > maxFlow {
+		w.Flow.Scale(maxFlow / l)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDigit0) {
+		w.Flow = physics.Vector{}
+	}
+
 	spawn := false
 	var x, y float64
 
@@ -264,68 +428,108 @@ func (g *Game) handleInput() {
 	}
 
 	if spawn {
-		// Ensure the new ball is within boundaries
-		x = math.Max(BallRadius, math.Min(x, float64(screenW)-BallRadius))
-		y = math.Max(BallRadius, math.Min(y, float64(screenH)-BallRadius))
-
-		newBall := &Ball{
-			Pos:    Vector{X: x, Y: y},
-			Vel:    Vector{X: float64(rand.IntN(500)-250) / 100.0, Y: float64(rand.IntN(500)-250) / 100.0},
-			Radius: 10,
-			Mass:   1.0,
-			Color:  color.RGBA{255, 255, 255, 255}, // Start white
+		b := w.SpawnAt(physics.Vector{X: x, Y: y})
+		if g.spawnGhosts {
+			b.Layer, b.Mask = physics.LayerGhost, physics.LayerGhost|physics.LayerWalls
 		}
-		balls = append(balls, newBall)
 	}
 }
 
-// ============================
-// Initialization
-// ============================
-
-const BallRadius = 10.0
-
-func initGame(n int) {
-	balls = make([]*Ball, 0, n)
-
-	// Create initial balls
-	for i := 0; i < n; i++ {
-		b := &Ball{
-			Pos:    Vector{float64(rand.IntN(screenW-40) + 20), float64(rand.IntN(screenH/4) + 20)},
-			Vel:    Vector{float64(rand.IntN(10) - 5), float64(rand.IntN(10) - 5)},
-			Radius: BallRadius,
-			Mass:   1.0,
-			Color:  color.RGBA{255, 255, 255, 255},
-		}
-		balls = append(balls, b)
+// runHeadless builds a world from opts and steps it frames times without
+// rendering, printing the per-frame step time and a checksum of the final
+// state so runs can be compared for both speed and reproducibility.
+func runHeadless(opts physics.Options, frames int) error {
+	w, err := physics.NewWorld(opts)
+	if err != nil {
+		return err
 	}
-
-	// Define Walls
-	wallColor := color.RGBA{100, 100, 100, 255}
-	wallThickness := 20.0
-
-	// 1. Boundary Walls
-	walls = []Wall{
-		// top
-		{X: 0, Y: 0, W: float64(screenW), H: wallThickness, Color: wallColor},
-		// bottom
-		{X: 0, Y: float64(screenH) - wallThickness, W: float64(screenW), H: wallThickness, Color: wallColor},
-		// left
-		{X: 0, Y: 0, W: wallThickness, H: float64(screenH), Color: wallColor},
-		// right
-		{X: float64(screenW) - wallThickness, Y: 0, W: wallThickness, H: float64(screenH), Color: wallColor},
-
-		// 2. Internal Obstacle (A Static Shelf/Ramp)
-		{X: 100, Y: 650, W: 350, H: 30, Color: color.RGBA{200, 150, 0, 255}}, // Gold-colored shelf
-		{X: 450, Y: 500, W: 50, H: 180, Color: color.RGBA{200, 150, 0, 255}}, // Pillar
+	if len(w.Balls) == 0 {
+		return fmt.Errorf("the %s layout placed no balls to simulate", opts.Layout)
 	}
+	times := make([]time.Duration, frames)
+	var total time.Duration
+	for i := range times {
+		start := time.Now()
+		w.Step(w.TimeStep())
+		times[i] = time.Since(start)
+		total += times[i]
+	}
+	slices.Sort(times)
+	sum := 0.0
+	for _, b := range w.Balls {
+		sum += b.Pos.X + 2*b.Pos.Y + 3*b.Vel.X + 4*b.Vel.Y
+	}
+	contacts := "pairwise"
+	if opts.Simultaneous {
+		contacts = "simultaneous"
+	}
+	fmt.Printf("%d balls (radius %.2f), %d frames, %s contacts: mean %v/frame, median %v, p99 %v, max %v\n",
+		len(w.Balls), w.Balls[0].Radius, frames, contacts, total/time.Duration(frames),
+		times[frames/2], times[frames*99/100], times[frames-1])
+	fmt.Printf("final state checksum %.6f\n", sum)
+	return nil
 }
 
 func main() {
-	initGame(20) // Start with 20 balls
+	opts := physics.DefaultOptions()
+	opts.Width, opts.Height = screenW, screenH
+	flag.StringVar(&opts.Level, "level", opts.Level, "obstacle layout: "+strings.Join(physics.LevelNames(), ", "))
+	flag.StringVar(&opts.Layout, "layout", opts.Layout, "initial ball placement: random, grid (deterministic, at rest) or stress (see -stress)")
+	stress := flag.Int("stress", 0, "start with N balls packed in a tight grid with small seeded random velocities (sets -layout stress, raises -maxballs)")
+	flag.Uint64Var(&opts.Seed, "seed", opts.Seed, "random seed for the layouts, shaking and spawned balls")
+	frames := flag.Int("frames", 0, "step the simulation N frames headless, print step timing, then exit")
+	shake := flag.Float64("shake", 150, "maximum velocity kick per ball when shaking with Space")
+	interpolate := flag.Bool("interpolate", true, "draw balls interpolated between physics steps")
+	ghosts := flag.Bool("ghosts", false, "spawn clicked balls on the ghost layer, which passes through the main one")
+	flag.Float64Var(&opts.Density, "density", opts.Density, "derive spawned ball radius from a random mass at this relative density (0 = fixed radius)")
+	flag.BoolVar(&opts.Simultaneous, "simultaneous", opts.Simultaneous, "resolve all ball-ball contacts together instead of pair by pair")
+	method := flag.String("integrator", opts.Integrator.String(), "integration method: semi (semi-implicit Euler), euler (explicit), verlet (velocity Verlet) or rk2 (midpoint)")
+	flag.Float64Var(&opts.SettleEnergy, "settleenergy", opts.SettleEnergy, "pause once the mean kinetic energy per ball stays below this (0 = never pause)")
+	flag.IntVar(&opts.SettleFrames, "settleframes", opts.SettleFrames, "frames the energy must stay below -settleenergy before pausing")
+	flag.IntVar(&opts.MaxBalls, "maxballs", opts.MaxBalls, "maximum number of balls; new balls recycle the oldest beyond this")
+	ramp := flag.String("ramp", "", "speed color ramp, slow to fast: a preset ("+strings.Join(palette.Names(), ", ")+") or comma-separated rrggbb colors (default cyan,white,yellow)")
+	colorMax := flag.Float64("colormax", math.Sqrt(500), "speed shown at the top of the color ramp")
+	autoColorMax := flag.Bool("autocolormax", false, "calibrate the color ramp's top speed from the fastest ball")
+	flag.Parse()
+	if *colorMax < minColorMaxSpeed {
+		log.Fatalf("-colormax %g: want at least %g", *colorMax, minColorMaxSpeed)
+	}
+	m, err := integrator.Parse(*method)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts.Integrator = m
+	if *stress < 0 || *frames < 0 {
+		log.Fatal("-stress and -frames must not be negative")
+	}
+	if *stress > 0 {
+		opts.Layout, opts.Balls = "stress", *stress
+		opts.MaxBalls = max(opts.MaxBalls, *stress)
+	}
+	if *frames > 0 {
+		if opts.Balls == 0 {
+			log.Fatal("-frames needs balls to simulate")
+		}
+		if err := runHeadless(opts, *frames); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	g, err := NewGame(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *ramp != "" {
+		if g.speedRamp, err = palette.Parse(*ramp); err != nil {
+			log.Fatal(err)
+		}
+	}
+	g.colorMaxSpeed, g.autoColorMax = *colorMax, *autoColorMax
+	g.interpolate, g.spawnGhosts, g.shakeStrength = *interpolate, *ghosts, *shake
 	ebiten.SetWindowSize(screenW, screenH)
 	ebiten.SetWindowTitle("Kinetic Energy Visualizer")
-	if err := ebiten.RunGame(&Game{}); err != nil {
+	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,141 @@
+package smoke
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sync/atomic"
+
+	"github.com/arcesoftware/GO_Examples/audiolevel"
+)
+
+// Audio reactivity (-audio): the ambient spawn rate follows the level of a
+// raw PCM stream, signed 16-bit little-endian mono, e.g.
+//
+//	arecord -q -f S16_LE -c 1 -r 44100 | go run smoke.main.go -audio -
+//
+// Ebiten's audio package only plays sound, so capture is left to the
+// platform's recorder (arecord, parec, sox or ffmpeg all write this format).
+// A raw file recorded that way can be given too; it plays in real time.
+const (
+	DefaultAudioRate   = 44100
+	DefaultAudioSmooth = 0.15 // level smoothing time constant, seconds
+	DefaultAudioRef    = 0.1  // level (RMS of full scale) that spawns at the normal rate
+
+	audioFloor   = 0.1 // spawn multiplier in silence, so the plume never quite dies
+	audioMaxGain = 8   // cap on the spawn multiplier
+)
+
+// audioInput states: a FIFO source blocks in open until its writer starts,
+// and the spawn rate stays constant until samples arrive and again once the
+// stream ends or fails.
+const (
+	audioWaiting int32 = iota
+	audioLive
+	audioEnded
+)
+
+// audioInput reads the PCM stream on its own goroutine and publishes the
+// RMS of each block of one tick's worth of samples; step smooths it into
+// level.
+type audioInput struct {
+	source      string
+	sampleRate  int
+	smoothing   float64       // seconds (-audio-smooth)
+	ref         float64       // -audio-ref
+	level       float64       // smoothed RMS, 0..1
+	rms         atomic.Uint64 // math.Float64bits of the last block's RMS
+	state       atomic.Int32
+	blockFrames int
+}
+
+// newAudioInput returns an input that hasn't started reading.
+func newAudioInput(source string, sampleRate int, smoothing, ref float64) *audioInput {
+	return &audioInput{
+		source:      source,
+		sampleRate:  sampleRate,
+		smoothing:   smoothing,
+		ref:         ref,
+		blockFrames: max(sampleRate/60, 1),
+	}
+}
+
+// start opens the source ("-" is stdin) and reads it in the background.
+// A regular file is paced to sampleRate, one block per tick, so a recording
+// plays back in real time; pipes and FIFOs already arrive at that rate.
+// Failing to open it, or the stream ending, is logged once and leaves the
+// spawn rate constant.
+func (a *audioInput) start() {
+	go func() {
+		r := io.Reader(os.Stdin)
+		if a.source != "-" {
+			f, err := os.Open(a.source)
+			if err != nil {
+				a.state.Store(audioEnded)
+				log.Printf("-audio: %v; using the constant spawn rate", err)
+				return
+			}
+			defer f.Close()
+			r = f
+			if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+				r = audiolevel.Paced(f, float64(2*a.sampleRate))
+			}
+		}
+		if err := a.read(r); err != nil && err != io.EOF {
+			log.Printf("-audio: %v; back to the constant spawn rate", err)
+		} else {
+			log.Printf("-audio: stream ended; back to the constant spawn rate")
+		}
+	}()
+}
+
+// read consumes r block by block until it ends, storing each block's RMS.
+func (a *audioInput) read(r io.Reader) error {
+	block := make([]byte, 2*a.blockFrames)
+	for {
+		if _, err := io.ReadFull(r, block); err != nil {
+			a.state.Store(audioEnded)
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF // a partial last block is just the end
+			}
+			return err
+		}
+		a.rms.Store(math.Float64bits(audiolevel.BlockRMS(block)))
+		a.state.Store(audioLive)
+	}
+}
+
+// smooth eases level toward the latest block's RMS over dt seconds, an
+// exponential moving average with the smoothing time constant, so the rate
+// follows the music's swell rather than every transient.
+func (a *audioInput) smooth(dt float64) {
+	raw := math.Float64frombits(a.rms.Load())
+	if a.smoothing <= 0 {
+		a.level = raw
+		return
+	}
+	a.level += (raw - a.level) * (1 - math.Exp(-dt/a.smoothing))
+}
+
+// spawnScale is what the ambient spawn rate is multiplied by: 1 at the
+// reference level, audioFloor in silence, at most audioMaxGain, and 1
+// whenever no samples are coming in.
+func (a *audioInput) spawnScale() float64 {
+	if a.state.Load() != audioLive {
+		return 1
+	}
+	return math.Min(audioFloor+(1-audioFloor)*a.level/a.ref, audioMaxGain)
+}
+
+// status describes the input for the HUD.
+func (a *audioInput) status() string {
+	switch a.state.Load() {
+	case audioWaiting:
+		return fmt.Sprintf("waiting for %s", a.source)
+	case audioLive:
+		return fmt.Sprintf("level %.3f, spawn x%.2f", a.level, a.spawnScale())
+	}
+	return "ended (constant rate)"
+}
@@ -0,0 +1,326 @@
+// Package smoke is the smoke demo's particle plume: a growable pool of
+// rotating smoke puffs, stored as a struct of arrays, rising from a
+// drifting emitter and drawn in one batch with a fade envelope and a size
+// curve over their lives. Clicks puff extra smoke and the spawn rate can
+// follow the level of a PCM stream. The demo only reads flags, builds a
+// Game from them and runs it.
+package smoke
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// rotLUTSize is the number of angle steps in the sine table (a power of two).
+const rotLUTSize = 1024
+
+// sinLUT holds sin over one turn in rotLUTSize steps; cos reads it a quarter
+// turn ahead.
+var sinLUT [rotLUTSize]float64
+
+func init() {
+	for i := range sinLUT {
+		sinLUT[i] = math.Sin(2 * math.Pi * float64(i) / rotLUTSize)
+	}
+}
+
+// lutSinCos returns sin and cos of angle quantized to the nearest table step
+// (about 0.35 degrees).
+func lutSinCos(angle float64) (sin, cos float64) {
+	i := int(math.Round(angle*(rotLUTSize/(2*math.Pi)))) & (rotLUTSize - 1)
+	return sinLUT[i], sinLUT[(i+rotLUTSize/4)&(rotLUTSize-1)]
+}
+
+// quadCorners returns the screen positions of a particle quad's top-left,
+// bottom-left, top-right and bottom-right corners: the smoke image centered
+// on (x, y), rotated by angle and scaled. With useLUT the rotation is built
+// by hand from the sine table instead of a GeoM (which calls math.Sin/Cos).
+func quadCorners(x, y, angle, scale float64, useLUT bool) [4][2]float64 {
+	local := [4][2]float64{{0, 0}, {0, smokeImageH}, {smokeImageW, 0}, {smokeImageW, smokeImageH}}
+	var pts [4][2]float64
+	if !useLUT {
+		var geo ebiten.GeoM
+		geo.Translate(-smokeImageW/2, -smokeImageH/2) // 1. Move to center
+		geo.Rotate(angle)                             // 2. Rotate
+		geo.Scale(scale, scale)                       // 3. Scale
+		geo.Translate(x, y)                           // 4. Translate to final position
+		for i, c := range local {
+			pts[i][0], pts[i][1] = geo.Apply(c[0], c[1])
+		}
+		return pts
+	}
+	sin, cos := lutSinCos(angle)
+	sin, cos = sin*scale, cos*scale
+	for i, c := range local {
+		lx, ly := c[0]-smokeImageW/2, c[1]-smokeImageH/2
+		pts[i][0] = lx*cos - ly*sin + x
+		pts[i][1] = lx*sin + ly*cos + y
+	}
+	return pts
+}
+
+// Particle is one particle's state. The game keeps its particles in a
+// particlePool; this form is what newParticle builds and the pool stores.
+type Particle struct {
+	x, y            float64
+	vx, vy          float64
+	lifetime        int
+	maxLife         int
+	img             *ebiten.Image
+	baseScale       float64
+	angle           float64
+	angularVelocity float64
+	baseAlpha       float32
+	color           *color.RGBA
+	active          bool
+}
+
+func (p *Particle) update() {
+	if !p.active {
+		return
+	}
+
+	p.lifetime++
+	if p.lifetime >= p.maxLife {
+		p.active = false
+		return
+	}
+
+	p.x += p.vx
+	p.y += p.vy
+	p.angle += p.angularVelocity
+}
+
+// newParticle is unchanged, initializing a particle
+func newParticle(img *ebiten.Image, emitterX, emitterY float64) *Particle {
+	maxLife := rand.IntN(60) + 240
+	angle := rand.Float64() * math.Pi / 3.0
+	if rand.IntN(2) == 0 {
+		angle = -angle
+	}
+	angle += math.Pi / 2.0
+
+	speed := rand.Float64()*0.4 + 0.1
+	updraft := -1.0
+
+	vx := math.Cos(angle) * speed
+	vy := math.Sin(angle)*speed + updraft
+
+	r := uint8(0xc0 + rand.IntN(0x3f))
+	g := uint8(0xc0 + rand.IntN(0x3f))
+	b := uint8(0xc0 + rand.IntN(0x3f))
+
+	return &Particle{
+		img: img,
+
+		active:   true,
+		maxLife:  maxLife,
+		lifetime: 0,
+
+		x:  emitterX,
+		y:  emitterY,
+		vx: vx,
+		vy: vy,
+
+		angle:           rand.Float64() * 2 * math.Pi,
+		angularVelocity: rand.Float64() * 0.03 * (rand.Float64()*2 - 1),
+		baseScale:       rand.Float64()*0.1 + 0.3,
+		baseAlpha:       0.8,
+		color:           &color.RGBA{R: r, G: g, B: b, A: 0xff},
+	}
+}
+
+// particlePool holds the simulated particles as parallel slices (struct of
+// arrays) indexed by pool slot, so the update and draw loops stream through
+// contiguous memory instead of chasing a pointer per particle. Particle is
+// still the shape of a single particle, as built by newParticle.
+type particlePool struct {
+	x, y            []float64
+	vx, vy          []float64
+	lifetime        []int
+	maxLife         []int
+	baseScale       []float64
+	angle           []float64
+	angularVelocity []float64
+	baseAlpha       []float32
+	color           []color.RGBA
+	active          []bool
+}
+
+func newParticlePool(capacity int) particlePool {
+	return particlePool{
+		x:               make([]float64, 0, capacity),
+		y:               make([]float64, 0, capacity),
+		vx:              make([]float64, 0, capacity),
+		vy:              make([]float64, 0, capacity),
+		lifetime:        make([]int, 0, capacity),
+		maxLife:         make([]int, 0, capacity),
+		baseScale:       make([]float64, 0, capacity),
+		angle:           make([]float64, 0, capacity),
+		angularVelocity: make([]float64, 0, capacity),
+		baseAlpha:       make([]float32, 0, capacity),
+		color:           make([]color.RGBA, 0, capacity),
+		active:          make([]bool, 0, capacity),
+	}
+}
+
+// len is the number of slots, active or not.
+func (pp *particlePool) len() int {
+	return len(pp.active)
+}
+
+// grow appends an inactive slot and returns its index.
+func (pp *particlePool) grow() int {
+	pp.x = append(pp.x, 0)
+	pp.y = append(pp.y, 0)
+	pp.vx = append(pp.vx, 0)
+	pp.vy = append(pp.vy, 0)
+	pp.lifetime = append(pp.lifetime, 0)
+	pp.maxLife = append(pp.maxLife, 0)
+	pp.baseScale = append(pp.baseScale, 0)
+	pp.angle = append(pp.angle, 0)
+	pp.angularVelocity = append(pp.angularVelocity, 0)
+	pp.baseAlpha = append(pp.baseAlpha, 0)
+	pp.color = append(pp.color, color.RGBA{})
+	pp.active = append(pp.active, false)
+	return len(pp.active) - 1
+}
+
+// set stores p in slot i.
+func (pp *particlePool) set(i int, p *Particle) {
+	pp.x[i], pp.y[i] = p.x, p.y
+	pp.vx[i], pp.vy[i] = p.vx, p.vy
+	pp.lifetime[i], pp.maxLife[i] = p.lifetime, p.maxLife
+	pp.baseScale[i] = p.baseScale
+	pp.angle[i], pp.angularVelocity[i] = p.angle, p.angularVelocity
+	pp.baseAlpha[i] = p.baseAlpha
+	pp.color[i] = *p.color
+	pp.active[i] = p.active
+}
+
+// update advances every active particle by one tick, like Particle.update.
+func (pp *particlePool) update() {
+	// Reslicing to one length lets the compiler drop the bounds checks.
+	n := len(pp.active)
+	x, y, vx, vy := pp.x[:n], pp.y[:n], pp.vx[:n], pp.vy[:n]
+	lifetime, maxLife := pp.lifetime[:n], pp.maxLife[:n]
+	angle, angularVelocity := pp.angle[:n], pp.angularVelocity[:n]
+	for i, active := range pp.active {
+		if !active {
+			continue
+		}
+		lifetime[i]++
+		if lifetime[i] >= maxLife[i] {
+			pp.active[i] = false
+			continue
+		}
+		x[i] += vx[i]
+		y[i] += vy[i]
+		angle[i] += angularVelocity[i]
+	}
+}
+
+// particleQuad returns the corners and premultiplied color of a particle's
+// quad at the given point of its life.
+func particleQuad(x, y, angle, baseScale float64, lifetime, maxLife int, baseAlpha float32, c color.RGBA, curve SizeCurve, dissipate, useLUT bool) (pts [4][2]float64, r, g, b, a float32) {
+	// Calculate dynamic properties (Scale and Alpha)
+	rate := float64(lifetime) / float64(maxLife)
+	scale := baseScale * curve.at(rate)
+	alpha := lifeAlpha(rate, curve, dissipate) * baseAlpha
+
+	// Color Scale
+	r = float32(c.R) / 0xff * alpha
+	g = float32(c.G) / 0xff * alpha
+	b = float32(c.B) / 0xff * alpha
+	a = alpha // Alpha is already factored into the component colors via pre-multiplied alpha
+
+	// Calculate the four corners of the quad
+	return quadCorners(x, y, angle, scale, useLUT), r, g, b, a
+}
+
+// envelopeAlpha is the fade envelope over a particle's life fraction: a
+// ramp in for rate < 0.2, full opacity in the middle, a ramp out for
+// rate > 0.8. Draw scales it by the particle's baseAlpha.
+func envelopeAlpha(rate float64) float32 {
+	if rate < 0.2 {
+		return float32(rate / 0.2)
+	}
+	if rate > 0.8 {
+		return float32((1 - rate) / 0.2)
+	}
+	return 1.0
+}
+
+// lifeAlpha is a particle's opacity over its life before baseAlpha. With
+// dissipate the envelope is divided by the puff's area growth since birth
+// (size curve squared, relative to its starting value), so a growing puff
+// thins out and keeps its apparent mass instead of fading on a schedule
+// unrelated to its size. Shrinking never makes it more opaque. A curve that
+// starts at zero size has no birth area to compare against, so it doesn't
+// dissipate.
+func lifeAlpha(rate float64, curve SizeCurve, dissipate bool) float32 {
+	a := envelopeAlpha(rate)
+	if start := curve.at(0); dissipate && start > 0 {
+		growth := curve.at(rate) / start
+		a *= float32(math.Min(1, 1/(growth*growth)))
+	}
+	return a
+}
+
+// curveKey is one keyframe of a curve: value v at normalized life t.
+type curveKey struct {
+	t, v float64
+}
+
+// SizeCurve maps a particle's life fraction (0..1) to a scale multiplier by
+// linear interpolation between keyframes sorted by t.
+type SizeCurve []curveKey
+
+// LinearGrowth is the original size-over-life: 0.8 at birth growing to 1.3.
+var LinearGrowth = SizeCurve{{0, 0.8}, {1, 1.3}}
+
+func (c SizeCurve) at(t float64) float64 {
+	if len(c) == 0 {
+		return 1
+	}
+	if t <= c[0].t {
+		return c[0].v
+	}
+	for i := 1; i < len(c); i++ {
+		if t <= c[i].t {
+			a, b := c[i-1], c[i]
+			return a.v + (b.v-a.v)*(t-a.t)/(b.t-a.t)
+		}
+	}
+	return c[len(c)-1].v
+}
+
+// ParseSizeCurve parses "t:v,t:v,..." with t in [0,1] strictly increasing.
+func ParseSizeCurve(spec string) (SizeCurve, error) {
+	var c SizeCurve
+	for _, kv := range strings.Split(spec, ",") {
+		ts, vs, ok := strings.Cut(strings.TrimSpace(kv), ":")
+		if !ok {
+			return nil, fmt.Errorf("keyframe %q: want t:v", kv)
+		}
+		t, err := strconv.ParseFloat(ts, 64)
+		if err != nil || t < 0 || t > 1 {
+			return nil, fmt.Errorf("keyframe %q: t must be a number in [0,1]", kv)
+		}
+		v, err := strconv.ParseFloat(vs, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("keyframe %q: v must be a non-negative number", kv)
+		}
+		if len(c) > 0 && t <= c[len(c)-1].t {
+			return nil, fmt.Errorf("keyframe %q: t must increase", kv)
+		}
+		c = append(c, curveKey{t, v})
+	}
+	return c, nil
+}
@@ -0,0 +1,531 @@
+package smoke
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"log"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/examples/resources/images"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/arcesoftware/GO_Examples/spritebatch"
+)
+
+const (
+	ScreenWidth  = 640
+	ScreenHeight = 480
+	// DefaultMaxParticles is the starting pool size; -max overrides it.
+	DefaultMaxParticles = 8000
+
+	// MaxCeiling bounds -max and -ceiling. Past spritebatch.MaxQuads particles the
+	// batch simply splits into more draw calls.
+	MaxCeiling = 4 * spritebatch.MaxQuads
+
+	// Overdraw heatmap grid (H): cell size in pixels and resulting grid size
+	heatCell = 32
+	heatCols = (ScreenWidth + heatCell - 1) / heatCell
+	heatRows = (ScreenHeight + heatCell - 1) / heatCell
+)
+
+var smokeImage *ebiten.Image
+var smokeImageW, smokeImageH float64 // Width and Height of the source image
+
+// RunInBackground keeps the simulation going while the window is unfocused.
+var RunInBackground bool
+
+func init() {
+	// Decode an image from the image file's byte slice.
+	img, _, err := image.Decode(bytes.NewReader(images.Smoke_png))
+	if err != nil {
+		log.Fatal(err)
+	}
+	smokeImage = ebiten.NewImageFromImage(img)
+
+	// Pre-calculate image dimensions for texture coordinates
+	smokeImageW = float64(smokeImage.Bounds().Dx())
+	smokeImageH = float64(smokeImage.Bounds().Dy())
+}
+
+// particleShaderSrc is the Kage program for the -shader path. It draws each
+// quad as a soft disc computed per pixel instead of sampling smokeImage.
+// Custom0/Custom1 carry the quad-local position in [-1,1]; the vertex color
+// is the premultiplied particle color and alpha.
+const particleShaderSrc = `//kage:unit pixels
+
+package main
+
+func Fragment(dstPos vec4, srcPos vec2, color vec4, custom vec4) vec4 {
+	a := clamp(1-length(custom.xy), 0, 1)
+	return color * a * a
+}
+`
+
+// Options configures a game built by NewGame.
+type Options struct {
+	MaxParticles int // starting pool size (-max), at most MaxCeiling
+	Ceiling      int // hard limit the pool may grow to (-ceiling); 0 = MaxParticles
+	Warmup       int // ticks simulated before the first frame (-warmup)
+
+	RotLUT    bool      // rotate quads with the sine table instead of GeoM (-lut)
+	Dissipate bool      // couple alpha to the puff's growth (-dissipate)
+	SizeCurve SizeCurve // scale multiplier over each particle's life (-sizecurve)
+}
+
+// DefaultOptions returns the demo's starting settings.
+func DefaultOptions() Options {
+	return Options{
+		MaxParticles: DefaultMaxParticles,
+		Warmup:       180,
+		SizeCurve:    LinearGrowth,
+	}
+}
+
+// NewGame returns a plume configured by opts. The pool is allocated and
+// warmed up on the first Update.
+func NewGame(opts Options) *Game {
+	ceiling := opts.Ceiling
+	if ceiling == 0 {
+		ceiling = opts.MaxParticles
+	}
+	return &Game{
+		maxParticles: opts.MaxParticles,
+		ceiling:      ceiling,
+		warmupFrames: opts.Warmup,
+		rotLUT:       opts.RotLUT,
+		dissipate:    opts.Dissipate,
+		sizeCurve:    opts.SizeCurve,
+	}
+}
+
+// UseShader compiles the particle shader and draws with it instead of the
+// smoke texture (-shader). On error the game keeps the texture.
+func (g *Game) UseShader() error {
+	s, err := ebiten.NewShader([]byte(particleShaderSrc))
+	if err != nil {
+		return err
+	}
+	g.shader = s
+	return nil
+}
+
+// ListenAudio scales the spawn rate with the level of raw s16le mono PCM
+// read from source (-audio), a file, FIFO or "-" for stdin, which is read
+// in the background from now on.
+func (g *Game) ListenAudio(source string, sampleRate int, smoothing, ref float64) {
+	g.audio = newAudioInput(source, sampleRate, smoothing, ref)
+	g.audio.start()
+}
+
+// --- Game Structure and Optimization ---
+
+type Game struct {
+	particles    particlePool
+	maxParticles int // pool size allocated at startup
+	started      bool
+	emitterX     float64
+	emitterY     float64
+
+	// Quad batch for DrawTriangles, reused every frame so drawing doesn't
+	// allocate
+	batch spritebatch.SpriteBatch
+
+	// Hard pool limit (-ceiling); the pool grows past maxParticles up to it
+	ceiling    int
+	ceilingHit bool // already logged that spawns are being dropped
+
+	// Ticks simulated before the first frame (-warmup)
+	warmupFrames int
+
+	// Build quad rotations from the sine table instead of GeoM (-lut)
+	rotLUT bool
+
+	// Compiled particle shader; nil draws with the smokeImage texture
+	shader *ebiten.Shader
+
+	// Scale multiplier over each particle's life
+	sizeCurve SizeCurve
+
+	// Overdraw heatmap: number of particle quads covering each grid cell
+	showHeat bool
+	heat     [heatCols * heatRows]int
+
+	// Last click puff: particles spawned out of those requested
+	lastPuff, lastPuffWant int
+
+	// Particle inspector (I): highlights the particle nearest the cursor
+	// and prints its state
+	inspect bool
+
+	// Couple alpha to the puff's growth instead of the envelope alone (D,
+	// -dissipate)
+	dissipate bool
+
+	// Audio-reactive spawn rate (-audio); nil = constant rate
+	audio *audioInput
+}
+
+// addHeat counts one quad against every heatmap cell its bounding box covers.
+func (g *Game) addHeat(minX, minY, maxX, maxY float64) {
+	c0 := max(int(minX)/heatCell, 0)
+	r0 := max(int(minY)/heatCell, 0)
+	c1 := min(int(maxX)/heatCell, heatCols-1)
+	r1 := min(int(maxY)/heatCell, heatRows-1)
+	for r := r0; r <= r1; r++ {
+		for c := c0; c <= c1; c++ {
+			g.heat[r*heatCols+c]++
+		}
+	}
+}
+
+// heatColor maps t in [0,1] from blue (low overdraw) through green to red.
+func heatColor(t float64) color.RGBA {
+	r := uint8(255 * math.Min(1, math.Max(0, 2*t-1)))
+	gr := uint8(255 * (1 - math.Abs(2*t-1)))
+	b := uint8(255 * math.Min(1, math.Max(0, 1-2*t)))
+	return color.RGBA{R: r, G: gr, B: b, A: 0xff}
+}
+
+// drawHeat overlays the heatmap, normalized to the busiest cell.
+func (g *Game) drawHeat(screen *ebiten.Image) {
+	peak := 0
+	for _, n := range g.heat {
+		peak = max(peak, n)
+	}
+	if peak == 0 {
+		return
+	}
+	for i, n := range g.heat {
+		if n == 0 {
+			continue
+		}
+		c := heatColor(float64(n) / float64(peak))
+		c.R, c.G, c.B, c.A = c.R/2, c.G/2, c.B/2, 0x80 // premultiplied, 50% opacity
+		x := float64(i%heatCols) * heatCell
+		y := float64(i/heatCols) * heatCell
+		ebitenutil.DrawRect(screen, x, y, heatCell, heatCell, c)
+	}
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Heatmap: peak %d quads/cell", peak), 0, ScreenHeight-16)
+}
+
+// inspectRadius is how close (px) the cursor must be to a particle for the
+// inspector to pick it.
+const inspectRadius = 48.0
+
+// nearestParticle returns the slot of the active particle closest to (x, y)
+// within inspectRadius, or -1 if there is none.
+func (g *Game) nearestParticle(x, y float64) int {
+	pp := &g.particles
+	best, bestD := -1, inspectRadius*inspectRadius
+	for i, active := range pp.active {
+		if !active {
+			continue
+		}
+		dx, dy := pp.x[i]-x, pp.y[i]-y
+		if d := dx*dx + dy*dy; d <= bestD {
+			best, bestD = i, d
+		}
+	}
+	return best
+}
+
+// drawInspector rings the particle nearest the cursor and prints its state
+// in a box beside it.
+func (g *Game) drawInspector(screen *ebiten.Image) {
+	mx, my := ebiten.CursorPosition()
+	i := g.nearestParticle(float64(mx), float64(my))
+	if i < 0 {
+		ebitenutil.DebugPrintAt(screen, "Inspector: no particle near the cursor", 0, ScreenHeight-32)
+		return
+	}
+	pp := &g.particles
+	rate := float64(pp.lifetime[i]) / float64(pp.maxLife[i])
+	scale := pp.baseScale[i] * g.sizeCurve.at(rate)
+	alpha := lifeAlpha(rate, g.sizeCurve, g.dissipate) * pp.baseAlpha[i]
+
+	// The ring hugs the visible puff, about half the scaled texture.
+	r := float32(math.Max(smokeImageW, smokeImageH) * scale / 4)
+	vector.StrokeCircle(screen, float32(pp.x[i]), float32(pp.y[i]), r, 1.5, color.RGBA{0xff, 0xe0, 0x40, 0xff}, true)
+
+	c := pp.color[i]
+	text := fmt.Sprintf("slot %d\npos (%.1f, %.1f)\nvel (%.2f, %.2f)\nlife %d/%d (%.0f%%)\nscale %.3f\nalpha %.2f\nangle %.2f (%+.3f/tick)\ncolor #%02x%02x%02x",
+		i, pp.x[i], pp.y[i], pp.vx[i], pp.vy[i], pp.lifetime[i], pp.maxLife[i], rate*100, scale, alpha, pp.angle[i], pp.angularVelocity[i], c.R, c.G, c.B)
+
+	// Box to the right of the particle, flipped left/up to stay on screen.
+	const boxW, boxH = 190, 8*16 + 8
+	bx, by := pp.x[i]+float64(r)+8, pp.y[i]-boxH/2
+	if bx+boxW > ScreenWidth {
+		bx = pp.x[i] - float64(r) - 8 - boxW
+	}
+	by = math.Max(0, math.Min(by, ScreenHeight-boxH))
+	ebitenutil.DrawRect(screen, bx, by, boxW, boxH, color.RGBA{0, 0, 0, 0xb0})
+	ebitenutil.DebugPrintAt(screen, text, int(bx)+4, int(by)+4)
+}
+
+// allocateParticle returns a free pool slot, growing the pool below the
+// ceiling, or -1 when there is none.
+func (g *Game) allocateParticle() int {
+	for i, active := range g.particles.active {
+		if !active {
+			return i
+		}
+	}
+
+	if g.particles.len() < g.ceiling {
+		i := g.particles.grow()
+		g.reserveBuffers(g.particles.len())
+		return i
+	}
+	if !g.ceilingHit {
+		g.ceilingHit = true
+		log.Printf("particle ceiling %d reached; dropping spawns", g.ceiling)
+	}
+	return -1
+}
+
+// puffSize is how many particles a left click asks spawnBurst for, and
+// puffSpread the extra outward speed (px/tick) a puff particle can get.
+const (
+	puffSize   = 400
+	puffSpread = 1.2
+)
+
+// spawnBurst injects up to count particles at (x, y) in one go, pushed
+// outward so they bloom into a puff. The burst is capped to the free pool
+// slots plus whatever room is left below the ceiling; it returns how many
+// particles were spawned.
+func (g *Game) spawnBurst(x, y float64, count int) int {
+	free := g.ceiling - g.particles.len()
+	for _, active := range g.particles.active {
+		if !active {
+			free++
+		}
+	}
+	count = min(count, free)
+	for i := 0; i < count; i++ {
+		slot := g.allocateParticle()
+		if slot < 0 {
+			return i
+		}
+		g.particles.set(slot, newParticle(smokeImage, x, y))
+		a := rand.Float64() * 2 * math.Pi
+		v := rand.Float64() * puffSpread
+		g.particles.vx[slot] += math.Cos(a) * v
+		g.particles.vy[slot] += math.Sin(a) * v
+	}
+	return count
+}
+
+// Count is the number of live particles in the pool.
+func (g *Game) Count() int {
+	n := 0
+	for _, active := range g.particles.active {
+		if active {
+			n++
+		}
+	}
+	return n
+}
+
+// reserveBuffers makes sure the sprite batch can hold n particles,
+// doubling its capacity (up to the ceiling) when they can't. It runs from
+// Update, so Draw never reallocates while it is building a frame.
+func (g *Game) reserveBuffers(n int) {
+	if g.batch.Cap() >= n {
+		return
+	}
+	g.batch.Reserve(min(max(n, 2*g.batch.Cap()), g.ceiling))
+}
+
+func (g *Game) Update() error {
+	if !g.started {
+		g.started = true
+		g.particles = newParticlePool(g.maxParticles)
+		g.emitterX = ScreenWidth / 2
+		g.emitterY = ScreenHeight / 2
+
+		// Pre-allocate the sprite batch
+		g.batch.Reserve(g.maxParticles)
+
+		// Open mid-plume instead of on an empty screen
+		g.warmup(g.warmupFrames)
+	}
+
+	// Pause the simulation (Draw still runs) while the window is unfocused
+	if !RunInBackground && !ebiten.IsFocused() {
+		return nil
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
+		g.showHeat = !g.showHeat
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		g.inspect = !g.inspect
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		g.dissipate = !g.dissipate
+	}
+
+	// Left click puffs smoke at the cursor; the ambient emitter keeps going
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		mx, my := ebiten.CursorPosition()
+		g.lastPuffWant = puffSize
+		g.lastPuff = g.spawnBurst(float64(mx), float64(my), puffSize)
+	}
+
+	g.step()
+	return nil
+}
+
+// spawnPerTick is the ambient emitter's average spawns per tick; -audio
+// scales it with the sound level.
+const spawnPerTick = 2.0 / 3
+
+// step spawns, advances every active particle and drifts the emitter by one
+// tick. The fractional part of the spawn rate is a chance of one more.
+func (g *Game) step() {
+	rate := spawnPerTick
+	if g.audio != nil {
+		g.audio.smooth(1 / float64(ebiten.TPS()))
+		rate *= g.audio.spawnScale()
+	}
+	n := int(rate)
+	if rand.Float64() < rate-float64(n) {
+		n++
+	}
+	for i := 0; i < n; i++ {
+		if slot := g.allocateParticle(); slot >= 0 {
+			g.particles.set(slot, newParticle(smokeImage, g.emitterX, g.emitterY))
+		}
+	}
+
+	g.particles.update()
+
+	g.driftEmitter(1 / float64(ebiten.TPS()))
+}
+
+// Emitter drift: a gentle random walk that rises slowly, held inside a soft
+// box so the plume stays on screen during long runs.
+const (
+	emitterJitter = 1.94 // random-walk strength, px/sqrt(s) (0.25px/tick at 60 TPS)
+	emitterRise   = 6.0  // upward drift, px/s
+	emitterSpring = 2.0  // pull back per px outside the box, 1/s
+
+	emitterMinX = ScreenWidth / 4
+	emitterMaxX = ScreenWidth * 3 / 4
+	emitterMinY = ScreenHeight / 3
+	emitterMaxY = ScreenHeight * 3 / 4
+)
+
+// driftEmitter moves the emitter by dt seconds of drift. The random step
+// scales with sqrt(dt) and the rise and spring with dt, so the walk looks
+// the same at any TPS.
+func (g *Game) driftEmitter(dt float64) {
+	g.emitterX += (rand.Float64()*2 - 1) * emitterJitter * math.Sqrt(dt)
+	g.emitterY -= emitterRise * dt
+	g.emitterX += softBound(g.emitterX, emitterMinX, emitterMaxX) * emitterSpring * dt
+	g.emitterY += softBound(g.emitterY, emitterMinY, emitterMaxY) * emitterSpring * dt
+}
+
+// softBound returns how far v must move to get back into [lo, hi]: 0 inside,
+// positive below lo, negative above hi.
+func softBound(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo - v
+	}
+	if v > hi {
+		return hi - v
+	}
+	return 0
+}
+
+// warmup runs the simulation for n ticks without drawing. Spawns go through
+// allocateParticle, so the pool ceiling still applies.
+func (g *Game) warmup(n int) {
+	for i := 0; i < n; i++ {
+		g.step()
+	}
+}
+
+// --- The Critical Draw Function Refactor ---
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{R: 0x66, G: 0x99, B: 0xcc, A: 0xff})
+
+	g.batch.Reset()
+	src := smokeImage.Bounds()
+
+	if g.showHeat {
+		g.heat = [heatCols * heatRows]int{}
+	}
+
+	pp := &g.particles
+	for i, active := range pp.active {
+		if !active {
+			continue
+		}
+
+		pts, cr, cg, cb, ca := particleQuad(pp.x[i], pp.y[i], pp.angle[i], pp.baseScale[i], pp.lifetime[i], pp.maxLife[i], pp.baseAlpha[i], pp.color[i], g.sizeCurve, g.dissipate, g.rotLUT)
+		g.batch.AddQuad(pts, src, cr, cg, cb, ca)
+
+		if g.showHeat {
+			minX, minY := pts[0][0], pts[0][1]
+			maxX, maxY := minX, minY
+			for _, c := range pts[1:] {
+				minX, maxX = math.Min(minX, c[0]), math.Max(maxX, c[0])
+				minY, maxY = math.Min(minY, c[1]), math.Max(maxY, c[1])
+			}
+			g.addHeat(minX, minY, maxX, maxY)
+		}
+	}
+	activeCount := g.batch.Len()
+
+	// ** Batched draw for ALL particles **
+	// One DrawTriangles call per spritebatch.MaxQuads particles.
+	if g.shader != nil {
+		g.batch.FlushShader(screen, g.shader, ebiten.CompositeModeLighter)
+	} else {
+		g.batch.Flush(screen, smokeImage, ebiten.CompositeModeLighter) // Lighter is often better for smoke/fire
+	}
+
+	if g.showHeat {
+		g.drawHeat(screen)
+	}
+	if g.inspect {
+		g.drawInspector(screen)
+	}
+
+	path := "texture"
+	if g.shader != nil {
+		path = "shader"
+	}
+	if g.rotLUT {
+		path += ", LUT rotation"
+	}
+	fade := "envelope"
+	if g.dissipate {
+		fade = "dissipating"
+	}
+	msg := fmt.Sprintf("TPS: %0.2f\nActive Particles: %d/%d (Pool) /%d (Ceiling)\nRender: %s\nFade (D): %s\nHeatmap: H\nInspector: I\nPuff: LMB", ebiten.ActualTPS(), activeCount, g.particles.len(), g.ceiling, path, fade)
+	if g.lastPuffWant > 0 {
+		msg += fmt.Sprintf(" (last %d/%d)", g.lastPuff, g.lastPuffWant)
+	}
+	if g.audio != nil {
+		msg += "\nAudio: " + g.audio.status()
+	}
+	if !RunInBackground && !ebiten.IsFocused() {
+		msg += "\nPaused (window unfocused)"
+	}
+	ebitenutil.DebugPrint(screen, msg)
+}
+
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return ScreenWidth, ScreenHeight
+}
@@ -0,0 +1,322 @@
+package smoke
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/arcesoftware/GO_Examples/audiolevel"
+	"github.com/arcesoftware/GO_Examples/gametest"
+	"github.com/arcesoftware/GO_Examples/spritebatch"
+)
+
+func TestMain(m *testing.M) {
+	gametest.Main(m)
+}
+
+// benchParticles is how many particles the benchmarks run over.
+const benchParticles = DefaultMaxParticles
+
+// pose is one particle quad's placement.
+type pose struct{ x, y, angle, scale float64 }
+
+// randomPoses returns n seeded quad placements spread over the screen.
+func randomPoses(n int) []pose {
+	rng := rand.New(rand.NewPCG(1, 2))
+	poses := make([]pose, n)
+	for i := range poses {
+		poses[i] = pose{rng.Float64() * ScreenWidth, rng.Float64() * ScreenHeight, rng.Float64()*40 - 20, 0.3 + rng.Float64()*0.3}
+	}
+	return poses
+}
+
+// TestLUTRotation checks that quad corners rotated with the sine table stay
+// within the table's quantization of the GeoM ones: half a step of arc at
+// the corner's distance from the quad center.
+func TestLUTRotation(t *testing.T) {
+	maxErr := 0.0
+	for _, p := range randomPoses(benchParticles) {
+		a := quadCorners(p.x, p.y, p.angle, p.scale, false)
+		b := quadCorners(p.x, p.y, p.angle, p.scale, true)
+		bound := math.Hypot(smokeImageW, smokeImageH) / 2 * p.scale * math.Pi / rotLUTSize
+		for i := range a {
+			d := math.Hypot(a[i][0]-b[i][0], a[i][1]-b[i][1])
+			if d > bound+1e-9 {
+				t.Fatalf("angle %.3f scale %.2f: corner %d off by %.3f px, table quantization allows %.3f", p.angle, p.scale, i, d, bound)
+			}
+			maxErr = math.Max(maxErr, d)
+		}
+	}
+	t.Logf("max corner error %.3f px", maxErr)
+}
+
+// BenchmarkRotation times quadCorners with GeoM and with the lookup table,
+// one op being benchParticles quads.
+func BenchmarkRotation(b *testing.B) {
+	poses := randomPoses(benchParticles)
+	for _, mode := range []struct {
+		name string
+		lut  bool
+	}{
+		{"GeoM", false},
+		{"LUT", true},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			var sink float64
+			for i := 0; i < b.N; i++ {
+				for _, p := range poses {
+					pts := quadCorners(p.x, p.y, p.angle, p.scale, mode.lut)
+					sink += pts[3][0]
+				}
+			}
+			_ = sink
+		})
+	}
+}
+
+// layouts fills n particles spread over their lives, once as the old
+// []*Particle layout and once as a particlePool, kept alive for ticks more
+// ticks.
+func layouts(n, ticks int) ([]*Particle, particlePool) {
+	aos := make([]*Particle, n)
+	soa := newParticlePool(n)
+	for i := range aos {
+		p := newParticle(nil, rand.Float64()*ScreenWidth, rand.Float64()*ScreenHeight)
+		p.maxLife += ticks
+		p.lifetime = rand.IntN(p.maxLife - ticks)
+		aos[i] = p
+		soa.set(soa.grow(), p) // copies, so the layouts evolve independently
+	}
+	return aos, soa
+}
+
+// TestPoolMatchesParticles checks that particlePool.update moves every
+// particle exactly as Particle.update does, deaths included.
+func TestPoolMatchesParticles(t *testing.T) {
+	const ticks = 400 // past the longest life, so every particle dies
+	aos, soa := layouts(1000, 0)
+	for tick := 0; tick < ticks; tick++ {
+		for _, p := range aos {
+			p.update()
+		}
+		soa.update()
+		for i, p := range aos {
+			if p.active != soa.active[i] || p.x != soa.x[i] || p.y != soa.y[i] || p.angle != soa.angle[i] || p.lifetime != soa.lifetime[i] {
+				t.Fatalf("tick %d: particle %d diverged between the layouts", tick, i)
+			}
+		}
+	}
+}
+
+// BenchmarkLayout times updating benchParticles particles, alone and
+// together with building their quads, with the old []*Particle layout and
+// with particlePool.
+func BenchmarkLayout(b *testing.B) {
+	src := image.Rect(0, 0, int(smokeImageW), int(smokeImageH))
+	b.Run("Particles/Update", func(b *testing.B) {
+		aos, _ := layouts(benchParticles, b.N)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, p := range aos {
+				if p.active {
+					p.update()
+				}
+			}
+		}
+	})
+	b.Run("Pool/Update", func(b *testing.B) {
+		_, soa := layouts(benchParticles, b.N)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			soa.update()
+		}
+	})
+	b.Run("Particles/UpdateQuads", func(b *testing.B) {
+		aos, _ := layouts(benchParticles, b.N)
+		var sb spritebatch.SpriteBatch
+		sb.Reserve(benchParticles)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sb.Reset()
+			for _, p := range aos {
+				if !p.active {
+					continue
+				}
+				p.update()
+				pts, r, g, bl, a := particleQuad(p.x, p.y, p.angle, p.baseScale, p.lifetime, p.maxLife, p.baseAlpha, *p.color, LinearGrowth, false, false)
+				sb.AddQuad(pts, src, r, g, bl, a)
+			}
+		}
+	})
+	b.Run("Pool/UpdateQuads", func(b *testing.B) {
+		_, soa := layouts(benchParticles, b.N)
+		var sb spritebatch.SpriteBatch
+		sb.Reserve(benchParticles)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sb.Reset()
+			soa.update()
+			for j, active := range soa.active {
+				if !active {
+					continue
+				}
+				pts, r, g, bl, a := particleQuad(soa.x[j], soa.y[j], soa.angle[j], soa.baseScale[j], soa.lifetime[j], soa.maxLife[j], soa.baseAlpha[j], soa.color[j], LinearGrowth, false, false)
+				sb.AddQuad(pts, src, r, g, bl, a)
+			}
+		}
+	})
+}
+
+// TestEnvelope walks a particle through its whole life and checks the fade
+// contract: alpha starts at 0, holds at baseAlpha on the plateau, returns
+// to ~0 at end of life and has no jumps at the 0.2/0.8 breakpoints. It
+// then checks that dissipating alpha conserves opacity times area on the
+// plateau.
+func TestEnvelope(t *testing.T) {
+	const eps = 1e-6
+	p := newParticle(nil, 0, 0)
+	alphaAt := func(rate float64) float64 {
+		return float64(envelopeAlpha(rate) * p.baseAlpha)
+	}
+
+	if a := alphaAt(0); a != 0 {
+		t.Errorf("alpha at birth = %v, want 0", a)
+	}
+	for _, bp := range []float64{0.2, 0.8} {
+		below, at, above := alphaAt(bp-eps), alphaAt(bp), alphaAt(bp+eps)
+		if math.Abs(below-at) > 1e-4 || math.Abs(above-at) > 1e-4 {
+			t.Errorf("alpha jumps at rate %v: %v / %v / %v", bp, below, at, above)
+		}
+	}
+
+	peak := 0.0
+	for life := 0; life < p.maxLife; life++ {
+		rate := float64(life) / float64(p.maxLife)
+		a := alphaAt(rate)
+		if a < 0 || a > float64(p.baseAlpha)+eps {
+			t.Fatalf("alpha at rate %.3f = %v, outside [0, %v]", rate, a, p.baseAlpha)
+		}
+		if rate >= 0.2 && rate <= 0.8 && math.Abs(a-float64(p.baseAlpha)) > eps {
+			t.Fatalf("plateau alpha at rate %.3f = %v, want %v", rate, a, p.baseAlpha)
+		}
+		peak = math.Max(peak, a)
+	}
+	if math.Abs(peak-float64(p.baseAlpha)) > eps {
+		t.Errorf("peak alpha = %v, want %v", peak, p.baseAlpha)
+	}
+	if last := alphaAt(float64(p.maxLife-1) / float64(p.maxLife)); last > 0.02 {
+		t.Errorf("alpha on the last frame = %v, want ~0", last)
+	}
+
+	// Dissipation: on the plateau opacity times area stays at its value
+	// for the birth size, and the ends still fade to 0.
+	for _, curve := range []SizeCurve{LinearGrowth, {{0, 0.5}, {0.3, 1.4}, {1, 0.7}}} {
+		mass := curve.at(0) * curve.at(0)
+		for life := 0; life < p.maxLife; life++ {
+			rate := float64(life) / float64(p.maxLife)
+			a := float64(lifeAlpha(rate, curve, true))
+			if rate >= 0.2 && rate <= 0.8 {
+				if m := a * curve.at(rate) * curve.at(rate); math.Abs(m-mass) > 1e-4*mass {
+					t.Fatalf("curve %v: dissipating alpha %v at rate %.3f gives mass %v, want %v", curve, a, rate, m, mass)
+				}
+			}
+			if a > float64(envelopeAlpha(rate))+eps {
+				t.Fatalf("curve %v: dissipating alpha %v at rate %.3f exceeds the envelope", curve, a, rate)
+			}
+		}
+		if a := lifeAlpha(0, curve, true); a != 0 {
+			t.Errorf("curve %v: dissipating alpha at birth = %v, want 0", curve, a)
+		}
+	}
+	// A curve starting at zero size falls back to the plain envelope.
+	zeroStart := SizeCurve{{0, 0}, {1, 1.2}}
+	for life := 0; life < p.maxLife; life++ {
+		rate := float64(life) / float64(p.maxLife)
+		if a, want := lifeAlpha(rate, zeroStart, true), envelopeAlpha(rate); a != want {
+			t.Fatalf("zero-start curve alpha at rate %.3f = %v, want %v", rate, a, want)
+		}
+	}
+}
+
+func TestParseSizeCurve(t *testing.T) {
+	c, err := ParseSizeCurve("0:0.5, 0.3:1.4, 1:0.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.at(0.15); math.Abs(got-0.95) > 1e-12 {
+		t.Errorf("at(0.15) = %v, want 0.95", got)
+	}
+	for _, bad := range []string{"0.5", "0:1,0:2", "2:1", "0:-1", "x:1"} {
+		if _, err := ParseSizeCurve(bad); err == nil {
+			t.Errorf("ParseSizeCurve(%q) accepted", bad)
+		}
+	}
+}
+
+// TestAudio feeds synthetic PCM through the audio path: the smoothing time
+// constant and its bound on tick-to-tick change, the spawn scale at the
+// reference level and its limits, and the constant-rate fallback once the
+// stream ends.
+func TestAudio(t *testing.T) {
+	a := newAudioInput("test", DefaultAudioRate, DefaultAudioSmooth, DefaultAudioRef)
+	pcm := func(frames int, amp float64) []byte {
+		b := make([]byte, 2*frames)
+		for i := 0; i < frames; i++ {
+			v := amp * 32767 * math.Sin(2*math.Pi*440*float64(i)/float64(a.sampleRate))
+			binary.LittleEndian.PutUint16(b[2*i:], uint16(int16(v)))
+		}
+		return b
+	}
+	if s := a.spawnScale(); s != 1 {
+		t.Errorf("spawn scale before any samples = %v, want 1", s)
+	}
+
+	// One second of a steady tone, one block per tick as the reader would
+	// see it: the level climbs without jumps and reaches 1-1/e of the RMS
+	// after one time constant.
+	const dt = 1.0 / 60
+	tone := pcm(a.blockFrames*60, 0.2)
+	want := audiolevel.BlockRMS(tone)
+	for tick := 0; tick < 60; tick++ {
+		block := tone[2*a.blockFrames*tick : 2*a.blockFrames*(tick+1)]
+		if err := a.read(bytes.NewReader(block)); err != io.EOF {
+			t.Fatalf("reading one block: %v", err)
+		}
+		a.state.Store(audioLive)
+		before := a.level
+		a.smooth(dt)
+		if maxStep := audiolevel.BlockRMS(block) * (1 - math.Exp(-dt/a.smoothing)); a.level-before > maxStep+1e-12 {
+			t.Fatalf("tick %d: level jumped %.4f, smoothing allows %.4f", tick, a.level-before, maxStep)
+		}
+		if t0 := float64(tick+1) * dt; math.Abs(t0-a.smoothing) < dt/2 {
+			if got := a.level / want; math.Abs(got-(1-1/math.E)) > 0.05 {
+				t.Errorf("after one time constant the level is %.2f of the RMS, want %.2f", got, 1-1/math.E)
+			}
+		}
+	}
+
+	a.level = a.ref
+	if s := a.spawnScale(); math.Abs(s-1) > 1e-12 {
+		t.Errorf("spawn scale at the reference level = %v, want 1", s)
+	}
+	a.level = 0
+	if s := a.spawnScale(); s != audioFloor {
+		t.Errorf("spawn scale in silence = %v, want %v", s, audioFloor)
+	}
+	a.level = 1
+	if s := a.spawnScale(); s != audioMaxGain {
+		t.Errorf("spawn scale at full scale = %v, want the cap %v", s, audioMaxGain)
+	}
+
+	// A stream that ends (here mid-block) falls back to the constant rate.
+	if err := a.read(bytes.NewReader(pcm(a.blockFrames*3+7, 0.8))); err != io.EOF {
+		t.Fatalf("reading a finite stream: %v, want EOF", err)
+	}
+	if s := a.spawnScale(); s != 1 {
+		t.Errorf("spawn scale after the stream ended = %v, want 1", s)
+	}
+}
@@ -0,0 +1,86 @@
+// Package smokepaint is the NewParticles demo's smoke particle: a sprite
+// that drifts from where it was painted or emitted, fades over its life and
+// bounces off the ground. Every random value comes from the caller's
+// generator, so a seeded one reproduces the same particles.
+package smokepaint
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Particle is one smoke sprite.
+type Particle struct {
+	X, Y     float64
+	VX, VY   float64
+	Angle    float64
+	Scale    float64
+	Alpha    float32
+	Life     int
+	MaxLife  int
+	Img      *ebiten.Image
+	ColorMix color.RGBA
+}
+
+// New creates a particle at (x, y) moving in a random direction, drawing
+// every random value from rng.
+func New(rng *rand.Rand, img *ebiten.Image, x, y float64) *Particle {
+	dir := rng.Float64() * 2 * math.Pi
+	speed := rng.Float64()*1.5 + 0.5
+	// life counts down from maxLife, so the fade ratio starts at exactly 1.0
+	maxLife := 60 + rng.Intn(120)
+
+	return &Particle{
+		X:        x,
+		Y:        y,
+		VX:       math.Cos(dir) * speed,
+		VY:       math.Sin(dir) * speed,
+		Angle:    rng.Float64() * 2 * math.Pi,
+		Scale:    rng.Float64()*0.2 + 0.3,
+		Alpha:    0.6,
+		Life:     maxLife,
+		MaxLife:  maxLife,
+		Img:      img,
+		ColorMix: color.RGBA{uint8(200 + rng.Intn(55)), uint8(200 + rng.Intn(55)), 255, 255},
+	}
+}
+
+// Update advances the particle and applies the given gravity acceleration.
+func (p *Particle) Update(gx, gy float64) bool {
+	p.X += p.VX
+	p.Y += p.VY
+	p.VX += gx
+	p.VY += gy
+
+	p.Angle += 0.01
+	p.Life--
+
+	return p.Life > 0
+}
+
+// Fade is the fraction of its life the particle has left, 1 at spawn
+// falling to 0; it scales the particle's alpha.
+func (p *Particle) Fade() float32 {
+	return float32(p.Life) / float32(p.MaxLife)
+}
+
+// Bounce reflects a particle that has crossed the ground at groundY moving
+// downward, keeping restitution of its vertical speed and friction of its
+// horizontal speed.
+func (p *Particle) Bounce(groundY, restitution, friction float64) {
+	if p.Y < groundY || p.VY <= 0 {
+		return
+	}
+	p.Y = groundY - (p.Y-groundY)*restitution
+	p.VY = -p.VY * restitution
+	p.VX *= friction
+}
+
+// Offscreen reports whether the particle is more than margin pixels outside
+// a width x height screen.
+func (p *Particle) Offscreen(width, height, margin float64) bool {
+	return p.X < -margin || p.X > width+margin || p.Y < -margin || p.Y > height+margin
+}
@@ -0,0 +1,67 @@
+package smokepaint
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestNewFullLife checks that a new particle starts with its whole life
+// ahead of it: life equals maxLife, so its first frame draws at exactly
+// full alpha rather than one tick into its fade.
+func TestNewFullLife(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		p := New(rng, nil, 0, 0)
+		if p.Life != p.MaxLife {
+			t.Fatalf("life %d, maxLife %d; want them equal", p.Life, p.MaxLife)
+		}
+		if f := p.Fade(); f != 1.0 {
+			t.Fatalf("initial fade ratio %v, want exactly 1", f)
+		}
+	}
+}
+
+// TestNewRanges draws many particles from a seeded generator and checks
+// their fields stay in the documented ranges, that directions spread
+// uniformly over the circle, and that the same seed reproduces the same
+// particle.
+func TestNewRanges(t *testing.T) {
+	const n, bins = 100000, 8
+	rng := rand.New(rand.NewSource(1))
+	var counts [bins]int
+	for i := 0; i < n; i++ {
+		p := New(rng, nil, 100, 200)
+		speed := math.Hypot(p.VX, p.VY)
+		switch {
+		case p.X != 100 || p.Y != 200:
+			t.Fatalf("particle spawned at (%v, %v), want (100, 200)", p.X, p.Y)
+		case speed < 0.5-1e-9 || speed >= 2+1e-9:
+			t.Fatalf("speed %v outside [0.5, 2)", speed)
+		case p.MaxLife < 60 || p.MaxLife >= 180:
+			t.Fatalf("maxLife %d outside [60, 180)", p.MaxLife)
+		case p.Angle < 0 || p.Angle >= 2*math.Pi:
+			t.Fatalf("angle %v outside [0, 2π)", p.Angle)
+		case p.Scale < 0.3 || p.Scale >= 0.5:
+			t.Fatalf("scale %v outside [0.3, 0.5)", p.Scale)
+		case p.ColorMix.R < 200 || p.ColorMix.G < 200 || p.ColorMix.B != 255:
+			t.Fatalf("color %v outside the pale blue range", p.ColorMix)
+		}
+		dir := math.Atan2(p.VY, p.VX) + math.Pi
+		counts[min(int(dir/(2*math.Pi)*bins), bins-1)]++
+	}
+	// each bin count is binomial(n, 1/bins); allow four standard deviations
+	want := float64(n) / bins
+	tol := 4 * math.Sqrt(want*(1-1.0/bins))
+	for i, c := range counts {
+		if math.Abs(float64(c)-want) > tol {
+			t.Errorf("direction bin %d holds %d of %d particles, want %.0f ± %.0f", i, c, n, want, tol)
+		}
+	}
+
+	a := New(rand.New(rand.NewSource(42)), nil, 0, 0)
+	b := New(rand.New(rand.NewSource(42)), nil, 0, 0)
+	if *a != *b {
+		t.Errorf("same seed gave different particles: %+v vs %+v", *a, *b)
+	}
+}
@@ -0,0 +1,224 @@
+// Package spawnlog reads and writes amazing's spawn recordings
+// (-recordspawns, -replayspawns). The file is text: a header line
+//
+//	take seed <n> pool <n> limit <n> view <3d|2d> maxspeed <fire> <ember> parallax <px>
+//
+// then one event per line, as
+//
+//	<tick> spawn <fire|ember> <back|mid|front> <x> <y> <z> <velocity scale>
+//	<tick> burst <x> <y> <count>
+//	<tick> chain <x> <y>
+//	<tick> clearchain
+//	<tick> zone <x0> <y0> <x1> <y1>
+//	<tick> unzone
+//	<tick> limit <particles>
+//
+// with # starting a comment line. Numbers are written in full precision, so
+// a recording read back is exactly what was written. It has no ebiten
+// dependency.
+package spawnlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kinds and Layers are the particle kinds and depth layers a spawn event
+// may name.
+var (
+	Kinds  = []string{"fire", "ember"}
+	Layers = []string{"back", "mid", "front"}
+)
+
+// Args is how many arguments follow each event's name.
+var Args = map[string]int{
+	"spawn": 6, "burst": 3, "chain": 2, "clearchain": 0, "zone": 4, "unzone": 0, "limit": 1,
+}
+
+// headerFormat is the header line, for writing and scanning.
+const headerFormat = "take seed %d pool %d limit %d view %s maxspeed %v %v parallax %v\n"
+
+// Header is the show a recording starts from.
+type Header struct {
+	Seed              uint64 // spawn generator state
+	Pool, Limit       int    // particle pool size and limit
+	View              string // "3d" or "2d"
+	MaxFire, MaxEmber float64
+	Parallax          float64
+}
+
+// Write writes h as a header line.
+func (h Header) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, headerFormat, h.Seed, h.Pool, h.Limit, h.View, h.MaxFire, h.MaxEmber, h.Parallax)
+	return err
+}
+
+// ParseHeader reads a header line.
+func ParseHeader(text string) (Header, error) {
+	var h Header
+	if _, err := fmt.Sscanf(text+"\n", headerFormat, &h.Seed, &h.Pool, &h.Limit, &h.View, &h.MaxFire, &h.MaxEmber, &h.Parallax); err != nil {
+		return h, fmt.Errorf("bad header: %v", err)
+	}
+	if h.View != "3d" && h.View != "2d" {
+		return h, fmt.Errorf("unknown view %q", h.View)
+	}
+	if h.Limit < 1 || h.Limit > h.Pool {
+		return h, fmt.Errorf("particle limit %d outside the pool of %d", h.Limit, h.Pool)
+	}
+	return h, nil
+}
+
+// Event is one recorded event; which fields are used depends on Op.
+type Event struct {
+	Tick        int64
+	Op          string
+	Kind, Layer string // spawn
+	X, Y, Z     float64
+	Scale       float64    // spawn: velocity scale
+	N           int        // burst: count; limit: particle limit
+	Zone        [4]float64 // zone: x0, y0, x1, y1
+}
+
+// Write writes ev as one line of a recording.
+func (ev Event) Write(w io.Writer) error {
+	var err error
+	switch ev.Op {
+	case "spawn":
+		_, err = fmt.Fprintf(w, "%d spawn %s %s %v %v %v %v\n", ev.Tick, ev.Kind, ev.Layer, ev.X, ev.Y, ev.Z, ev.Scale)
+	case "burst":
+		_, err = fmt.Fprintf(w, "%d burst %v %v %d\n", ev.Tick, ev.X, ev.Y, ev.N)
+	case "chain":
+		_, err = fmt.Fprintf(w, "%d chain %v %v\n", ev.Tick, ev.X, ev.Y)
+	case "zone":
+		z := ev.Zone
+		_, err = fmt.Fprintf(w, "%d zone %v %v %v %v\n", ev.Tick, z[0], z[1], z[2], z[3])
+	case "limit":
+		_, err = fmt.Fprintf(w, "%d limit %d\n", ev.Tick, ev.N)
+	default:
+		_, err = fmt.Fprintf(w, "%d %s\n", ev.Tick, ev.Op)
+	}
+	return err
+}
+
+// Parse reads one event line.
+func Parse(text string) (Event, error) {
+	var ev Event
+	f := strings.Fields(text)
+	if len(f) < 2 {
+		return ev, fmt.Errorf("want \"<tick> <event> [args]\"")
+	}
+	tick, err := strconv.ParseInt(f[0], 10, 64)
+	if err != nil || tick < 1 {
+		return ev, fmt.Errorf("bad tick %q", f[0])
+	}
+	ev.Tick, ev.Op = tick, f[1]
+	args := f[2:]
+	want, ok := Args[ev.Op]
+	if !ok {
+		return ev, fmt.Errorf("unknown event %q", ev.Op)
+	}
+	if len(args) != want {
+		return ev, fmt.Errorf("%s takes %d arguments, got %d", ev.Op, want, len(args))
+	}
+	if ev.Op == "spawn" {
+		ev.Kind, ev.Layer = args[0], args[1]
+		if !slices.Contains(Kinds, ev.Kind) {
+			return ev, fmt.Errorf("spawn: unknown kind %q", ev.Kind)
+		}
+		if !slices.Contains(Layers, ev.Layer) {
+			return ev, fmt.Errorf("spawn: unknown layer %q", ev.Layer)
+		}
+		args = args[2:]
+	}
+	nums := make([]float64, len(args))
+	for i, a := range args {
+		if nums[i], err = strconv.ParseFloat(a, 64); err != nil || math.IsInf(nums[i], 0) || math.IsNaN(nums[i]) {
+			return ev, fmt.Errorf("%s: bad number %q", ev.Op, a)
+		}
+	}
+	count := func(v float64) (int, error) {
+		if v < 1 || v != math.Trunc(v) {
+			return 0, fmt.Errorf("%s: %v is not a positive count", ev.Op, v)
+		}
+		return int(v), nil
+	}
+	switch ev.Op {
+	case "spawn":
+		ev.X, ev.Y, ev.Z, ev.Scale = nums[0], nums[1], nums[2], nums[3]
+	case "burst":
+		ev.X, ev.Y = nums[0], nums[1]
+		ev.N, err = count(nums[2])
+	case "chain":
+		ev.X, ev.Y = nums[0], nums[1]
+	case "zone":
+		ev.Zone = [4]float64(nums)
+	case "limit":
+		ev.N, err = count(nums[0])
+	}
+	return ev, err
+}
+
+// Reader reads a recording a line at a time, so long takes don't have to
+// fit in memory.
+type Reader struct {
+	sc   *bufio.Scanner
+	line int
+	tick int64 // of the last event read
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{sc: bufio.NewScanner(r)}
+}
+
+// scan returns the next line that isn't blank or a comment.
+func (r *Reader) scan() (string, error) {
+	for r.sc.Scan() {
+		r.line++
+		if text := strings.TrimSpace(r.sc.Text()); text != "" && !strings.HasPrefix(text, "#") {
+			return text, nil
+		}
+	}
+	if err := r.sc.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// Header reads the header line; call it before Next.
+func (r *Reader) Header() (Header, error) {
+	text, err := r.scan()
+	if err == io.EOF {
+		return Header{}, fmt.Errorf("empty recording")
+	}
+	if err != nil {
+		return Header{}, err
+	}
+	h, err := ParseHeader(text)
+	if err != nil {
+		return h, fmt.Errorf("line %d: %v", r.line, err)
+	}
+	return h, nil
+}
+
+// Next reads the next event. At the end of the recording it returns
+// io.EOF; events must come in tick order.
+func (r *Reader) Next() (Event, error) {
+	text, err := r.scan()
+	if err != nil {
+		return Event{}, err
+	}
+	ev, err := Parse(text)
+	if err == nil && ev.Tick < r.tick {
+		err = fmt.Errorf("tick %d after tick %d", ev.Tick, r.tick)
+	}
+	if err != nil {
+		return ev, fmt.Errorf("line %d: %v", r.line, err)
+	}
+	r.tick = ev.Tick
+	return ev, nil
+}
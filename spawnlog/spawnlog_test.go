@@ -0,0 +1,133 @@
+package spawnlog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// events has one line of every kind, as Write writes them.
+var events = []string{
+	"1 spawn fire back 640.5 360 -0.25 1",
+	"1 spawn ember front 0.1 1e-07 0 0.75",
+	"2 burst 100 200 300",
+	"2 chain 300.125 200",
+	"3 clearchain",
+	"3 zone 1000 0 1280 120",
+	"4 unzone",
+	"5 limit 4000",
+}
+
+// TestRoundTrip checks that every event line parses and is written back
+// unchanged, and that the lines cover every event.
+func TestRoundTrip(t *testing.T) {
+	seen := map[string]bool{}
+	var buf bytes.Buffer
+	for _, line := range events {
+		ev, err := Parse(line)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", line, err)
+			continue
+		}
+		buf.Reset()
+		if err := ev.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSuffix(buf.String(), "\n"); got != line {
+			t.Errorf("%q written back as %q", line, got)
+		}
+		seen[ev.Op] = true
+	}
+	for op := range Args {
+		if !seen[op] {
+			t.Errorf("no %s line tested", op)
+		}
+	}
+
+	ev, err := Parse("7 zone 1 2 3 4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Tick != 7 || ev.Zone != [4]float64{1, 2, 3, 4} {
+		t.Errorf("zone parsed as %+v", ev)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, bad := range []string{
+		"", "1", "spawn fire back 0 0 0 1", "0 clearchain", "-3 unzone", "1.5 unzone",
+		"1 fly 0 0",
+		"1 burst 100 200", "1 clearchain now", "1 limit",
+		"1 spawn smoke back 0 0 0 1", "1 spawn fire side 0 0 0 1",
+		"1 chain x 0", "1 chain NaN 0", "1 zone 0 0 +Inf 1",
+		"1 burst 0 0 0", "1 burst 0 0 2.5", "1 limit -1",
+	} {
+		if ev, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", bad, ev)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h := Header{Seed: 1 << 63, Pool: 5000, Limit: 2500, View: "3d", MaxFire: 4.5, MaxEmber: 3, Parallax: 0.35}
+	var buf bytes.Buffer
+	if err := h.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseHeader(strings.TrimSuffix(buf.String(), "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != h {
+		t.Errorf("header %+v read back as %+v", h, got)
+	}
+	for _, bad := range []string{
+		"take seed 1 pool 10 limit 5",
+		"take seed 1 pool 10 limit 5 view 4d maxspeed 1 1 parallax 0",
+		"take seed 1 pool 10 limit 11 view 2d maxspeed 1 1 parallax 0",
+		"take seed 1 pool 10 limit 0 view 2d maxspeed 1 1 parallax 0",
+	} {
+		if _, err := ParseHeader(bad); err == nil {
+			t.Errorf("ParseHeader(%q) succeeded", bad)
+		}
+	}
+}
+
+// TestReader checks that a recording reads back in order past comments and
+// blank lines, and that errors name the line.
+func TestReader(t *testing.T) {
+	rec := "# a take\ntake seed 1 pool 10 limit 10 view 2d maxspeed 1 1 parallax 0\n\n" +
+		strings.Join(events, "\n# more\n") + "\n"
+	r := NewReader(strings.NewReader(rec))
+	if _, err := r.Header(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range events {
+		ev, err := r.Next()
+		if err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		if i > 0 && ev.Op == "spawn" && ev.Kind != "ember" {
+			t.Errorf("event %d is %+v, want the ember spawn", i, ev)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("after the last event: %v, want io.EOF", err)
+	}
+
+	r = NewReader(strings.NewReader("take seed 1 pool 10 limit 10 view 2d maxspeed 1 1 parallax 0\n2 unzone\n\n1 unzone\n"))
+	if _, err := r.Header(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Errorf("event out of tick order: %v, want an error on line 4", err)
+	}
+
+	if _, err := NewReader(strings.NewReader("# nothing\n")).Header(); err == nil {
+		t.Errorf("empty recording read a header")
+	}
+}
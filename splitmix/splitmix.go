@@ -0,0 +1,31 @@
+// Package splitmix is the splitmix64 generator and a stateless noise
+// function built on its finalizer. The generator's whole state is one word,
+// so a program can save it and later draw exactly the same numbers again.
+package splitmix
+
+// golden is the generator's increment, 2^64 divided by the golden ratio.
+const golden = 0x9e3779b97f4a7c15
+
+// Mix64 is the splitmix64 finalizer.
+func Mix64(z uint64) uint64 {
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// Noise returns a value in [-1, 1) that depends only on seed and n: the
+// finalizer of their combination.
+func Noise(seed, n uint64) float64 {
+	return float64(Mix64(seed+n*golden)>>11)/(1<<52) - 1
+}
+
+// Source is a splitmix64 generator, usable as a math/rand Source64.
+type Source struct{ State uint64 }
+
+func (s *Source) Uint64() uint64 {
+	s.State += golden
+	return Mix64(s.State)
+}
+
+func (s *Source) Int63() int64    { return int64(s.Uint64() >> 1) }
+func (s *Source) Seed(seed int64) { s.State = uint64(seed) }
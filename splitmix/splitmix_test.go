@@ -0,0 +1,48 @@
+package splitmix
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestSource checks the generator against the reference splitmix64 output
+// for seed 1234567 and that reseeding starts the sequence over.
+func TestSource(t *testing.T) {
+	want := []uint64{6457827717110365317, 3203168211198807973, 9817491932198370423, 4593380528125082431, 16408922859458223821}
+	s := &Source{State: 1234567}
+	for i, w := range want {
+		if got := s.Uint64(); got != w {
+			t.Errorf("draw %d = %d, want %d", i, got, w)
+		}
+	}
+	s.Seed(1234567)
+	if got := s.Int63(); got != int64(want[0]>>1) {
+		t.Errorf("Int63 after reseeding = %d, want %d", got, want[0]>>1)
+	}
+
+	a, b := rand.New(&Source{State: 9}), rand.New(&Source{State: 9})
+	for i := 0; i < 100; i++ {
+		if x, y := a.Float64(), b.Float64(); x != y {
+			t.Fatalf("draw %d from equal states: %v and %v", i, x, y)
+		}
+	}
+}
+
+func TestNoise(t *testing.T) {
+	const samples = 100000
+	sum := 0.0
+	for n := uint64(0); n < samples; n++ {
+		v := Noise(7, n)
+		if v < -1 || v >= 1 {
+			t.Fatalf("Noise(7, %d) = %v, outside [-1, 1)", n, v)
+		}
+		sum += v
+	}
+	if mean := sum / samples; math.Abs(mean) > 0.01 {
+		t.Errorf("Noise mean %.4f over %d samples, want about 0", mean, samples)
+	}
+	if Noise(7, 3) == Noise(8, 3) || Noise(7, 3) == Noise(7, 4) {
+		t.Errorf("Noise(7, 3) = %v repeats for a neighbouring seed or n", Noise(7, 3))
+	}
+}
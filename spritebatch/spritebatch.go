@@ -0,0 +1,135 @@
+// Package spritebatch collects textured quads into DrawTriangles vertex and
+// index buffers and draws them with as few calls as the uint16 index limit
+// allows. It replaces the "append four vertices and six indices, then
+// DrawTriangles" loop the particle demos each wrote by hand.
+package spritebatch
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// MaxQuads is how many quads one draw call can address with uint16 indices
+// (4 vertices each). A batch holding more is split into several calls.
+const MaxQuads = (math.MaxUint16 + 1) / 4
+
+// chunk is the buffers for one draw call, at most MaxQuads quads.
+type chunk struct {
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+// SpriteBatch accumulates quads between Reset calls. The zero value is ready
+// to use; buffers are kept across Reset so a steady frame allocates nothing.
+type SpriteBatch struct {
+	chunks []chunk
+	used   int // chunks holding quads this frame
+	quads  int
+}
+
+// Reset empties the batch, keeping its buffers.
+func (sb *SpriteBatch) Reset() {
+	for i := range sb.chunks[:sb.used] {
+		sb.chunks[i].vertices = sb.chunks[i].vertices[:0]
+		sb.chunks[i].indices = sb.chunks[i].indices[:0]
+	}
+	sb.used = 0
+	sb.quads = 0
+}
+
+// Len is the number of quads added since the last Reset.
+func (sb *SpriteBatch) Len() int {
+	return sb.quads
+}
+
+// Cap is the number of quads the batch can hold without allocating.
+func (sb *SpriteBatch) Cap() int {
+	n := 0
+	for _, c := range sb.chunks {
+		n += cap(c.vertices) / 4
+	}
+	return n
+}
+
+// Reserve grows the buffers so that n quads fit without allocating.
+func (sb *SpriteBatch) Reserve(n int) {
+	for i := 0; n > 0; i++ {
+		q := min(n, MaxQuads)
+		if i == len(sb.chunks) {
+			sb.chunks = append(sb.chunks, chunk{})
+		}
+		c := &sb.chunks[i]
+		if cap(c.vertices) < q*4 {
+			c.vertices = append(make([]ebiten.Vertex, 0, q*4), c.vertices...)
+			c.indices = append(make([]uint16, 0, q*6), c.indices...)
+		}
+		n -= q
+	}
+}
+
+// Add appends the src rectangle of the texture, placed on the destination by
+// geo, tinted by the premultiplied color (r, g, b, a).
+func (sb *SpriteBatch) Add(geo ebiten.GeoM, src image.Rectangle, r, g, b, a float32) {
+	w, h := float64(src.Dx()), float64(src.Dy())
+	var pts [4][2]float64
+	for i, c := range [4][2]float64{{0, 0}, {0, h}, {w, 0}, {w, h}} {
+		pts[i][0], pts[i][1] = geo.Apply(c[0], c[1])
+	}
+	sb.AddQuad(pts, src, r, g, b, a)
+}
+
+// AddQuad appends a quad with explicit destination corners, in the order
+// top-left, bottom-left, top-right, bottom-right of src. Custom0/Custom1 of
+// each vertex hold its quad-local position (-1 or 1) for shaders.
+func (sb *SpriteBatch) AddQuad(pts [4][2]float64, src image.Rectangle, r, g, b, a float32) {
+	if sb.used == 0 || len(sb.chunks[sb.used-1].vertices) == MaxQuads*4 {
+		if sb.used == len(sb.chunks) {
+			sb.chunks = append(sb.chunks, chunk{})
+		}
+		sb.used++
+	}
+	c := &sb.chunks[sb.used-1]
+
+	x0, y0 := float32(src.Min.X), float32(src.Min.Y)
+	x1, y1 := float32(src.Max.X), float32(src.Max.Y)
+	srcs := [4][2]float32{{x0, y0}, {x0, y1}, {x1, y0}, {x1, y1}}
+	local := [4][2]float32{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+
+	vIndex := uint16(len(c.vertices))
+	for i, p := range pts {
+		c.vertices = append(c.vertices, ebiten.Vertex{
+			DstX: float32(p[0]), DstY: float32(p[1]),
+			SrcX: srcs[i][0], SrcY: srcs[i][1],
+			ColorR: r, ColorG: g, ColorB: b, ColorA: a,
+			Custom0: local[i][0], Custom1: local[i][1],
+		})
+	}
+	// Two triangles: (0, 1, 2) and (1, 3, 2)
+	c.indices = append(c.indices,
+		vIndex, vIndex+1, vIndex+2,
+		vIndex+1, vIndex+3, vIndex+2,
+	)
+	sb.quads++
+}
+
+// Flush draws every quad onto dst sampling tex, one DrawTriangles call per
+// MaxQuads quads, and returns the number of calls. The quads stay in the
+// batch until Reset.
+func (sb *SpriteBatch) Flush(dst, tex *ebiten.Image, mode ebiten.CompositeMode) int {
+	op := &ebiten.DrawTrianglesOptions{CompositeMode: mode}
+	for _, c := range sb.chunks[:sb.used] {
+		dst.DrawTriangles(c.vertices, c.indices, tex, op)
+	}
+	return sb.used
+}
+
+// FlushShader is Flush drawing with a shader instead of a texture.
+func (sb *SpriteBatch) FlushShader(dst *ebiten.Image, shader *ebiten.Shader, mode ebiten.CompositeMode) int {
+	op := &ebiten.DrawTrianglesShaderOptions{CompositeMode: mode}
+	for _, c := range sb.chunks[:sb.used] {
+		dst.DrawTrianglesShader(c.vertices, c.indices, shader, op)
+	}
+	return sb.used
+}
@@ -0,0 +1,68 @@
+package spritebatch
+
+import (
+	"image"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// fill adds n 32x16 quads, quad i translated i pixels right.
+func fill(sb *SpriteBatch, n int) {
+	src := image.Rect(0, 0, 32, 16)
+	for i := 0; i < n; i++ {
+		var geo ebiten.GeoM
+		geo.Translate(float64(i), 0)
+		sb.Add(geo, src, 0.5, 0.5, 0.5, 0.5)
+	}
+}
+
+// TestSplit fills a batch past one draw call's worth of quads and checks the
+// split: every chunk but the last is full, each chunk's indices stay inside
+// its own vertices, and corners and colors land where Add put them.
+func TestSplit(t *testing.T) {
+	var sb SpriteBatch
+	const n = MaxQuads + 10
+	fill(&sb, n)
+	if sb.Len() != n || sb.used != 2 {
+		t.Fatalf("%d quads in %d chunks, want %d in 2", sb.Len(), sb.used, n)
+	}
+	if got := len(sb.chunks[0].vertices) / 4; got != MaxQuads {
+		t.Errorf("first chunk holds %d quads, want %d", got, MaxQuads)
+	}
+	if got := len(sb.chunks[1].vertices) / 4; got != 10 {
+		t.Errorf("second chunk holds %d quads, want 10", got)
+	}
+	for ci, c := range sb.chunks[:sb.used] {
+		if len(c.indices) != len(c.vertices)/4*6 {
+			t.Errorf("chunk %d: %d indices for %d vertices", ci, len(c.indices), len(c.vertices))
+		}
+		for _, idx := range c.indices {
+			if int(idx) >= len(c.vertices) {
+				t.Fatalf("chunk %d: index %d past its %d vertices", ci, idx, len(c.vertices))
+			}
+		}
+	}
+
+	// The first quad of the second chunk is quad MaxQuads, translated by it.
+	v := sb.chunks[1].vertices
+	want := [4][2]float32{{MaxQuads, 0}, {MaxQuads, 16}, {MaxQuads + 32, 0}, {MaxQuads + 32, 16}}
+	for i, w := range want {
+		if v[i].DstX != w[0] || v[i].DstY != w[1] || v[i].ColorA != 0.5 {
+			t.Errorf("split quad corner %d at (%v, %v) alpha %v, want (%v, %v) alpha 0.5", i, v[i].DstX, v[i].DstY, v[i].ColorA, w[0], w[1])
+		}
+	}
+}
+
+// TestResetReusesBuffers checks that Reset empties the batch but keeps its
+// capacity.
+func TestResetReusesBuffers(t *testing.T) {
+	var sb SpriteBatch
+	fill(&sb, MaxQuads+10)
+	capBefore := sb.Cap()
+	sb.Reset()
+	sb.Add(ebiten.GeoM{}, image.Rect(0, 0, 32, 16), 1, 1, 1, 1)
+	if sb.Len() != 1 || sb.used != 1 || sb.Cap() != capBefore {
+		t.Errorf("after Reset and one Add: %d quads, %d chunks, cap %d; want 1, 1, cap %d", sb.Len(), sb.used, sb.Cap(), capBefore)
+	}
+}
@@ -0,0 +1,28 @@
+package spritebatch
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// RadialAlpha builds a white w x h image whose alpha falls off from the
+// center as (1 - d/maxR)^exponent. Larger exponents give a harder, tighter
+// core; smaller ones a softer glow. It is the usual particle sprite.
+func RadialAlpha(w, h int, exponent float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	cx, cy := float64(w)/2.0, float64(h)/2.0
+	maxR := math.Hypot(cx, cy)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			d := math.Hypot(float64(x)-cx, float64(y)-cy)
+			t := 1.0 - d/maxR
+			if t < 0 {
+				t = 0
+			}
+			a := uint8(math.Pow(t, exponent) * 255)
+			img.SetRGBA(x, y, color.RGBA{255, 255, 255, a})
+		}
+	}
+	return img
+}